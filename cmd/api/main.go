@@ -1,10 +1,21 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/LaQuannT/astronaut-api/internal/app"
 	_ "github.com/joho/godotenv/autoload"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if len(os.Args) != 3 {
+			log.Fatal("usage: astronaut-api import <dataset.csv>")
+		}
+		app.Import(os.Args[2])
+		return
+	}
+
 	app.Run()
 }
@@ -1,19 +1,32 @@
 package app
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/config"
 	"github.com/LaQuannT/astronaut-api/internal/database/postgres"
+	"github.com/LaQuannT/astronaut-api/internal/metrics"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
 	"github.com/LaQuannT/astronaut-api/internal/transport"
+	"github.com/LaQuannT/astronaut-api/migration"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-func initialize(c *config.Config) http.Handler {
+func connect(c *config.DB) *sql.DB {
 	connStr := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=%s",
 		c.DBUsername, c.DBPassword, c.DBHost, c.DBPort, c.DBName, c.DBSSLMode)
 
@@ -21,35 +34,237 @@ func initialize(c *config.Config) http.Handler {
 	if err != nil {
 		log.Fatal(err)
 	}
+	return dbConn
+}
+
+// application is the api handler along with the resources it holds, released on shutdown.
+type application struct {
+	handler   http.Handler
+	readiness *service.Readiness
+	db        *sql.DB
+	logger    *slog.Logger
+
+	// tracerProvider is nil when tracing is off
+	tracerProvider *sdktrace.TracerProvider
+}
 
-	astronautRepository, _, _, _, _, usrRepository := postgres.InitializeRepositories(dbConn)
+// initialize connects to the database and builds the api, background jobs run until ctx is done.
+func initialize(ctx context.Context, c *config.Config) *application {
+	dbConn := connect(&c.DB)
+
+	astronautRepository, astronautLogRepository, academicLogRepository, militaryLogRepository, missionRepository, usrRepository := postgres.InitializeRepositories(dbConn)
+	datasetRepository := postgres.NewDatasetRepo(dbConn)
+	searchRepository := postgres.NewSearchRepo(dbConn)
+	sessionRepository := postgres.NewSessionRepo(dbConn)
+	apiKeyRepository := postgres.NewAPIKeyRepo(dbConn)
+	quotaRepository := postgres.NewQuotaRepo(dbConn)
+	auditRepository := postgres.NewAuditRepo(dbConn)
+	statsRepository := postgres.NewStatsRepo(dbConn)
+	healthRepository := postgres.NewHealthRepo(dbConn)
+
+	tokens := service.NewTokens(c.JWTSecret, c.AccessTokenTTL, c.RefreshTokenTTL)
+	limiter := service.NewRateLimiter(quotaRepository, c.RateLimits, nil)
+
+	migrations, err := migration.Latest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	readiness := service.NewReadiness(healthRepository, migrations)
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	tracerProvider := newTracerProvider(ctx, c, logger)
+
+	postgres.RegisterPoolMetrics(metrics.Default, dbConn, c.DBName)
+	go service.RunDomainMetrics(ctx, logger, service.NewDomainMetrics(metrics.Default, statsRepository), c.MetricsInterval)
+
+	go service.RunPurge(
+		ctx,
+		logger,
+		service.NewAuditedAstronautRepo(astronautRepository, auditRepository),
+		service.NewAuditedMissionRepo(missionRepository, auditRepository),
+		service.NewAuditedUserRepo(usrRepository, auditRepository),
+		quotaRepository,
+		c.RetentionPeriod,
+		c.PurgeInterval,
+	)
 
 	handler := transport.NewServer(
 		logger,
+		limiter,
+		tokens,
 		usrRepository,
+		sessionRepository,
+		apiKeyRepository,
 		astronautRepository,
+		astronautLogRepository,
+		militaryLogRepository,
+		academicLogRepository,
+		missionRepository,
+		datasetRepository,
+		searchRepository,
+		auditRepository,
+		readiness,
+		c.MetricsToken,
 	)
-	return handler
+	return &application{
+		handler:   handler,
+		readiness: readiness,
+		db:        dbConn,
+		logger:    logger,
+
+		tracerProvider: tracerProvider,
+	}
+}
+
+// newTracerProvider sets the global tracer provider to export spans as configured by c, it returns nil
+// when tracing is off.
+func newTracerProvider(ctx context.Context, c *config.Config, logger *slog.Logger) *sdktrace.TracerProvider {
+	tp, err := tracing.NewProvider(ctx, c.TracesExporter, c.OTLPEndpoint, c.ServiceName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if tp == nil {
+		return nil
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Error("exporting traces", slog.String("error", err.Error()))
+	}))
+	return tp
 }
 
+// Run serves the api until SIGINT or SIGTERM, then drains it: readiness fails for the drain delay
+// so load balancers stop routing to the server, then in-flight requests get the grace period to
+// finish before the database is closed. A second signal stops the server immediately.
 func Run() {
 	c, err := config.New()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	handler := initialize(c)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app := initialize(ctx, c)
+	logger := app.logger
 
 	srv := &http.Server{
-		Addr:    net.JoinHostPort(c.Host, c.Port),
-		Handler: handler,
+		Addr:              net.JoinHostPort(c.Host, c.Port),
+		Handler:           app.handler,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+
+	if c.TLSCertFile != "" {
+		certs, err := newCertReloader(c.TLSCertFile, c.TLSKeyFile, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go certs.watch(ctx, certReloadInterval)
+
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
 	}
 
-	log.Printf("Server listening on %q", srv.Addr)
-	err = srv.ListenAndServe()
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("server listening", slog.String("addr", srv.Addr), slog.Bool("tls", srv.TLSConfig != nil))
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		app.db.Close()
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	// restore the default signal handling so a second signal kills the process
+	stop()
+
+	drain(srv, app.readiness, logger, c.ShutdownDrainDelay, c.ShutdownGracePeriod, time.Sleep)
+
+	if app.tracerProvider != nil {
+		flushCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownGracePeriod)
+		defer cancel()
+
+		if err := app.tracerProvider.Shutdown(flushCtx); err != nil {
+			logger.Error("flushing traces", slog.String("error", err.Error()))
+		}
+	}
+	if err := app.db.Close(); err != nil {
+		logger.Error("closing database", slog.String("error", err.Error()))
+	}
+	logger.Info("server stopped")
+}
+
+// server is the part of an http.Server drain stops.
+type server interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// drain stops srv taking traffic: readiness fails for drainDelay so load balancers stop routing to
+// srv, then in-flight requests get gracePeriod to finish before srv is closed. sleep waits out the
+// drain delay, it is time.Sleep outside of tests.
+func drain(srv server, readiness *service.Readiness, logger *slog.Logger, drainDelay, gracePeriod time.Duration, sleep func(time.Duration)) {
+	logger.Info("shutting down", slog.Duration("drain_delay", drainDelay), slog.Duration("grace_period", gracePeriod))
+	readiness.Drain()
+	sleep(drainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("requests did not finish within the grace period", slog.String("error", err.Error()))
+		srv.Close()
+	}
+}
+
+// Import loads the NASA astronaut dataset CSV at path into the database and prints the import report,
+// only the database settings need to be set.
+func Import(path string) {
+	c, err := config.NewDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	data, err := service.DecodeAstronautCSV(f)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	dbConn := connect(c)
+	defer dbConn.Close()
+
+	repository := service.NewAuditedDatasetRepo(postgres.NewDatasetRepo(dbConn), postgres.NewAuditRepo(dbConn))
+	report, err := service.ImportAstronautData(context.Background(), repository, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Imported %d, skipped %d, failed %d astronauts", report.Inserted, report.Skipped, report.Failed)
 }
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+	"github.com/stretchr/testify/assert"
+)
+
+// healthyRepo reports a reachable database at migration version 1.
+type healthyRepo struct{}
+
+func (healthyRepo) Ping(ctx context.Context) error { return nil }
+
+func (healthyRepo) SchemaVersion(ctx context.Context) (uint, bool, error) { return 1, false, nil }
+
+// fakeServer records the calls drain makes, its Shutdown waits for the in-flight requests to finish
+// or ctx to be done.
+type fakeServer struct {
+	inFlight chan struct{}
+	calls    []string
+	deadline time.Time
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.calls = append(s.calls, "shutdown")
+	s.deadline, _ = ctx.Deadline()
+
+	select {
+	case <-s.inFlight:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *fakeServer) Close() error {
+	s.calls = append(s.calls, "close")
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	readyz := func(readiness *service.Readiness) int {
+		rec := httptest.NewRecorder()
+		handlers.HandleReadyz(readiness).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	t.Run("fails readiness for the drain delay before shutting down", func(t *testing.T) {
+		readiness := service.NewReadiness(healthyRepo{}, 1)
+		assert.Equal(t, http.StatusOK, readyz(readiness))
+
+		srv := &fakeServer{inFlight: make(chan struct{})}
+		close(srv.inFlight)
+
+		var slept time.Duration
+		sleep := func(d time.Duration) {
+			slept = d
+			assert.Empty(t, srv.calls, "the server shut down before the drain delay")
+			assert.Equal(t, http.StatusServiceUnavailable, readyz(readiness))
+		}
+
+		start := time.Now()
+		drain(srv, readiness, logger, 5*time.Second, 30*time.Second, sleep)
+
+		assert.Equal(t, 5*time.Second, slept)
+		assert.Equal(t, []string{"shutdown"}, srv.calls)
+		assert.WithinDuration(t, start.Add(30*time.Second), srv.deadline, time.Second)
+		assert.Equal(t, http.StatusServiceUnavailable, readyz(readiness))
+	})
+
+	t.Run("closes the server when requests outlast the grace period", func(t *testing.T) {
+		srv := &fakeServer{inFlight: make(chan struct{})}
+
+		start := time.Now()
+		drain(srv, service.NewReadiness(healthyRepo{}, 1), logger, time.Second, 20*time.Millisecond, func(time.Duration) {})
+
+		assert.Equal(t, []string{"shutdown", "close"}, srv.calls)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the certificate files are checked for changes.
+const certReloadInterval = 30 * time.Second
+
+// certReloader serves a TLS certificate loaded from files, reloading it when the files change so a
+// renewed certificate is picked up without a restart. A pair that fails to load is logged and the
+// previous certificate kept.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is a tls.Config GetCertificate func.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch reloads the certificate every interval its files have changed until ctx is done.
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				r.logger.Error("reloading tls certificate", slog.String("error", err.Error()))
+			} else if reloaded {
+				r.logger.Info("reloaded tls certificate", slog.String("cert", r.certFile))
+			}
+		}
+	}
+}
+
+// reload loads the certificate when either file has changed since it was last loaded, reporting
+// whether it did.
+func (r *certReloader) reload() (bool, error) {
+	modified, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && modified.Equal(r.modified)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("loading tls certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.modified = &cert, modified
+	return true, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return latest, fmt.Errorf("loading tls certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
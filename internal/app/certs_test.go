@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self-signed certificate for name and its key to certFile and keyFile, dated
+// modified, and returns the DER bytes of the certificate.
+func writeCert(t *testing.T, certFile, keyFile, name string, modified time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modified)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modified)
+	return der
+}
+
+func writeFile(t *testing.T, name string, data []byte, modified time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+	if err := os.Chtimes(name, modified, modified); err != nil {
+		t.Fatalf("setting the modification time of %s: %v", name, err)
+	}
+}
+
+func servedCert(t *testing.T, r *certReloader) []byte {
+	t.Helper()
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("getting certificate: %v", err)
+	}
+	return cert.Certificate[0]
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	modified := time.Now().Add(-time.Hour)
+
+	t.Run("fails when the files can't be loaded", func(t *testing.T) {
+		_, err := newCertReloader(certFile, keyFile, logger)
+		assert.Error(t, err)
+	})
+
+	first := writeCert(t, certFile, keyFile, "first.test", modified)
+	r, err := newCertReloader(certFile, keyFile, logger)
+	if err != nil {
+		t.Fatalf("unexpected error loading certificate: %v", err)
+	}
+	assert.Equal(t, first, servedCert(t, r))
+
+	t.Run("does not reload unchanged files", func(t *testing.T) {
+		reloaded, err := r.reload()
+		assert.NoError(t, err)
+		assert.False(t, reloaded)
+	})
+
+	modified = modified.Add(time.Minute)
+	second := writeCert(t, certFile, keyFile, "second.test", modified)
+
+	t.Run("reloads files that changed", func(t *testing.T) {
+		reloaded, err := r.reload()
+		assert.NoError(t, err)
+		assert.True(t, reloaded)
+		assert.Equal(t, second, servedCert(t, r))
+	})
+
+	t.Run("keeps the previous certificate when a reload fails", func(t *testing.T) {
+		modified = modified.Add(time.Minute)
+		writeFile(t, certFile, []byte("not a certificate"), modified)
+
+		reloaded, err := r.reload()
+		assert.Error(t, err)
+		assert.False(t, reloaded)
+		assert.Equal(t, second, servedCert(t, r))
+	})
+
+	t.Run("watch reloads the files until ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			r.watch(ctx, 10*time.Millisecond)
+			close(done)
+		}()
+
+		modified = modified.Add(time.Minute)
+		third := writeCert(t, certFile, keyFile, "third.test", modified)
+		assert.Eventually(t, func() bool {
+			cert, _ := r.GetCertificate(nil)
+			return string(cert.Certificate[0]) == string(third)
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("watch did not return once ctx was done")
+		}
+	})
+}
@@ -2,21 +2,236 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
 )
 
-type Config struct {
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+	defaultRateLimitRPS   = 10
+	defaultRateLimitBurst = 20
+	defaultDailyQuota     = 10000
+
+	defaultRetentionPeriod = 30 * 24 * time.Hour
+	defaultPurgeInterval   = time.Hour
+
+	defaultMetricsInterval = time.Minute
+
+	defaultReadTimeout       = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxHeaderBytes    = 1 << 20
+
+	defaultShutdownDrainDelay  = 5 * time.Second
+	defaultShutdownGracePeriod = 30 * time.Second
+
+	defaultTracesExporter = "none"
+	defaultOTLPEndpoint   = "http://localhost:4318"
+	defaultServiceName    = "astronaut-api"
+)
+
+// DB is the database connection settings, which are all that commands other than the api server need.
+type DB struct {
 	DBUsername string
 	DBPassword string
 	DBName     string
 	DBHost     string
 	DBPort     string
 	DBSSLMode  string
-	Port       string
-	Host       string
+}
+
+type Config struct {
+	DB
+
+	Port string
+	Host string
+
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	RateLimits model.RateLimits
+
+	// RetentionPeriod is how long soft deleted rows, and the daily quota counts, are kept before the
+	// purge job deletes them.
+	RetentionPeriod time.Duration
+	PurgeInterval   time.Duration
+
+	// MetricsToken is the bearer token scrapers send to /metrics, metrics are not served when it is
+	// empty.
+	MetricsToken string
+	// MetricsInterval is how often the astronaut and mission counts are refreshed for /metrics.
+	MetricsInterval time.Duration
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// ShutdownDrainDelay is how long the server keeps serving after a shutdown signal while failing
+	// readiness, so load balancers stop routing to it before it stops accepting connections.
+	ShutdownDrainDelay time.Duration
+	// ShutdownGracePeriod is how long in-flight requests are given to finish once it stops accepting
+	// connections.
+	ShutdownGracePeriod time.Duration
+
+	// TLSCertFile and TLSKeyFile serve the api over https when both are set, the files are reloaded
+	// when they change so certificates can be renewed without a restart.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TracesExporter is where spans are sent, one of otlp, stdout or none.
+	TracesExporter string
+	// OTLPEndpoint is the base url of the OpenTelemetry collector receiving OTLP/HTTP.
+	OTLPEndpoint string
+	ServiceName  string
 }
 
 func New() (*Config, error) {
+	db, err := NewDB()
+	if err != nil {
+		return nil, err
+	}
+
+	port, ok := os.LookupEnv("APP_PORT")
+	if !ok {
+		return nil, errors.New("APP_PORT environment variable not set")
+	}
+
+	host, ok := os.LookupEnv("APP_HOST")
+	if !ok {
+		return nil, errors.New("APP_HOST environment variable not set")
+	}
+
+	jwtSecret, ok := os.LookupEnv("JWT_SECRET")
+	if !ok || jwtSecret == "" {
+		return nil, errors.New("JWT_SECRET environment variable not set")
+	}
+
+	accessTTL, err := lookupDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTTL, err := lookupDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimits, err := lookupRateLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := lookupDuration("RETENTION_PERIOD", defaultRetentionPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	purgeInterval, err := lookupDuration("PURGE_INTERVAL", defaultPurgeInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	metricsInterval, err := lookupDuration("METRICS_INTERVAL", defaultMetricsInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	readTimeout, err := lookupDuration("READ_TIMEOUT", defaultReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	readHeaderTimeout, err := lookupDuration("READ_HEADER_TIMEOUT", defaultReadHeaderTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	writeTimeout, err := lookupDuration("WRITE_TIMEOUT", defaultWriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	idleTimeout, err := lookupDuration("IDLE_TIMEOUT", defaultIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	maxHeaderBytes := defaultMaxHeaderBytes
+	if v, ok := os.LookupEnv("MAX_HEADER_BYTES"); ok && v != "" {
+		maxHeaderBytes, err = strconv.Atoi(v)
+		if err != nil || maxHeaderBytes < 1 {
+			return nil, errors.New("MAX_HEADER_BYTES environment variable must be a positive integer")
+		}
+	}
+
+	drainDelay, err := lookupDuration("SHUTDOWN_DRAIN_DELAY", defaultShutdownDrainDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod, err := lookupDuration("SHUTDOWN_GRACE_PERIOD", defaultShutdownGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	certFile, keyFile := lookupString("TLS_CERT_FILE", ""), lookupString("TLS_KEY_FILE", "")
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE environment variables must be set together")
+	}
+
+	exporter := lookupString("OTEL_TRACES_EXPORTER", defaultTracesExporter)
+	if exporter != "otlp" && exporter != "stdout" && exporter != "none" {
+		return nil, errors.New("OTEL_TRACES_EXPORTER environment variable must be one of otlp, stdout or none")
+	}
+
+	return &Config{
+		DB:   *db,
+		Port: port,
+		Host: host,
+
+		JWTSecret:       jwtSecret,
+		AccessTokenTTL:  accessTTL,
+		RefreshTokenTTL: refreshTTL,
+
+		RateLimits: rateLimits,
+
+		RetentionPeriod: retention,
+		PurgeInterval:   purgeInterval,
+
+		MetricsToken:    lookupString("METRICS_TOKEN", ""),
+		MetricsInterval: metricsInterval,
+
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+
+		ShutdownDrainDelay:  drainDelay,
+		ShutdownGracePeriod: gracePeriod,
+
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+
+		TracesExporter: exporter,
+		OTLPEndpoint:   lookupString("OTEL_EXPORTER_OTLP_ENDPOINT", defaultOTLPEndpoint),
+		ServiceName:    lookupString("OTEL_SERVICE_NAME", defaultServiceName),
+	}, nil
+}
+
+// NewDB reads the database connection settings from the environment.
+func NewDB() (*DB, error) {
 	username, ok := os.LookupEnv("DB_USERNAME")
 	if !ok {
 		return nil, errors.New("DB_USERNAME environment variable not set")
@@ -47,24 +262,90 @@ func New() (*Config, error) {
 		return nil, errors.New("DB_SSL_MODE environment variable not set")
 	}
 
-	port, ok := os.LookupEnv("APP_PORT")
-	if !ok {
-		return nil, errors.New("APP_PORT environment variable not set")
-	}
-
-	host, ok := os.LookupEnv("APP_HOST")
-	if !ok {
-		return nil, errors.New("APP_HOST environment variable not set")
-	}
-
-	return &Config{
+	return &DB{
 		DBUsername: username,
 		DBPassword: password,
 		DBName:     dbName,
 		DBHost:     dbHost,
 		DBPort:     dbPort,
 		DBSSLMode:  sslMode,
-		Port:       port,
-		Host:       host,
 	}, nil
 }
+
+// lookupString reads an optional setting from the environment.
+func lookupString(key, fallback string) string {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback
+	}
+	return v
+}
+
+// lookupDuration reads an optional duration such as 15m from the environment.
+func lookupDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s environment variable must be a positive duration e.g. 15m", key)
+	}
+	return d, nil
+}
+
+// lookupRateLimits reads the optional RATE_LIMIT_RPS, RATE_LIMIT_BURST and DAILY_QUOTA settings along
+// with RATE_LIMIT_SCOPES, a comma separated list of scope=rps:burst overrides such as
+// "astronauts:read=5:10,missions:write=1:5".
+func lookupRateLimits() (model.RateLimits, error) {
+	limits := model.RateLimits{
+		Default:    model.RateLimit{Rate: defaultRateLimitRPS, Burst: defaultRateLimitBurst},
+		Scopes:     make(map[string]model.RateLimit),
+		DailyQuota: defaultDailyQuota,
+	}
+
+	if v, ok := os.LookupEnv("RATE_LIMIT_RPS"); ok && v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil || rps <= 0 {
+			return limits, errors.New("RATE_LIMIT_RPS environment variable must be a positive number")
+		}
+		limits.Default.Rate = rps
+	}
+
+	if v, ok := os.LookupEnv("RATE_LIMIT_BURST"); ok && v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil || burst < 1 {
+			return limits, errors.New("RATE_LIMIT_BURST environment variable must be a positive integer")
+		}
+		limits.Default.Burst = burst
+	}
+
+	if v, ok := os.LookupEnv("DAILY_QUOTA"); ok && v != "" {
+		quota, err := strconv.Atoi(v)
+		if err != nil || quota < 0 {
+			return limits, errors.New("DAILY_QUOTA environment variable must be zero, for no quota, or a positive integer")
+		}
+		limits.DailyQuota = quota
+	}
+
+	if v, ok := os.LookupEnv("RATE_LIMIT_SCOPES"); ok && v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			scope, limit, _ := strings.Cut(strings.TrimSpace(entry), "=")
+			rpsStr, burstStr, _ := strings.Cut(limit, ":")
+
+			rps, err := strconv.ParseFloat(rpsStr, 64)
+			if err != nil || rps <= 0 || scope == "" {
+				return limits, fmt.Errorf("RATE_LIMIT_SCOPES entry %q must be of the form scope=rps:burst", entry)
+			}
+
+			burst, err := strconv.Atoi(burstStr)
+			if err != nil || burst < 1 {
+				return limits, fmt.Errorf("RATE_LIMIT_SCOPES entry %q must be of the form scope=rps:burst", entry)
+			}
+
+			limits.Scopes[scope] = model.RateLimit{Rate: rps, Burst: burst}
+		}
+	}
+	return limits, nil
+}
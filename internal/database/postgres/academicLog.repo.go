@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
@@ -17,16 +18,24 @@ func newAcademicRepo(db *sql.DB) *AcademicLogRepository {
 	}
 }
 
+const (
+	majorVersion     = `SELECT version FROM major WHERE id = $1;`
+	almaMaterVersion = `SELECT version FROM alma_mater WHERE id = $1;`
+)
+
 func (r *AcademicLogRepository) CreateMajor(ctx context.Context, m *model.Major) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO major (course) VALUES ($1) RETURNING id;`
+	stmt := `INSERT INTO major (course) VALUES ($1) RETURNING id, version;`
 
-	err = tx.QueryRowContext(ctx, stmt, m.Course).Scan(&m.ID)
+	err = tx.QueryRowContext(ctx, stmt, m.Course).Scan(&m.ID, &m.Version)
 	if err != nil {
 		return err
 	}
@@ -36,14 +45,17 @@ func (r *AcademicLogRepository) CreateMajor(ctx context.Context, m *model.Major)
 }
 
 func (r *AcademicLogRepository) CreateAlmaMater(ctx context.Context, a *model.AlmaMater) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO alma_mater (school) VALUES ($1) RETURNING id;`
-	err = tx.QueryRowContext(ctx, stmt, a.School).Scan(&a.ID)
+	stmt := `INSERT INTO alma_mater (school) VALUES ($1) RETURNING id, version;`
+	err = tx.QueryRowContext(ctx, stmt, a.School).Scan(&a.ID, &a.Version)
 	if err != nil {
 		return err
 	}
@@ -53,13 +65,17 @@ func (r *AcademicLogRepository) CreateAlmaMater(ctx context.Context, a *model.Al
 }
 
 func (r *AcademicLogRepository) AddUnderGradMajor(ctx context.Context, astronautID, majorID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO astronaut_undergrad_major (astronaut_id, major_id) VALUES ($1, $2);`
+	stmt := `INSERT INTO astronaut_undergrad_major (astronaut_id, major_id) SELECT $1, $2
+	WHERE NOT EXISTS (SELECT 1 FROM astronaut_undergrad_major WHERE astronaut_id=$1 AND major_id=$2);`
 	_, err = tx.ExecContext(ctx, stmt, astronautID, majorID)
 	if err != nil {
 		return err
@@ -70,13 +86,17 @@ func (r *AcademicLogRepository) AddUnderGradMajor(ctx context.Context, astronaut
 }
 
 func (r *AcademicLogRepository) AddGradMajor(ctx context.Context, astronautID, majorID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO astronaut_grad_major (astronaut_id, major_id) VALUES ($1, $2);`
+	stmt := `INSERT INTO astronaut_grad_major (astronaut_id, major_id) SELECT $1, $2
+	WHERE NOT EXISTS (SELECT 1 FROM astronaut_grad_major WHERE astronaut_id=$1 AND major_id=$2);`
 	_, err = tx.ExecContext(ctx, stmt, astronautID, majorID)
 	if err != nil {
 		return err
@@ -87,13 +107,17 @@ func (r *AcademicLogRepository) AddGradMajor(ctx context.Context, astronautID, m
 }
 
 func (r *AcademicLogRepository) AddAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO astronaut_alma_mater (astronaut_id, alma_mater_id) VALUES ($1, $2);`
+	stmt := `INSERT INTO astronaut_alma_mater (astronaut_id, alma_mater_id) SELECT $1, $2
+	WHERE NOT EXISTS (SELECT 1 FROM astronaut_alma_mater WHERE astronaut_id=$1 AND alma_mater_id=$2);`
 	_, err = tx.ExecContext(ctx, stmt, astronautID, almaMaterID)
 	if err != nil {
 		return err
@@ -103,59 +127,58 @@ func (r *AcademicLogRepository) AddAstronautAlmaMater(ctx context.Context, astro
 	return nil
 }
 
-func (r *AcademicLogRepository) UpdateMajor(ctx context.Context, m *model.Major) error {
-	tx, err := r.db.Begin()
+func (r *AcademicLogRepository) UpdateMajor(ctx context.Context, m *model.Major, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE major SET course=$1  WHERE id=$2;`
+	stmt := `UPDATE major SET course=$1 WHERE id=$2 AND ($3 = 0 OR version = $3) RETURNING version;`
 
-	result, err := tx.ExecContext(ctx, stmt, m.Course, m.ID)
-	if err != nil {
-		return err
+	err = tx.QueryRowContext(ctx, stmt, m.Course, m.ID, version).Scan(&m.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, tx, majorVersion, m.ID, version)
 	}
-
-	changes, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if changes != 1 {
-		return model.ErrNoChange
-	}
 	tx.Commit()
 
 	return nil
 }
 
-func (r *AcademicLogRepository) UpdateAlmaMater(ctx context.Context, a *model.AlmaMater) error {
-	tx, err := r.db.Begin()
+func (r *AcademicLogRepository) UpdateAlmaMater(ctx context.Context, a *model.AlmaMater, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE alma_mater SET school=$1 WHERE id=$2;`
-	result, err := tx.ExecContext(ctx, stmt, a.School, a.ID)
-	if err != nil {
-		return err
+	stmt := `UPDATE alma_mater SET school=$1 WHERE id=$2 AND ($3 = 0 OR version = $3) RETURNING version;`
+	err = tx.QueryRowContext(ctx, stmt, a.School, a.ID, version).Scan(&a.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, tx, almaMaterVersion, a.ID, version)
 	}
-
-	changes, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if changes != 1 {
-		return model.ErrNoChange
-	}
 	tx.Commit()
 
 	return nil
 }
 
 func (r *AcademicLogRepository) FindMajorByID(ctx context.Context, id int) (*model.Major, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -163,9 +186,9 @@ func (r *AcademicLogRepository) FindMajorByID(ctx context.Context, id int) (*mod
 
 	m := new(model.Major)
 
-	stmt := `SELECT * FROM major WHERE id=$1;`
+	stmt := `SELECT id, course, version FROM major WHERE id=$1;`
 
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.Course)
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.Course, &m.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +198,10 @@ func (r *AcademicLogRepository) FindMajorByID(ctx context.Context, id int) (*mod
 }
 
 func (r *AcademicLogRepository) FindAlmaMaterByID(ctx context.Context, id int) (*model.AlmaMater, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -183,9 +209,9 @@ func (r *AcademicLogRepository) FindAlmaMaterByID(ctx context.Context, id int) (
 
 	m := new(model.AlmaMater)
 
-	stmt := `SELECT * FROM alma_mater WHERE id=$1;`
+	stmt := `SELECT id, school, version FROM alma_mater WHERE id=$1;`
 
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.School)
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.School, &m.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -194,8 +220,75 @@ func (r *AcademicLogRepository) FindAlmaMaterByID(ctx context.Context, id int) (
 	return m, nil
 }
 
+func (r *AcademicLogRepository) FindAllMajors(ctx context.Context) ([]*model.Major, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, course FROM major ORDER BY course;`
+
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var majors []*model.Major
+
+	for rows.Next() {
+		m := new(model.Major)
+		if err := rows.Scan(&m.ID, &m.Course); err != nil {
+			return nil, err
+		}
+		majors = append(majors, m)
+	}
+	tx.Commit()
+
+	return majors, nil
+}
+
+func (r *AcademicLogRepository) FindAllAlmaMaters(ctx context.Context) ([]*model.AlmaMater, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, school FROM alma_mater ORDER BY school;`
+
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var almaMaters []*model.AlmaMater
+
+	for rows.Next() {
+		a := new(model.AlmaMater)
+		if err := rows.Scan(&a.ID, &a.School); err != nil {
+			return nil, err
+		}
+		almaMaters = append(almaMaters, a)
+	}
+	tx.Commit()
+
+	return almaMaters, nil
+}
+
 func (r *AcademicLogRepository) FindAstronautUnderGradMajors(ctx context.Context, astronautID int) ([]*model.Major, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -203,6 +296,7 @@ func (r *AcademicLogRepository) FindAstronautUnderGradMajors(ctx context.Context
 
 	stmt := `SELECT m.id, m.course FROM astronaut_undergrad_major AS u
 	INNER JOIN major AS m ON u.major_id = m.id
+	WHERE u.astronaut_id=$1 AND u.` + activeAstronaut + `
 	ORDER BY m.course;`
 
 	rows, err := tx.QueryContext(ctx, stmt, astronautID)
@@ -226,7 +320,10 @@ func (r *AcademicLogRepository) FindAstronautUnderGradMajors(ctx context.Context
 }
 
 func (r *AcademicLogRepository) FindAstronautGradMajors(ctx context.Context, astronautID int) ([]*model.Major, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -234,6 +331,7 @@ func (r *AcademicLogRepository) FindAstronautGradMajors(ctx context.Context, ast
 
 	stmt := `SELECT m.id, m.course FROM astronaut_grad_major AS g
 	INNER JOIN major AS m ON g.major_id = m.id
+	WHERE g.astronaut_id=$1 AND g.` + activeAstronaut + `
 	ORDER BY m.course;`
 
 	rows, err := tx.QueryContext(ctx, stmt, astronautID)
@@ -257,7 +355,10 @@ func (r *AcademicLogRepository) FindAstronautGradMajors(ctx context.Context, ast
 }
 
 func (r *AcademicLogRepository) FindAstronautAlmaMaters(ctx context.Context, astronautID int) ([]*model.AlmaMater, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -265,6 +366,7 @@ func (r *AcademicLogRepository) FindAstronautAlmaMaters(ctx context.Context, ast
 
 	stmt := `SELECT am.id, am.school FROM astronaut_alma_mater AS aa
 	INNER JOIN alma_mater AS am ON aa.alma_mater_id = am.id
+	WHERE aa.astronaut_id=$1 AND aa.` + activeAstronaut + `
 	ORDER BY am.school;`
 
 	rows, err := tx.QueryContext(ctx, stmt, astronautID)
@@ -287,48 +389,54 @@ func (r *AcademicLogRepository) FindAstronautAlmaMaters(ctx context.Context, ast
 	return almaMaters, nil
 }
 
-func (r *AcademicLogRepository) DeleteMajor(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+func (r *AcademicLogRepository) DeleteMajor(ctx context.Context, id, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM major WHERE id=$1;`
+	stmt := `DELETE FROM astronaut_undergrad_major WHERE major_id=$1;`
 
-	result, err := tx.ExecContext(ctx, stmt, id)
+	_, err = tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
 	}
 
-	changes, err := result.RowsAffected()
+	stmt = `DELETE FROM astronaut_grad_major WHERE major_id=$1;`
+	_, err = tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
 	}
 
-	if changes != 1 {
-		return model.ErrNoChange
-	}
-
-	stmt = `DELETE FROM astronaut_undergrad_major WHERE major_id=$1;`
+	stmt = `DELETE FROM major WHERE id=$1 AND ($2 = 0 OR version = $2);`
 
-	_, err = tx.ExecContext(ctx, stmt, id)
+	result, err := tx.ExecContext(ctx, stmt, id, version)
 	if err != nil {
 		return err
 	}
 
-	stmt = `DELETE FROM astronaut_grad_major WHERE major_id=$1;`
-	_, err = tx.ExecContext(ctx, stmt, id)
+	changes, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+
+	if changes != 1 {
+		return versionError(ctx, tx, majorVersion, id, version)
+	}
 	tx.Commit()
 
 	return nil
 }
 
 func (r *AcademicLogRepository) DeleteAstronautUnderGradMajor(ctx context.Context, astronautID, majorID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
@@ -346,7 +454,10 @@ func (r *AcademicLogRepository) DeleteAstronautUnderGradMajor(ctx context.Contex
 }
 
 func (r *AcademicLogRepository) DeleteAstronautGradMajor(ctx context.Context, astronautID, majorID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
@@ -363,15 +474,24 @@ func (r *AcademicLogRepository) DeleteAstronautGradMajor(ctx context.Context, as
 	return nil
 }
 
-func (r *AcademicLogRepository) DeleteAlmaMater(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+func (r *AcademicLogRepository) DeleteAlmaMater(ctx context.Context, id, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM alma_mater WHERE id=$1;`
-	result, err := tx.ExecContext(ctx, stmt, id)
+	stmt := `DELETE FROM astronaut_alma_mater WHERE alma_mater_id=$1;`
+	_, err = tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	stmt = `DELETE FROM alma_mater WHERE id=$1 AND ($2 = 0 OR version = $2);`
+	result, err := tx.ExecContext(ctx, stmt, id, version)
 	if err != nil {
 		return err
 	}
@@ -382,28 +502,25 @@ func (r *AcademicLogRepository) DeleteAlmaMater(ctx context.Context, id int) err
 	}
 
 	if changes != 1 {
-		return model.ErrNoChange
-	}
-
-	stmt = `DELETE FROM astronaut_alma_mater WHERE id=$1;`
-	_, err = tx.ExecContext(ctx, stmt, id)
-	if err != nil {
-		return err
+		return versionError(ctx, tx, almaMaterVersion, id, version)
 	}
 	tx.Commit()
 
 	return nil
 }
 
-func (r *AcademicLogRepository) DeleteAstronautAlmaMater(ctx context.Context, astronautID, majorID int) error {
-	tx, err := r.db.Begin()
+func (r *AcademicLogRepository) DeleteAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM astronaut_alma_mater WHERE astronaut_id=$1 AND major_id=$2;`
-	_, err = tx.ExecContext(ctx, stmt, astronautID, majorID)
+	stmt := `DELETE FROM astronaut_alma_mater WHERE astronaut_id=$1 AND alma_mater_id=$2;`
+	_, err = tx.ExecContext(ctx, stmt, astronautID, almaMaterID)
 	if err != nil {
 		return err
 	}
@@ -413,6 +530,9 @@ func (r *AcademicLogRepository) DeleteAstronautAlmaMater(ctx context.Context, as
 }
 
 func (r *AcademicLogRepository) GetAcademicLog(ctx context.Context, astronautID int) (*model.AcademicLog, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
 	log := new(model.AcademicLog)
 	var err error
 
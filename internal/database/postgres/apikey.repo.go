@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepo(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertAPIKey(ctx, tx, k); err != nil {
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// insertAPIKey stores k within tx, shared with user registration which creates the first key.
+func insertAPIKey(ctx context.Context, tx *dbTx, k *model.APIKey) error {
+	stmt := `INSERT INTO api_key (user_id, name, prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at;`
+
+	return tx.QueryRowContext(ctx, stmt, k.UserID, k.Name, k.Prefix, k.KeyHash, pq.Array(k.Scopes), k.ExpiresAt).Scan(&k.ID, &k.CreatedAt)
+}
+
+func (r *APIKeyRepository) FindAPIKeys(ctx context.Context, userID int) ([]*model.APIKey, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_key
+    WHERE user_id = $1 ORDER BY id;`
+
+	rows, err := tx.QueryContext(ctx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+
+	for rows.Next() {
+		k := new(model.APIKey)
+		err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	tx.Commit()
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) FindActiveAPIKeysByPrefix(ctx context.Context, prefix string) ([]*model.APIKey, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_key
+    WHERE prefix = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP);`
+
+	rows, err := tx.QueryContext(ctx, stmt, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+
+	for rows.Next() {
+		k := new(model.APIKey)
+		err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, pq.Array(&k.Scopes), &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	tx.Commit()
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, id int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// last use is only recorded to the minute to avoid a write on every request
+	stmt := `UPDATE api_key SET last_used_at=CURRENT_TIMESTAMP
+    WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute');`
+
+	_, err = tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *APIKeyRepository) RotateAPIKey(ctx context.Context, userID, keyID int, next *model.APIKey, graceUntil time.Time) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the new key keeps the lifetime of the old one, counted from now
+	stmt := `INSERT INTO api_key (user_id, name, prefix, key_hash, scopes, expires_at)
+    SELECT user_id, name, $1, $2, scopes, CURRENT_TIMESTAMP + (expires_at - created_at) FROM api_key
+    WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+    RETURNING id, user_id, name, scopes, expires_at, created_at;`
+
+	err = tx.QueryRowContext(ctx, stmt, next.Prefix, next.KeyHash, keyID, userID).Scan(&next.ID, &next.UserID, &next.Name, pq.Array(&next.Scopes), &next.ExpiresAt, &next.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	stmt = `UPDATE api_key SET expires_at=LEAST(COALESCE(expires_at, $1), $1) WHERE id = $2;`
+
+	_, err = tx.ExecContext(ctx, stmt, graceUntil, keyID)
+	if err != nil {
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, userID, keyID int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE api_key SET revoked_at=CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;`
+
+	result, err := tx.ExecContext(ctx, stmt, keyID, userID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := result.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case changes != 1:
+		return model.ErrNoChange
+	}
+	tx.Commit()
+
+	return nil
+}
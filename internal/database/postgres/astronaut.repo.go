@@ -3,7 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
 
@@ -17,16 +20,24 @@ func NewAstronautRepo(db *sql.DB) *AstronautRepository {
 	}
 }
 
+// activeAstronaut hides the records of a soft deleted astronaut, they are kept so they come back on restore.
+const activeAstronaut = `astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NULL)`
+
+const astronautVersion = `SELECT version FROM astronaut WHERE id = $1 AND deleted_at IS NULL;`
+
 func (r *AstronautRepository) CreateAstronaut(ctx context.Context, a *model.Astronaut) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO astronaut (first_name, last_name, gender, birth_date, birth_place) VALUES ($1, $2, $3, $4, $5) RETURNING id;`
+	stmt := `INSERT INTO astronaut (first_name, last_name, gender, birth_date, birth_place) VALUES ($1, $2, $3, $4, $5) RETURNING id, version;`
 
-	err = tx.QueryRowContext(ctx, stmt, a.FirstName, a.LastName, a.Gender, a.BirthDate, a.BirthPlace).Scan(&a.ID)
+	err = tx.QueryRowContext(ctx, stmt, a.FirstName, a.LastName, a.Gender, a.BirthDate, a.BirthPlace).Scan(&a.ID, &a.Version)
 	if err != nil {
 		return err
 	}
@@ -35,7 +46,10 @@ func (r *AstronautRepository) CreateAstronaut(ctx context.Context, a *model.Astr
 }
 
 func (r *AstronautRepository) FindAstronautByID(ctx context.Context, id int) (*model.Astronaut, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +57,8 @@ func (r *AstronautRepository) FindAstronautByID(ctx context.Context, id int) (*m
 
 	a := new(model.Astronaut)
 
-	stmt := `SELECT * FROM astronaut WHERE id = $1;`
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace)
+	stmt := `SELECT id, first_name, last_name, gender, birth_date, birth_place, version FROM astronaut WHERE id = $1 AND deleted_at IS NULL;`
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace, &a.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -53,16 +67,42 @@ func (r *AstronautRepository) FindAstronautByID(ctx context.Context, id int) (*m
 	return a, nil
 }
 
-func (r *AstronautRepository) UpdateAstronaut(ctx context.Context, a *model.Astronaut) error {
-	tx, err := r.db.Begin()
+func (r *AstronautRepository) UpdateAstronaut(ctx context.Context, a *model.Astronaut, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE astronaut SET first_name=$1, last_name=$2, gender=$3, birth_date=$4, birth_place=$5 WHERE id = $6;`
+	stmt := `UPDATE astronaut SET first_name=$1, last_name=$2, gender=$3, birth_date=$4, birth_place=$5
+    WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING version;`
+
+	err = tx.QueryRowContext(ctx, stmt, a.FirstName, a.LastName, a.Gender, a.BirthDate, a.BirthPlace, a.ID, version).Scan(&a.Version)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return versionError(ctx, tx, astronautVersion, a.ID, version)
+	case err != nil:
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+func (r *AstronautRepository) DeleteAstronaut(ctx context.Context, id, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := tx.ExecContext(ctx, stmt, a.FirstName, a.LastName, a.Gender, a.BirthDate, a.BirthPlace, a.ID)
+	stmt := `UPDATE astronaut SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2);`
+	result, err := tx.ExecContext(ctx, stmt, id, version)
 	if err != nil {
 		return err
 	}
@@ -72,20 +112,24 @@ func (r *AstronautRepository) UpdateAstronaut(ctx context.Context, a *model.Astr
 	case err != nil:
 		return err
 	case changes != 1:
-		return model.ErrNoChange
+		return versionError(ctx, tx, astronautVersion, id, version)
 	}
 	tx.Commit()
 	return nil
 }
 
-func (r *AstronautRepository) DeleteAstronaut(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+// RestoreAstronaut brings back a soft deleted astronaut along with their log, military and academic records.
+func (r *AstronautRepository) RestoreAstronaut(ctx context.Context, id int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM astronaut WHERE id = $1;`
+	stmt := `UPDATE astronaut SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL;`
 	result, err := tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
@@ -102,38 +146,125 @@ func (r *AstronautRepository) DeleteAstronaut(ctx context.Context, id int) error
 	return nil
 }
 
-func (r *AstronautRepository) FindAstronauts(ctx context.Context) ([]*model.Astronaut, error) {
-	tx, err := r.db.Begin()
+// PurgeAstronauts hard deletes astronauts soft deleted before the cutoff and every record that belongs to them.
+func (r *AstronautRepository) PurgeAstronauts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"astronaut_log", "military_history", "astronaut_mission", "astronaut_alma_mater", "astronaut_undergrad_major", "astronaut_grad_major"} {
+		stmt := `DELETE FROM ` + table + ` WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at < $1);`
+		if _, err := tx.ExecContext(ctx, stmt, deletedBefore); err != nil {
+			return 0, err
+		}
+	}
+
+	stmt := `DELETE FROM astronaut WHERE deleted_at < $1;`
+	result, err := tx.ExecContext(ctx, stmt, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	tx.Commit()
+
+	return int(purged), nil
+}
+
+var astronautSortable = map[string]string{
+	"id":         "a.id",
+	"firstName":  "a.first_name",
+	"lastName":   "a.last_name",
+	"gender":     "a.gender",
+	"birthDate":  "a.birth_date",
+	"birthPlace": "a.birth_place",
+}
+
+func (r *AstronautRepository) FindAstronauts(ctx context.Context, opts model.QueryOptions, f model.AstronautFilter) (*model.Page[*model.Astronaut], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	q := &listQuery{
+		columns:  "a.id, a.first_name, a.last_name, a.gender, a.birth_date, a.birth_place",
+		from:     "astronaut AS a LEFT JOIN astronaut_log AS l ON l.astronaut_id = a.id",
+		id:       "a.id",
+		sortable: astronautSortable,
+		where:    []string{"a.deleted_at IS NULL"},
+	}
+	if f.Gender != "" {
+		q.filter("a.gender = ?", f.Gender)
+	}
+	if f.BirthYearFrom != 0 {
+		q.filter("EXTRACT(YEAR FROM a.birth_date) >= ?", f.BirthYearFrom)
+	}
+	if f.BirthYearTo != 0 {
+		q.filter("EXTRACT(YEAR FROM a.birth_date) <= ?", f.BirthYearTo)
+	}
+	if f.Status != "" {
+		q.filter("l.status = ?", f.Status)
+	}
+
+	stmt, count, args, keys, err := q.build(opts, []model.SortField{{Field: "lastName"}})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	var astronauts []*model.Astronaut
+	page := new(model.Page[*model.Astronaut])
 
-	stmt := `SELECT * FROM astronaut ORDER BY last_name;`
+	err = tx.QueryRowContext(ctx, count, q.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
 
-	rows, err := tx.QueryContext(ctx, stmt)
+	rows, err := tx.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var last []string
 	for rows.Next() {
+		if len(page.Data) == opts.Limit {
+			page.Next = opts.EncodeCursor(last)
+			break
+		}
+
 		var a model.Astronaut
-		err := rows.Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace)
+		key, dest := keyDest(keys)
+		err := rows.Scan(append([]any{&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace}, dest...)...)
 		if err != nil {
 			return nil, err
 		}
-		astronauts = append(astronauts, &a)
+		page.Data = append(page.Data, &a)
+		last = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 	tx.Commit()
 
-	return astronauts, nil
+	return page, nil
 }
 
 func (r *AstronautRepository) FindAstronautByName(ctx context.Context, name string) ([]*model.Astronaut, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +272,8 @@ func (r *AstronautRepository) FindAstronautByName(ctx context.Context, name stri
 
 	var astronauts []*model.Astronaut
 
-	stmt := `SELECT * FROM astronaut WHERE CONCAT(first_name, ' ', last_name) ILIKE $1 ORDER BY last_name;`
+	stmt := `SELECT id, first_name, last_name, gender, birth_date, birth_place FROM astronaut
+    WHERE CONCAT(first_name, ' ', last_name) ILIKE $1 AND deleted_at IS NULL ORDER BY last_name;`
 	name = fmt.Sprintf("%%%s%%", name)
 
 	rows, err := tx.QueryContext(ctx, stmt, name)
@@ -162,3 +294,64 @@ func (r *AstronautRepository) FindAstronautByName(ctx context.Context, name stri
 
 	return astronauts, nil
 }
+
+func (r *AstronautRepository) FindAstronautAsOf(ctx context.Context, id int, asOf time.Time) (*model.Astronaut, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a := new(model.Astronaut)
+
+	stmt := `SELECT id, first_name, last_name, gender, birth_date, birth_place FROM astronaut_version
+    WHERE id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2);`
+	err = tx.QueryRowContext(ctx, stmt, id, asOf).Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace)
+	if err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return a, nil
+}
+
+func (r *AstronautRepository) FindAstronautVersions(ctx context.Context, id int) ([]*model.Version[*model.Astronaut], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// versions replaced within the transaction that created them were never visible and are skipped
+	stmt := `SELECT id, first_name, last_name, gender, birth_date, birth_place, valid_from, valid_to FROM astronaut_version
+    WHERE id = $1 AND (valid_to IS NULL OR valid_to > valid_from) ORDER BY valid_from, version_id;`
+	rows, err := tx.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*model.Version[*model.Astronaut]
+
+	for rows.Next() {
+		a := new(model.Astronaut)
+		v := &model.Version[*model.Astronaut]{Record: a}
+		err := rows.Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace, &v.ValidFrom, &v.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return versions, nil
+}
@@ -3,6 +3,9 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
+
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
 
@@ -16,18 +19,23 @@ func newAstronautLogRepo(db *sql.DB) *AstronautLogRepository {
 	}
 }
 
+const astronautLogVersion = `SELECT version FROM astronaut_log WHERE astronaut_id = $1 AND ` + activeAstronaut + `;`
+
 func (r *AstronautLogRepository) CreateAstronautLog(ctx context.Context, a *model.AstronautLog) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	stmt := `INSERT INTO astronaut_log (astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
-    status, death_date) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+    status, death_date) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING version;`
 
-	_, err = tx.ExecContext(ctx, stmt, &a.AstronautID, &a.SpaceFlights, &a.SpaceFlightHours, &a.SpaceWalks, &a.SpaceWalkHours,
-		&a.Status, newNullString(a.DeathDate))
+	err = tx.QueryRowContext(ctx, stmt, &a.AstronautID, &a.SpaceFlights, &a.SpaceFlightHours, &a.SpaceWalks, &a.SpaceWalkHours,
+		&a.Status, newNullString(a.DeathDate)).Scan(&a.Version)
 	if err != nil {
 		return err
 	}
@@ -37,7 +45,10 @@ func (r *AstronautLogRepository) CreateAstronautLog(ctx context.Context, a *mode
 }
 
 func (r *AstronautLogRepository) FindAstronautLogById(ctx context.Context, astronautID int) (*model.AstronautLog, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -47,9 +58,9 @@ func (r *AstronautLogRepository) FindAstronautLogById(ctx context.Context, astro
 
 	// returns all fields converting death_date to string type turing null values to empty string
 	stmt := `SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
-    status, COALESCE(death_date::VARCHAR(255), '') AS death_date FROM astronaut_log WHERE astronaut_id=$1;`
+    status, COALESCE(death_date::VARCHAR(255), '') AS death_date, version FROM astronaut_log WHERE astronaut_id=$1 AND ` + activeAstronaut + `;`
 	err = tx.QueryRowContext(ctx, stmt, astronautID).Scan(&aLog.AstronautID, &aLog.SpaceFlights, &aLog.SpaceFlightHours,
-		&aLog.SpaceWalks, &aLog.SpaceWalkHours, &aLog.Status, &aLog.DeathDate)
+		&aLog.SpaceWalks, &aLog.SpaceWalkHours, &aLog.Status, &aLog.DeathDate, &aLog.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +70,10 @@ func (r *AstronautLogRepository) FindAstronautLogById(ctx context.Context, astro
 }
 
 func (r *AstronautLogRepository) FindAstronautLogs(ctx context.Context) ([]*model.AstronautLog, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +82,7 @@ func (r *AstronautLogRepository) FindAstronautLogs(ctx context.Context) ([]*mode
 	var aLogs []*model.AstronautLog
 
 	stmt := `SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs, 
-       status, COALESCE(death_date::VARCHAR(255), '') AS death_date FROM astronaut_log;`
+       status, COALESCE(death_date::VARCHAR(255), '') AS death_date FROM astronaut_log WHERE ` + activeAstronaut + `;`
 	rows, err := tx.QueryContext(ctx, stmt)
 	if err != nil {
 		return nil, err
@@ -89,44 +103,45 @@ func (r *AstronautLogRepository) FindAstronautLogs(ctx context.Context) ([]*mode
 	return aLogs, nil
 }
 
-func (r *AstronautLogRepository) UpdateAstronautLog(ctx context.Context, a *model.AstronautLog) error {
-	tx, err := r.db.Begin()
+func (r *AstronautLogRepository) UpdateAstronautLog(ctx context.Context, a *model.AstronautLog, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	stmt := `UPDATE astronaut_log SET space_flights=$1, space_flight_hrs=$2, space_walks=$3, space_walk_hrs=$4,
-    status=$5, death_date=$6 WHERE astronaut_id=$7;`
+    status=$5, death_date=$6 WHERE astronaut_id=$7 AND ($8 = 0 OR version = $8) AND ` + activeAstronaut + ` RETURNING version;`
 
-	result, err := tx.ExecContext(ctx, stmt, a.SpaceFlights, a.SpaceFlightHours, a.SpaceWalks, a.SpaceWalkHours,
-		a.Status, newNullString(a.DeathDate), a.AstronautID)
-	if err != nil {
-		return err
-	}
-
-	changes, err := result.RowsAffected()
+	err = tx.QueryRowContext(ctx, stmt, a.SpaceFlights, a.SpaceFlightHours, a.SpaceWalks, a.SpaceWalkHours,
+		a.Status, newNullString(a.DeathDate), a.AstronautID, version).Scan(&a.Version)
 	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return versionError(ctx, tx, astronautLogVersion, a.AstronautID, version)
 	case err != nil:
 		return err
-	case changes != 1:
-		return model.ErrNoChange
 	}
 	tx.Commit()
 
 	return nil
 }
 
-func (r *AstronautLogRepository) DeleteAstronautLog(ctx context.Context, astronautID int) error {
-	tx, err := r.db.Begin()
+func (r *AstronautLogRepository) DeleteAstronautLog(ctx context.Context, astronautID, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM astronaut_log WHERE astronaut_id=$1;`
+	stmt := `DELETE FROM astronaut_log WHERE astronaut_id=$1 AND ($2 = 0 OR version = $2) AND ` + activeAstronaut + `;`
 
-	result, err := tx.ExecContext(ctx, stmt, astronautID)
+	result, err := tx.ExecContext(ctx, stmt, astronautID, version)
 	if err != nil {
 		return err
 	}
@@ -135,9 +150,73 @@ func (r *AstronautLogRepository) DeleteAstronautLog(ctx context.Context, astrona
 	case err != nil:
 		return err
 	case changes != 1:
-		return model.ErrNoChange
+		return versionError(ctx, tx, astronautLogVersion, astronautID, version)
 	}
 	tx.Commit()
 
 	return nil
 }
+
+func (r *AstronautLogRepository) FindAstronautLogAsOf(ctx context.Context, astronautID int, asOf time.Time) (*model.AstronautLog, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	aLog := new(model.AstronautLog)
+
+	stmt := `SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+    status, COALESCE(death_date::VARCHAR(255), '') AS death_date FROM astronaut_log_version
+    WHERE astronaut_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2);`
+	err = tx.QueryRowContext(ctx, stmt, astronautID, asOf).Scan(&aLog.AstronautID, &aLog.SpaceFlights, &aLog.SpaceFlightHours,
+		&aLog.SpaceWalks, &aLog.SpaceWalkHours, &aLog.Status, &aLog.DeathDate)
+	if err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return aLog, nil
+}
+
+func (r *AstronautLogRepository) FindAstronautLogVersions(ctx context.Context, astronautID int) ([]*model.Version[*model.AstronautLog], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+    status, COALESCE(death_date::VARCHAR(255), '') AS death_date, valid_from, valid_to FROM astronaut_log_version
+    WHERE astronaut_id = $1 AND (valid_to IS NULL OR valid_to > valid_from) ORDER BY valid_from, version_id;`
+	rows, err := tx.QueryContext(ctx, stmt, astronautID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*model.Version[*model.AstronautLog]
+
+	for rows.Next() {
+		aLog := new(model.AstronautLog)
+		v := &model.Version[*model.AstronautLog]{Record: aLog}
+		err := rows.Scan(&aLog.AstronautID, &aLog.SpaceFlights, &aLog.SpaceFlightHours,
+			&aLog.SpaceWalks, &aLog.SpaceWalkHours, &aLog.Status, &aLog.DeathDate, &v.ValidFrom, &v.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return versions, nil
+}
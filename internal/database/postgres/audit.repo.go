@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (r *AuditRepository) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withinTx(ctx, r.db, fn)
+}
+
+func (r *AuditRepository) CreateAuditEvent(ctx context.Context, e *model.AuditEvent) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO audit_event (actor_id, action, entity_type, entity_id, before, after, request_id)
+    VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at;`
+
+	err = tx.QueryRowContext(ctx, stmt, e.ActorID, e.Action, e.EntityType, e.EntityID, nullJSON(e.Before), nullJSON(e.After), e.RequestID).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+var auditSortable = map[string]string{
+	"id":        "id",
+	"createdAt": "created_at",
+}
+
+func (r *AuditRepository) FindAuditEvents(ctx context.Context, opts model.QueryOptions, f model.AuditFilter) (*model.Page[*model.AuditEvent], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	q := &listQuery{
+		columns:  "id, actor_id, action, entity_type, entity_id, before, after, request_id, created_at",
+		from:     "audit_event",
+		id:       "id",
+		sortable: auditSortable,
+	}
+	if f.EntityType != "" {
+		q.filter("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != "" {
+		q.filter("entity_id = ?", f.EntityID)
+	}
+	if f.ActorID != 0 {
+		q.filter("actor_id = ?", f.ActorID)
+	}
+	if f.From != "" {
+		q.filter("created_at >= ?::TIMESTAMPTZ", f.From)
+	}
+	if len(f.To) == len(time.DateOnly) {
+		// a date includes the whole end day
+		q.filter("created_at < ?::DATE + 1", f.To)
+	} else if f.To != "" {
+		q.filter("created_at <= ?::TIMESTAMPTZ", f.To)
+	}
+
+	stmt, count, args, keys, err := q.build(opts, []model.SortField{{Field: "id", Desc: true}})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	page := new(model.Page[*model.AuditEvent])
+
+	err = tx.QueryRowContext(ctx, count, q.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var last []string
+	for rows.Next() {
+		if len(page.Data) == opts.Limit {
+			page.Next = opts.EncodeCursor(last)
+			break
+		}
+
+		e := new(model.AuditEvent)
+		var before, after []byte
+		key, dest := keyDest(keys)
+		if err := rows.Scan(append([]any{&e.ID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID, &before, &after, &e.RequestID, &e.CreatedAt}, dest...)...); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		page.Data = append(page.Data, e)
+		last = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return page, nil
+}
+
+// nullJSON stores an empty document as NULL rather than invalid jsonb.
+func nullJSON(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
@@ -4,7 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func newNullString(s string) sql.NullString {
@@ -18,10 +18,11 @@ func newNullString(s string) sql.NullString {
 }
 
 func Connect(connStr string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", connStr)
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	db := sql.OpenDB(tracedConnector{connector})
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
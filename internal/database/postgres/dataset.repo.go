@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/lib/pq"
+)
+
+type DatasetRepository struct {
+	db *sql.DB
+}
+
+func NewDatasetRepo(db *sql.DB) *DatasetRepository {
+	return &DatasetRepository{
+		db: db,
+	}
+}
+
+func (r *DatasetRepository) ImportAstronautData(ctx context.Context, data []*model.AstronautData) ([]*model.ImportRow, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows := make([]*model.ImportRow, 0, len(data))
+
+	for _, d := range data {
+		row := &model.ImportRow{Name: d.Name}
+		rows = append(rows, row)
+
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT import_row;`); err != nil {
+			return nil, err
+		}
+
+		row.AstronautID, row.Status, err = importAstronaut(ctx, tx, d)
+		if err != nil {
+			if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT import_row;`); rbErr != nil {
+				return nil, rbErr
+			}
+			row.Status = model.ImportFailed
+			row.Error = err.Error()
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT import_row;`); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// importAstronaut writes a single dataset record, returning the id of its astronaut. An astronaut with
+// the same name and birth date is treated as already imported and skipped.
+func importAstronaut(ctx context.Context, tx *dbTx, d *model.AstronautData) (int, string, error) {
+	a := d.Astronaut()
+
+	stmt := `SELECT id FROM astronaut WHERE first_name=$1 AND last_name=$2 AND birth_date=$3 AND deleted_at IS NULL;`
+	err := tx.QueryRowContext(ctx, stmt, a.FirstName, a.LastName, a.BirthDate).Scan(&a.ID)
+	switch {
+	case err == nil:
+		return a.ID, model.ImportSkipped, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, "", err
+	}
+
+	stmt = `INSERT INTO astronaut (first_name, last_name, gender, birth_date, birth_place) VALUES ($1, $2, $3, $4, $5) RETURNING id;`
+	err = tx.QueryRowContext(ctx, stmt, a.FirstName, a.LastName, a.Gender, a.BirthDate, a.BirthPlace).Scan(&a.ID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	aLog := d.AstronautLog(a.ID)
+	stmt = `INSERT INTO astronaut_log (astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+    status, death_date) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+	_, err = tx.ExecContext(ctx, stmt, aLog.AstronautID, aLog.SpaceFlights, aLog.SpaceFlightHours, aLog.SpaceWalks,
+		aLog.SpaceWalkHours, aLog.Status, newNullString(aLog.DeathDate))
+	if err != nil {
+		return 0, "", err
+	}
+
+	if m := d.MilitaryLog(a.ID); m != nil {
+		stmt = `INSERT INTO military_history (astronaut_id, branch, rank, retired) VALUES ($1, $2, $3, $4);`
+		_, err = tx.ExecContext(ctx, stmt, m.AstronautID, m.Branch, m.Rank, m.Retired)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	for _, school := range d.AlmaMater {
+		stmt = `WITH ins AS (INSERT INTO alma_mater (school) VALUES ($1) ON CONFLICT (school) DO NOTHING RETURNING id)
+		SELECT id FROM ins UNION ALL SELECT id FROM alma_mater WHERE school=$1 LIMIT 1;`
+		id, err := findOrCreate(ctx, tx, stmt, school)
+		if err != nil {
+			return 0, "", err
+		}
+
+		stmt = `INSERT INTO astronaut_alma_mater (astronaut_id, alma_mater_id) VALUES ($1, $2);`
+		if _, err = tx.ExecContext(ctx, stmt, a.ID, id); err != nil {
+			return 0, "", err
+		}
+	}
+
+	majors := []struct {
+		courses []string
+		stmt    string
+	}{
+		{d.UndergraduateMajor, `INSERT INTO astronaut_undergrad_major (astronaut_id, major_id) VALUES ($1, $2);`},
+		{d.GraduateMajor, `INSERT INTO astronaut_grad_major (astronaut_id, major_id) VALUES ($1, $2);`},
+	}
+	for _, m := range majors {
+		for _, course := range m.courses {
+			stmt = `WITH ins AS (INSERT INTO major (course) VALUES ($1) ON CONFLICT (course) DO NOTHING RETURNING id)
+			SELECT id FROM ins UNION ALL SELECT id FROM major WHERE course=$1 LIMIT 1;`
+			id, err := findOrCreate(ctx, tx, stmt, course)
+			if err != nil {
+				return 0, "", err
+			}
+
+			if _, err = tx.ExecContext(ctx, m.stmt, a.ID, id); err != nil {
+				return 0, "", err
+			}
+		}
+	}
+
+	missions := d.Missions
+	if d.DeathMission != "" && !slices.Contains(missions, d.DeathMission) {
+		missions = append(missions, d.DeathMission)
+	}
+	for _, name := range missions {
+		stmt = `WITH ins AS (INSERT INTO mission (name) VALUES ($1) ON CONFLICT (name) WHERE deleted_at IS NULL DO NOTHING RETURNING id)
+		SELECT id FROM ins UNION ALL SELECT id FROM mission WHERE name=$1 AND deleted_at IS NULL LIMIT 1;`
+		id, err := findOrCreate(ctx, tx, stmt, name)
+		if err != nil {
+			return 0, "", err
+		}
+
+		if name == d.DeathMission {
+			stmt = `UPDATE mission SET successful=FALSE WHERE id=$1;`
+			if _, err = tx.ExecContext(ctx, stmt, id); err != nil {
+				return 0, "", err
+			}
+		}
+
+		stmt = `INSERT INTO astronaut_mission (astronaut_id, mission_id) VALUES ($1, $2);`
+		if _, err = tx.ExecContext(ctx, stmt, a.ID, id); err != nil {
+			return 0, "", err
+		}
+	}
+
+	return a.ID, model.ImportInserted, nil
+}
+
+// findOrCreate runs an insert-or-select statement returning the id of the existing or new row.
+func findOrCreate(ctx context.Context, tx *dbTx, stmt, value string) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, stmt, value).Scan(&id)
+	return id, err
+}
+
+// exportBatchSize is the number of rows fetched from the export cursor per round trip.
+const exportBatchSize = 500
+
+// StreamAstronautData reads every astronaut through a server side cursor, calling fn for each record
+// so the full catalogue is never held in memory.
+func (r *DatasetRepository) StreamAstronautData(ctx context.Context, fn func(*model.AstronautData) error) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `DECLARE export_cursor NO SCROLL CURSOR FOR
+	SELECT CONCAT(a.first_name, ' ', a.last_name), a.gender, a.birth_date::VARCHAR(255), a.birth_place,
+		COALESCE(l.status::VARCHAR(255), ''), COALESCE(l.space_flights, 0), COALESCE(l.space_flight_hrs, 0),
+		COALESCE(l.space_walks, 0), COALESCE(l.space_walk_hrs, 0), COALESCE(l.death_date::VARCHAR(255), ''),
+		COALESCE(mh.rank, ''), COALESCE(mh.branch, ''), COALESCE(mh.retired, FALSE),
+		ARRAY(SELECT s.school FROM astronaut_alma_mater AS aa INNER JOIN alma_mater AS s ON s.id = aa.alma_mater_id
+			WHERE aa.astronaut_id = a.id ORDER BY s.school),
+		ARRAY(SELECT m.course FROM astronaut_undergrad_major AS u INNER JOIN major AS m ON m.id = u.major_id
+			WHERE u.astronaut_id = a.id ORDER BY m.course),
+		ARRAY(SELECT m.course FROM astronaut_grad_major AS g INNER JOIN major AS m ON m.id = g.major_id
+			WHERE g.astronaut_id = a.id ORDER BY m.course),
+		ARRAY(SELECT m.name FROM astronaut_mission AS am INNER JOIN mission AS m ON m.id = am.mission_id
+			WHERE am.astronaut_id = a.id AND m.deleted_at IS NULL ORDER BY m.date_of_mission NULLS LAST, m.name),
+		COALESCE((SELECT m.name FROM astronaut_mission AS am INNER JOIN mission AS m ON m.id = am.mission_id
+			WHERE am.astronaut_id = a.id AND m.deleted_at IS NULL AND l.death_date IS NOT NULL AND NOT m.successful
+			ORDER BY m.date_of_mission DESC NULLS LAST LIMIT 1), '')
+	FROM astronaut AS a
+	LEFT JOIN astronaut_log AS l ON l.astronaut_id = a.id
+	LEFT JOIN military_history AS mh ON mh.astronaut_id = a.id
+	WHERE a.deleted_at IS NULL
+	ORDER BY a.id;`
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM export_cursor;`, exportBatchSize)
+
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return err
+		}
+
+		n := 0
+		for rows.Next() {
+			n++
+			d := new(model.AstronautData)
+			var retired bool
+
+			err := rows.Scan(&d.Name, &d.Gender, &d.BirthDate, &d.BirthPlace, &d.Status, &d.SpaceFlights,
+				&d.SpaceFlightHours, &d.SpaceWalks, &d.SpaceWalkHours, &d.DeathDate, &d.MilitaryRank,
+				&d.MilitaryBranch, &retired, pq.Array(&d.AlmaMater), pq.Array(&d.UndergraduateMajor),
+				pq.Array(&d.GraduateMajor), pq.Array(&d.Missions), &d.DeathMission)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			// the dataset marks retired service on the branch, matching AstronautData.MilitaryLog
+			if retired && d.MilitaryBranch != "" {
+				d.MilitaryBranch += " (Retired)"
+			}
+
+			if err := fn(d); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n < exportBatchSize {
+			break
+		}
+	}
+	tx.Commit()
+
+	return nil
+}
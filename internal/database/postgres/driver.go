@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// maxStatementLength caps the db.statement attribute of a span, a method importing the dataset runs
+// thousands of statements.
+const maxStatementLength = 4096
+
+type (
+	// tracedConnector wraps the pq connector so that every statement run with a context carrying a
+	// repository span is recorded on that span.
+	tracedConnector struct {
+		driver.Connector
+	}
+
+	tracedConn struct {
+		driver.Conn
+	}
+)
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tracedConn{conn}, nil
+}
+
+func (c tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rows, err := queryer.QueryContext(ctx, query, args)
+	recordStatement(ctx, query, err)
+	return rows, err
+}
+
+func (c tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := execer.ExecContext(ctx, query, args)
+	recordStatement(ctx, query, err)
+	return res, err
+}
+
+func (c tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// recordStatement appends query to the db.statement attribute of the repository span in ctx, marking
+// the span as failed when the statement failed.
+func recordStatement(ctx context.Context, query string, err error) {
+	span, ok := ctx.Value(querySpanKey{}).(*querySpan)
+	if !ok || !span.IsRecording() || errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	if span.statements != "" {
+		query = span.statements + "\n" + query
+	}
+	if len(query) > maxStatementLength {
+		query = query[:maxStatementLength]
+	}
+	span.statements = query
+	span.SetAttributes(attribute.String("db.statement", query))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
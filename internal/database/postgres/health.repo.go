@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepo(db *sql.DB) *HealthRepository {
+	return &HealthRepository{
+		db: db,
+	}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	return r.db.PingContext(ctx)
+}
+
+func (r *HealthRepository) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	// begun with ctx, unlike the other repositories, so an unreachable database fails the readiness
+	// deadline rather than waiting on a connection
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	// schema_migrations is kept by golang-migrate, which holds a single row
+	stmt := `SELECT version, dirty FROM schema_migrations LIMIT 1;`
+
+	var (
+		version uint
+		dirty   bool
+	)
+	err = tx.QueryRowContext(ctx, stmt).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, err
+	}
+	tx.Commit()
+
+	return version, dirty, nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// querySpan is the span of a repository method along with the statements the method has run so
+	// far, which are recorded on the span as its db.statement attribute.
+	querySpan struct {
+		trace.Span
+		mu         sync.Mutex
+		statements string
+	}
+
+	querySpanKey struct{}
+)
+
+// startQuery starts timing and tracing the repository method that calls it, the returned context
+// carries the method's span so the statements it runs are recorded on it. A method is instrumented
+// with
+//
+//	ctx, end := startQuery(ctx)
+//	defer end()
+func startQuery(ctx context.Context) (context.Context, func()) {
+	repository, method := "unknown", "unknown"
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		// a method name looks like github.com/.../postgres.(*AstronautRepository).FindAstronautByID
+		name := runtime.FuncForPC(pc).Name()
+		name = name[strings.LastIndex(name, "/")+1:]
+		if parts := strings.Split(name, "."); len(parts) == 3 {
+			repository, method = strings.Trim(parts[1], "(*)"), parts[2]
+		}
+	}
+
+	ctx, span := tracing.Start(ctx, repository+"."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", method),
+		),
+	)
+	ctx = context.WithValue(ctx, querySpanKey{}, &querySpan{Span: span})
+
+	start := time.Now()
+	return ctx, func() {
+		queryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+		span.End()
+	}
+}
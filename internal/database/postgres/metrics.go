@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/LaQuannT/astronaut-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var queryDuration = promauto.With(metrics.Default).NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "Time taken by each repository method, including its transaction.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"repository", "method"})
+
+// RegisterPoolMetrics exposes the connection pool statistics of db in reg as the go_sql_* metrics,
+// labelled with the name of the database.
+func RegisterPoolMetrics(reg prometheus.Registerer, db *sql.DB, dbName string) {
+	reg.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
@@ -17,16 +19,21 @@ func newMilitaryLogRepo(db *sql.DB) *MilitaryLogRepository {
 	}
 }
 
+const militaryLogVersion = `SELECT version FROM military_history WHERE astronaut_id = $1 AND ` + activeAstronaut + `;`
+
 func (r *MilitaryLogRepository) CreateMilitaryLog(ctx context.Context, m *model.MilitaryLog) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO military_history (astronaut_id, branch, rank, retired) VALUES ($1, $2, $3, $4);`
+	stmt := `INSERT INTO military_history (astronaut_id, branch, rank, retired) VALUES ($1, $2, $3, $4) RETURNING version;`
 
-	_, err = tx.ExecContext(ctx, stmt, m.AstronautID, m.Branch, m.Rank, m.Retired)
+	err = tx.QueryRowContext(ctx, stmt, m.AstronautID, m.Branch, m.Rank, m.Retired).Scan(&m.Version)
 	if err != nil {
 		return err
 	}
@@ -36,7 +43,10 @@ func (r *MilitaryLogRepository) CreateMilitaryLog(ctx context.Context, m *model.
 }
 
 func (r *MilitaryLogRepository) FindMilitaryLog(ctx context.Context, astronautID int) (*model.MilitaryLog, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -44,9 +54,9 @@ func (r *MilitaryLogRepository) FindMilitaryLog(ctx context.Context, astronautID
 
 	m := new(model.MilitaryLog)
 
-	stmt := `SELECT * FROM military_history WHERE astronaut_id = $1;`
+	stmt := `SELECT astronaut_id, branch, rank, retired, version FROM military_history WHERE astronaut_id = $1 AND ` + activeAstronaut + `;`
 
-	err = tx.QueryRowContext(ctx, stmt, astronautID).Scan(&m.AstronautID, &m.Branch, &m.Rank, &m.Retired)
+	err = tx.QueryRowContext(ctx, stmt, astronautID).Scan(&m.AstronautID, &m.Branch, &m.Rank, &m.Retired, &m.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +66,10 @@ func (r *MilitaryLogRepository) FindMilitaryLog(ctx context.Context, astronautID
 }
 
 func (r *MilitaryLogRepository) FindAllMilitaryLogs(ctx context.Context) ([]*model.MilitaryLog, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +77,7 @@ func (r *MilitaryLogRepository) FindAllMilitaryLogs(ctx context.Context) ([]*mod
 
 	var mLogs []*model.MilitaryLog
 
-	stmt := `SELECT * FROM military_history;`
+	stmt := `SELECT astronaut_id, branch, rank, retired FROM military_history WHERE ` + activeAstronaut + `;`
 
 	rows, err := tx.QueryContext(ctx, stmt)
 	if err != nil {
@@ -84,26 +97,25 @@ func (r *MilitaryLogRepository) FindAllMilitaryLogs(ctx context.Context) ([]*mod
 	return mLogs, nil
 }
 
-func (r *MilitaryLogRepository) UpdateMilitaryLog(ctx context.Context, m *model.MilitaryLog) error {
-	tx, err := r.db.Begin()
+func (r *MilitaryLogRepository) UpdateMilitaryLog(ctx context.Context, m *model.MilitaryLog, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE military_history SET branch=$1, rank=$2, retired=$3 WHERE astronaut_id=$4`
-
-	result, err := tx.ExecContext(ctx, stmt, m.Branch, m.Rank, m.Retired, m.AstronautID)
-	if err != nil {
-		return err
-	}
+	stmt := `UPDATE military_history SET branch=$1, rank=$2, retired=$3
+    WHERE astronaut_id=$4 AND ($5 = 0 OR version = $5) AND ` + activeAstronaut + ` RETURNING version;`
 
-	changes, err := result.RowsAffected()
+	err = tx.QueryRowContext(ctx, stmt, m.Branch, m.Rank, m.Retired, m.AstronautID, version).Scan(&m.Version)
 	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return versionError(ctx, tx, militaryLogVersion, m.AstronautID, version)
 	case err != nil:
 		return err
-	case changes != 1:
-		return model.ErrNoChange
 	}
 
 	tx.Commit()
@@ -111,15 +123,18 @@ func (r *MilitaryLogRepository) UpdateMilitaryLog(ctx context.Context, m *model.
 	return nil
 }
 
-func (r *MilitaryLogRepository) DeleteMilitaryLog(ctx context.Context, astronautID int) error {
-	tx, err := r.db.Begin()
+func (r *MilitaryLogRepository) DeleteMilitaryLog(ctx context.Context, astronautID, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM military_history WHERE astronaut_id=$1`
-	result, err := tx.ExecContext(ctx, stmt, astronautID)
+	stmt := `DELETE FROM military_history WHERE astronaut_id=$1 AND ($2 = 0 OR version = $2) AND ` + activeAstronaut
+	result, err := tx.ExecContext(ctx, stmt, astronautID, version)
 	if err != nil {
 		return err
 	}
@@ -129,9 +144,71 @@ func (r *MilitaryLogRepository) DeleteMilitaryLog(ctx context.Context, astronaut
 	case err != nil:
 		return err
 	case changes != 1:
-		return model.ErrNoChange
+		return versionError(ctx, tx, militaryLogVersion, astronautID, version)
 	}
 	tx.Commit()
 
 	return nil
 }
+
+func (r *MilitaryLogRepository) FindMilitaryLogAsOf(ctx context.Context, astronautID int, asOf time.Time) (*model.MilitaryLog, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m := new(model.MilitaryLog)
+
+	stmt := `SELECT astronaut_id, branch, rank, retired FROM military_history_version
+    WHERE astronaut_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2);`
+
+	err = tx.QueryRowContext(ctx, stmt, astronautID, asOf).Scan(&m.AstronautID, &m.Branch, &m.Rank, &m.Retired)
+	if err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return m, nil
+}
+
+func (r *MilitaryLogRepository) FindMilitaryLogVersions(ctx context.Context, astronautID int) ([]*model.Version[*model.MilitaryLog], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT astronaut_id, branch, rank, retired, valid_from, valid_to FROM military_history_version
+    WHERE astronaut_id = $1 AND (valid_to IS NULL OR valid_to > valid_from) ORDER BY valid_from, version_id;`
+
+	rows, err := tx.QueryContext(ctx, stmt, astronautID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*model.Version[*model.MilitaryLog]
+
+	for rows.Next() {
+		m := new(model.MilitaryLog)
+		v := &model.Version[*model.MilitaryLog]{Record: m}
+		err := rows.Scan(&m.AstronautID, &m.Branch, &m.Rank, &m.Retired, &v.ValidFrom, &v.ValidTo)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return versions, nil
+}
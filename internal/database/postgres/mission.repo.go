@@ -3,7 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
 
@@ -17,16 +20,21 @@ func newMissionRepo(db *sql.DB) *MissionRepository {
 	}
 }
 
+const missionVersion = `SELECT version FROM mission WHERE id = $1 AND deleted_at IS NULL;`
+
 func (r *MissionRepository) CreateMission(ctx context.Context, m *model.Mission) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO mission (name, "alias", date_of_mission, successful) VALUES ($1, $2, $3, $4) RETURNING id;`
+	stmt := `INSERT INTO mission (name, "alias", date_of_mission, successful) VALUES ($1, $2, $3, $4) RETURNING id, version;`
 
-	err = tx.QueryRowContext(ctx, stmt, m.Name, m.Alias, m.DateOfMission, m.Successful).Scan(&m.ID)
+	err = tx.QueryRowContext(ctx, stmt, m.Name, m.Alias, newNullString(m.DateOfMission), m.Successful).Scan(&m.ID, &m.Version)
 	if err != nil {
 		return err
 	}
@@ -35,7 +43,10 @@ func (r *MissionRepository) CreateMission(ctx context.Context, m *model.Mission)
 	return nil
 }
 func (r *MissionRepository) FindMissionByID(ctx context.Context, id int) (*model.Mission, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +54,9 @@ func (r *MissionRepository) FindMissionByID(ctx context.Context, id int) (*model
 
 	m := new(model.Mission)
 
-	stmt := `SELECT * FROM mission WHERE id = $1;`
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.Name, &m.Alias, &m.DateOfMission, &m.Successful)
+	stmt := `SELECT id, name, COALESCE("alias", ''), COALESCE(date_of_mission::VARCHAR(255), ''), successful, version FROM mission
+    WHERE id = $1 AND deleted_at IS NULL;`
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&m.ID, &m.Name, &m.Alias, &m.DateOfMission, &m.Successful, &m.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -54,13 +66,17 @@ func (r *MissionRepository) FindMissionByID(ctx context.Context, id int) (*model
 }
 
 func (r *MissionRepository) FindMissionByNameOrAlias(ctx context.Context, target string) ([]*model.Mission, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	stmt := `SELECT * FROM mission WHERE name ILIKE $1 OR "alias" ILIKE $2 ORDER BY name;`
+	stmt := `SELECT id, name, COALESCE("alias", ''), COALESCE(date_of_mission::VARCHAR(255), ''), successful FROM mission
+    WHERE (name ILIKE $1 OR "alias" ILIKE $2) AND deleted_at IS NULL ORDER BY name;`
 	target = fmt.Sprintf("%%%s%%", target)
 
 	rows, err := tx.QueryContext(ctx, stmt, target, target)
@@ -83,54 +99,101 @@ func (r *MissionRepository) FindMissionByNameOrAlias(ctx context.Context, target
 	return missions, nil
 }
 
-func (r *MissionRepository) FindAllMissions(ctx context.Context) ([]*model.Mission, error) {
-	tx, err := r.db.Begin()
+var missionSortable = map[string]string{
+	"id":            "id",
+	"name":          "name",
+	"alias":         `COALESCE("alias", '')`,
+	"dateOfMission": "COALESCE(date_of_mission, DATE '0001-01-01')",
+	"successful":    "successful",
+}
+
+func (r *MissionRepository) FindAllMissions(ctx context.Context, opts model.QueryOptions, f model.MissionFilter) (*model.Page[*model.Mission], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	q := &listQuery{
+		columns:  `id, name, COALESCE("alias", ''), COALESCE(date_of_mission::VARCHAR(255), ''), successful`,
+		from:     "mission",
+		id:       "id",
+		sortable: missionSortable,
+		where:    []string{"deleted_at IS NULL"},
+	}
+	if f.From != "" {
+		q.filter("date_of_mission >= ?", f.From)
+	}
+	if f.To != "" {
+		q.filter("date_of_mission <= ?", f.To)
+	}
+	if f.Successful != nil {
+		q.filter("successful = ?", *f.Successful)
+	}
+
+	stmt, count, args, keys, err := q.build(opts, []model.SortField{{Field: "id"}})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	stmt := `SELECT * FROM mission;`
-	rows, err := tx.QueryContext(ctx, stmt)
+	page := new(model.Page[*model.Mission])
+
+	err = tx.QueryRowContext(ctx, count, q.args...).Scan(&page.Total)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var missions []*model.Mission
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	var last []string
 	for rows.Next() {
+		if len(page.Data) == opts.Limit {
+			page.Next = opts.EncodeCursor(last)
+			break
+		}
+
 		m := new(model.Mission)
-		if err := rows.Scan(&m.ID, &m.Name, &m.Alias, &m.DateOfMission, &m.Successful); err != nil {
+		key, dest := keyDest(keys)
+		if err := rows.Scan(append([]any{&m.ID, &m.Name, &m.Alias, &m.DateOfMission, &m.Successful}, dest...)...); err != nil {
 			return nil, err
 		}
-		missions = append(missions, m)
+		page.Data = append(page.Data, m)
+		last = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 	tx.Commit()
 
-	return missions, nil
+	return page, nil
 }
 
-func (r *MissionRepository) UpdateMission(ctx context.Context, m *model.Mission) error {
-	tx, err := r.db.Begin()
+func (r *MissionRepository) UpdateMission(ctx context.Context, m *model.Mission, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE mission SET name=$1, alias=$2, date_of_mission=$3, successful=$4 WHERE id=$5;`
-
-	result, err := tx.ExecContext(ctx, stmt, m.Name, m.Alias, m.DateOfMission, m.Successful, m.ID)
-	if err != nil {
-		return err
-	}
+	stmt := `UPDATE mission SET name=$1, alias=$2, date_of_mission=$3, successful=$4
+    WHERE id=$5 AND deleted_at IS NULL AND ($6 = 0 OR version = $6) RETURNING version;`
 
-	changes, err := result.RowsAffected()
+	err = tx.QueryRowContext(ctx, stmt, m.Name, m.Alias, newNullString(m.DateOfMission), m.Successful, m.ID, version).Scan(&m.Version)
 	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return versionError(ctx, tx, missionVersion, m.ID, version)
 	case err != nil:
 		return err
-	case changes != 1:
-		return model.ErrNoChange
 	}
 	tx.Commit()
 
@@ -138,13 +201,18 @@ func (r *MissionRepository) UpdateMission(ctx context.Context, m *model.Mission)
 }
 
 func (r *MissionRepository) CreateAstronautMission(ctx context.Context, astronautID, missionID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO astronaut_mission (astronaut_id, mission_id) VALUES ($1, $2);`
+	// registering an astronaut already on the mission is a no-op
+	stmt := `INSERT INTO astronaut_mission (astronaut_id, mission_id) SELECT $1, $2
+	WHERE NOT EXISTS (SELECT 1 FROM astronaut_mission WHERE astronaut_id=$1 AND mission_id=$2);`
 
 	_, err = tx.ExecContext(ctx, stmt, astronautID, missionID)
 	if err != nil {
@@ -156,15 +224,18 @@ func (r *MissionRepository) CreateAstronautMission(ctx context.Context, astronau
 }
 
 func (r *MissionRepository) FindMissionsByAstronaut(ctx context.Context, astronautID int) ([]*model.Mission, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	stmt := `SELECT m.id, name, "alias", date_of_mission, successful FROM astronaut_mission AS am 
+	stmt := `SELECT m.id, name, COALESCE("alias", ''), COALESCE(date_of_mission::VARCHAR(255), ''), successful FROM astronaut_mission AS am 
 	INNER JOIN mission AS m ON m.id = am.mission_id
-	WHERE am.astronaut_id =$1;`
+	WHERE am.astronaut_id =$1 AND m.deleted_at IS NULL AND am.` + activeAstronaut + `;`
 
 	rows, err := tx.QueryContext(ctx, stmt, astronautID)
 	if err != nil {
@@ -186,8 +257,46 @@ func (r *MissionRepository) FindMissionsByAstronaut(ctx context.Context, astrona
 	return missions, nil
 }
 
+func (r *MissionRepository) FindAstronautsByMission(ctx context.Context, missionID int) ([]*model.Astronaut, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT a.id, a.first_name, a.last_name, a.gender, a.birth_date, a.birth_place FROM astronaut_mission AS am
+	INNER JOIN astronaut AS a ON a.id = am.astronaut_id
+	WHERE am.mission_id=$1 AND a.deleted_at IS NULL
+	AND am.mission_id IN (SELECT id FROM mission WHERE deleted_at IS NULL) ORDER BY a.last_name;`
+
+	rows, err := tx.QueryContext(ctx, stmt, missionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var astronauts []*model.Astronaut
+
+	for rows.Next() {
+		a := new(model.Astronaut)
+		if err := rows.Scan(&a.ID, &a.FirstName, &a.LastName, &a.Gender, &a.BirthDate, &a.BirthPlace); err != nil {
+			return nil, err
+		}
+		astronauts = append(astronauts, a)
+	}
+	tx.Commit()
+
+	return astronauts, nil
+}
+
 func (r *MissionRepository) DeleteAstronautMission(ctx context.Context, astronautID, missionID int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
@@ -213,20 +322,46 @@ func (r *MissionRepository) DeleteAstronautMission(ctx context.Context, astronau
 	return nil
 }
 
-func (r *MissionRepository) DeleteMission(ctx context.Context, missionID int) error {
-	tx, err := r.db.Begin()
+func (r *MissionRepository) DeleteMission(ctx context.Context, missionID, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `DELETE FROM astronaut_mission WHERE mission_id=$1;`
-	_, err = tx.ExecContext(ctx, stmt, missionID)
+	// the crew is kept so it comes back if the mission is restored
+	stmt := `UPDATE mission SET deleted_at = CURRENT_TIMESTAMP WHERE id=$1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2);`
+	result, err := tx.ExecContext(ctx, stmt, missionID, version)
 	if err != nil {
 		return err
 	}
 
-	stmt = `DELETE FROM mission WHERE id=$1;`
+	changes, err := result.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case changes != 1:
+		return versionError(ctx, tx, missionVersion, missionID, version)
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *MissionRepository) RestoreMission(ctx context.Context, missionID int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE mission SET deleted_at = NULL WHERE id=$1 AND deleted_at IS NOT NULL;`
 	result, err := tx.ExecContext(ctx, stmt, missionID)
 	if err != nil {
 		return err
@@ -243,3 +378,35 @@ func (r *MissionRepository) DeleteMission(ctx context.Context, missionID int) er
 
 	return nil
 }
+
+// PurgeMissions hard deletes missions soft deleted before the cutoff along with their crew.
+func (r *MissionRepository) PurgeMissions(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `DELETE FROM astronaut_mission WHERE mission_id IN (SELECT id FROM mission WHERE deleted_at < $1);`
+	_, err = tx.ExecContext(ctx, stmt, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt = `DELETE FROM mission WHERE deleted_at < $1;`
+	result, err := tx.ExecContext(ctx, stmt, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	tx.Commit()
+
+	return int(purged), nil
+}
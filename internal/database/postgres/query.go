@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+// listQuery builds the keyset paged select behind every list method. sortable maps the api sort
+// fields to sql expressions, the id expression is always appended as the final tie breaker so the
+// sort key of a row is unique.
+type listQuery struct {
+	columns  string
+	from     string
+	id       string
+	sortable map[string]string
+	where    []string
+	args     []any
+}
+
+// filter adds a where condition, (?) in cond is replaced by the placeholder for arg.
+func (l *listQuery) filter(cond string, arg any) {
+	l.args = append(l.args, arg)
+	l.where = append(l.where, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(l.args))))
+}
+
+type sortExpr struct {
+	expr string
+	desc bool
+}
+
+// build returns the page select and count statements. The page select returns the listed columns
+// followed by the sort key of each row as text, and fetches one row past the limit to tell if there
+// is a next page.
+func (l *listQuery) build(opts model.QueryOptions, defaultSort []model.SortField) (stmt, count string, args []any, keys int, err error) {
+	fields := opts.Sort
+	if len(fields) == 0 {
+		fields = defaultSort
+	}
+
+	var sort []sortExpr
+	hasID := false
+	for _, f := range fields {
+		expr, ok := l.sortable[f.Field]
+		if !ok {
+			return "", "", nil, 0, fmt.Errorf("%w: unknown sort field %q", model.ErrInvalidQuery, f.Field)
+		}
+		hasID = hasID || expr == l.id
+		sort = append(sort, sortExpr{expr: expr, desc: f.Desc})
+	}
+	if !hasID {
+		sort = append(sort, sortExpr{expr: l.id})
+	}
+
+	where := strings.Join(l.where, " AND ")
+	count = fmt.Sprintf(`SELECT COUNT(*) FROM %s`, l.from)
+	if where != "" {
+		count += " WHERE " + where
+	}
+
+	args = append(args, l.args...)
+	conds := append([]string(nil), l.where...)
+
+	if opts.Cursor != "" {
+		values, err := opts.DecodeCursor(len(sort))
+		if err != nil {
+			return "", "", nil, 0, err
+		}
+
+		// (a > x) OR (a = x AND b > y) ... with the comparison flipped for descending fields
+		var or []string
+		for i, s := range sort {
+			var and []string
+			for j, prev := range sort[:i] {
+				args = append(args, values[j])
+				and = append(and, fmt.Sprintf("%s = $%d", prev.expr, len(args)))
+			}
+			op := ">"
+			if s.desc {
+				op = "<"
+			}
+			args = append(args, values[i])
+			and = append(and, fmt.Sprintf("%s %s $%d", s.expr, op, len(args)))
+			or = append(or, "("+strings.Join(and, " AND ")+")")
+		}
+		conds = append(conds, "("+strings.Join(or, " OR ")+")")
+	}
+
+	selects := make([]string, len(sort))
+	order := make([]string, len(sort))
+	for i, s := range sort {
+		selects[i] = s.expr + "::TEXT"
+		order[i] = s.expr
+		if s.desc {
+			order[i] += " DESC"
+		}
+	}
+
+	stmt = fmt.Sprintf(`SELECT %s, %s FROM %s`, l.columns, strings.Join(selects, ", "), l.from)
+	if len(conds) > 0 {
+		stmt += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, opts.Limit+1)
+	stmt += fmt.Sprintf(` ORDER BY %s LIMIT $%d;`, strings.Join(order, ", "), len(args))
+
+	return stmt, count, args, len(sort), nil
+}
+
+// keyDest returns scan destinations for the sort key columns of a page select.
+func keyDest(n int) ([]string, []any) {
+	keys := make([]string, n)
+	dest := make([]any, n)
+	for i := range keys {
+		dest[i] = &keys[i]
+	}
+	return keys, dest
+}
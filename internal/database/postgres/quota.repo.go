@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type QuotaRepository struct {
+	db *sql.DB
+}
+
+func NewQuotaRepo(db *sql.DB) *QuotaRepository {
+	return &QuotaRepository{
+		db: db,
+	}
+}
+
+func (r *QuotaRepository) IncrementQuota(ctx context.Context, subject string, day time.Time) (int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO api_quota (subject, day, used) VALUES ($1, $2, 1)
+    ON CONFLICT (subject, day) DO UPDATE SET used = api_quota.used + 1
+    RETURNING used;`
+
+	var used int
+
+	err = tx.QueryRowContext(ctx, stmt, subject, day.UTC().Format(time.DateOnly)).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+	tx.Commit()
+
+	return used, nil
+}
+
+func (r *QuotaRepository) PurgeQuotas(ctx context.Context, before time.Time) (int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt := `DELETE FROM api_quota WHERE day < $1;`
+	result, err := tx.ExecContext(ctx, stmt, before.UTC().Format(time.DateOnly))
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	tx.Commit()
+
+	return int(purged), nil
+}
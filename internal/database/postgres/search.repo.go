@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/lib/pq"
+)
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepo(db *sql.DB) *SearchRepository {
+	return &SearchRepository{
+		db: db,
+	}
+}
+
+func (r *SearchRepository) Search(ctx context.Context, q model.SearchQuery) ([]*model.SearchResult, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	// a document matches on full text or when the query is a close trigram match to one of its words,
+	// which catches typos such as "armstong". The snippet text is html escaped before the matches are
+	// wrapped in <b></b> so stored markup is never returned as html, the parser keeps the escapes whole.
+	stmt := `WITH query AS (
+        SELECT websearch_to_tsquery('simple', search_text($1)) AS tsq, search_text($1) AS text
+    )
+    SELECT d.type, d.id, d.title, d.detail,
+           ts_headline('simple',
+               REPLACE(REPLACE(REPLACE(REPLACE(CONCAT_WS(' ', d.title, NULLIF(d.detail, '')),
+                   '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+               q.tsq, 'StartSel=<b>, StopSel=</b>, HighlightAll=TRUE'),
+           GREATEST(ts_rank(to_tsvector('simple', d.document), q.tsq), word_similarity(q.text, d.document)) AS rank
+    FROM search_document AS d, query AS q
+    WHERE (to_tsvector('simple', d.document) @@ q.tsq OR q.text <% d.document)
+      AND (CARDINALITY($2::TEXT[]) = 0 OR d.type = ANY($2))
+    ORDER BY rank DESC, d.type, d.id
+    LIMIT $3;`
+
+	types := q.Types
+	if types == nil {
+		types = []string{}
+	}
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, stmt, q.Text, pq.Array(types), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.SearchResult
+
+	for rows.Next() {
+		s := new(model.SearchResult)
+		if err := rows.Scan(&s.Type, &s.ID, &s.Title, &s.Detail, &s.Snippet, &s.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return results, nil
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepo(db *sql.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, s *model.Session, refreshTokenHash string) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO session (user_id, refresh_token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id;`
+
+	err = tx.QueryRowContext(ctx, stmt, s.UserID, refreshTokenHash, s.ExpiresAt.UTC()).Scan(&s.ID)
+	if err != nil {
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *SessionRepository) FindSessionByID(ctx context.Context, id string) (*model.Session, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, user_id, expires_at, revoked_at IS NOT NULL FROM session WHERE id = $1;`
+
+	s := new(model.Session)
+
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&s.ID, &s.UserID, &s.ExpiresAt, &s.Revoked)
+	if err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return s, nil
+}
+
+func (r *SessionRepository) FindSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (*model.Session, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT id, user_id, expires_at, revoked_at IS NOT NULL FROM session WHERE refresh_token_hash = $1;`
+
+	s := new(model.Session)
+
+	err = tx.QueryRowContext(ctx, stmt, refreshTokenHash).Scan(&s.ID, &s.UserID, &s.ExpiresAt, &s.Revoked)
+	if err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return s, nil
+}
+
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// matching on the old hash stops two concurrent refreshes from both succeeding
+	stmt := `UPDATE session SET refresh_token_hash=$1, expires_at=$2
+    WHERE id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL;`
+
+	result, err := tx.ExecContext(ctx, stmt, newHash, expiresAt.UTC(), id, oldHash)
+	if err != nil {
+		return err
+	}
+
+	changes, err := result.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case changes != 1:
+		return model.ErrNoChange
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, id string) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE session SET revoked_at=CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL;`
+
+	result, err := tx.ExecContext(ctx, stmt, id)
+	if err != nil {
+		return err
+	}
+
+	changes, err := result.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case changes != 1:
+		return model.ErrNoChange
+	}
+	tx.Commit()
+
+	return nil
+}
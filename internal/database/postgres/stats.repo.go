@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+type StatsRepository struct {
+	db *sql.DB
+}
+
+func NewStatsRepo(db *sql.DB) *StatsRepository {
+	return &StatsRepository{
+		db: db,
+	}
+}
+
+func (r *StatsRepository) CountAstronautsByStatus(ctx context.Context) (map[string]int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT COALESCE(l.status, 'unknown'), COUNT(*) FROM astronaut AS a
+    LEFT JOIN astronaut_log AS l ON l.astronaut_id = a.id
+    WHERE a.deleted_at IS NULL GROUP BY 1;`
+
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			status string
+			n      int
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return counts, nil
+}
+
+func (r *StatsRepository) CountMissionsBySuccess(ctx context.Context) (map[bool]int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt := `SELECT successful, COUNT(*) FROM mission WHERE deleted_at IS NULL GROUP BY successful;`
+
+	rows, err := tx.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[bool]int)
+	for rows.Next() {
+		var (
+			successful bool
+			n          int
+		)
+		if err := rows.Scan(&successful, &n); err != nil {
+			return nil, err
+		}
+		counts[successful] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tx.Commit()
+
+	return counts, nil
+}
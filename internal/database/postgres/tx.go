@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// txAttempts is how many times a transaction failing on a concurrent change is run before giving up.
+const txAttempts = 3
+
+type txKey struct{}
+
+// dbTx is the transaction a repository method runs in. Within a transaction started by withinTx the
+// method joins it, leaving the commit or rollback to the caller that started it.
+type dbTx struct {
+	*sql.Tx
+	joined bool
+}
+
+func (t *dbTx) Commit() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t *dbTx) Rollback() error {
+	if t.joined {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// begin starts a transaction on db, or joins the one ctx is within.
+func begin(ctx context.Context, db *sql.DB) (*dbTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &dbTx{Tx: tx, joined: true}, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &dbTx{Tx: tx}, nil
+}
+
+// withinTx runs fn in a repeatable read transaction that repositories called with the ctx fn is given
+// join, so their changes commit or roll back together. A row read before it is changed is the row the
+// change applies to, as a concurrent change to it fails the transaction, which is then run again. A
+// ctx already within a transaction runs fn in that one.
+func withinTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+	for range txAttempts {
+		err = runTx(ctx, db, fn)
+
+		var pgErr *pq.Error
+		if !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+			return err
+		}
+	}
+	return err
+}
+
+func runTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
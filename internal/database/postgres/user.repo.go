@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
@@ -17,24 +19,27 @@ func newUserRepo(db *sql.DB) *UserRepository {
 	}
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, u *model.User) error {
-	tx, err := r.db.Begin()
+const userVersion = `SELECT version FROM "user" WHERE id = $1 AND deleted_at IS NULL;`
+
+func (r *UserRepository) CreateUser(ctx context.Context, u *model.User, k *model.APIKey) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO "user" (first_name, last_name, email, password) VALUES ($1, $2, $3, $4) RETURNING id, created_at;`
+	stmt := `INSERT INTO "user" (first_name, last_name, email, password) VALUES ($1, $2, $3, $4) RETURNING id, created_at, version;`
 
-	err = tx.QueryRowContext(ctx, stmt, u.FirstName, u.LastName, u.Email, u.Password).Scan(&u.ID, &u.CreatedAt)
+	err = tx.QueryRowContext(ctx, stmt, u.FirstName, u.LastName, u.Email, u.Password).Scan(&u.ID, &u.CreatedAt, &u.Version)
 	if err != nil {
 		return err
 	}
 
-	stmt = `INSERT INTO api_key (user_id) VALUES ($1) RETURNING key;`
-
-	err = tx.QueryRowContext(ctx, stmt, u.ID).Scan(&u.APIKey)
-	if err != nil {
+	k.UserID = u.ID
+	if err := insertAPIKey(ctx, tx, k); err != nil {
 		return err
 	}
 	tx.Commit()
@@ -43,18 +48,20 @@ func (r *UserRepository) CreateUser(ctx context.Context, u *model.User) error {
 }
 
 func (r *UserRepository) FindUserByID(ctx context.Context, id int) (*model.User, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	stmt := `SELECT u.id, u.first_name, u.last_name, u.email, u.password, a.key, u.created_at, u.updated_at FROM "user" AS U 
-         INNER JOIN api_key AS a ON a.user_id = u.id WHERE u.id = $1;`
+	stmt := `SELECT id, first_name, last_name, email, password, created_at, updated_at, version FROM "user" WHERE id = $1 AND deleted_at IS NULL;`
 
 	u := new(model.User)
 
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.APIKey, &u.CreatedAt, &u.UpdatedAt)
+	err = tx.QueryRowContext(ctx, stmt, id).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt, &u.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -64,18 +71,20 @@ func (r *UserRepository) FindUserByID(ctx context.Context, id int) (*model.User,
 }
 
 func (r *UserRepository) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	stmt := `SELECT u.id, u.first_name, u.last_name, u.email, u.password, a.key, u.created_at, u.updated_at FROM "user" AS U 
-         INNER JOIN api_key AS a ON a.user_id = u.id WHERE u.email = $1;`
+	stmt := `SELECT id, first_name, last_name, email, password, created_at, updated_at, version FROM "user" WHERE email = $1 AND deleted_at IS NULL;`
 
 	u := new(model.User)
 
-	err = tx.QueryRowContext(ctx, stmt, email).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.APIKey, &u.CreatedAt, &u.UpdatedAt)
+	err = tx.QueryRowContext(ctx, stmt, email).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt, &u.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -84,44 +93,97 @@ func (r *UserRepository) FindUserByEmail(ctx context.Context, email string) (*mo
 	return u, nil
 }
 
-func (r *UserRepository) FindAllUsers(ctx context.Context) ([]*model.User, error) {
-	tx, err := r.db.Begin()
+var userSortable = map[string]string{
+	"id":        "id",
+	"firstName": "first_name",
+	"lastName":  "last_name",
+	"email":     "email",
+	"createdAt": "created_at",
+}
+
+func (r *UserRepository) FindAllUsers(ctx context.Context, opts model.QueryOptions, f model.UserFilter) (*model.Page[*model.User], error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	q := &listQuery{
+		columns:  "id, first_name, last_name, email, created_at, updated_at",
+		from:     `"user"`,
+		id:       "id",
+		sortable: userSortable,
+		where:    []string{"deleted_at IS NULL"},
+	}
+	if f.CreatedFrom != "" {
+		q.filter("created_at >= ?", f.CreatedFrom)
+	}
+	if f.CreatedTo != "" {
+		// the range is inclusive of the whole end day
+		q.filter("created_at < ?::DATE + 1", f.CreatedTo)
+	}
+
+	stmt, count, args, keys, err := q.build(opts, []model.SortField{{Field: "id"}})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	var users []*model.User
+	page := new(model.Page[*model.User])
 
-	stmt := `SELECT id, first_name, last_name, email, created_at, updated_at FROM "user";`
-	rows, err := tx.QueryContext(ctx, stmt)
+	err = tx.QueryRowContext(ctx, count, q.args...).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var last []string
 	for rows.Next() {
+		if len(page.Data) == opts.Limit {
+			page.Next = opts.EncodeCursor(last)
+			break
+		}
+
 		u := new(model.User)
-		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		key, dest := keyDest(keys)
+		if err := rows.Scan(append([]any{&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.CreatedAt, &u.UpdatedAt}, dest...)...); err != nil {
 			return nil, err
 		}
-		users = append(users, u)
+		page.Data = append(page.Data, u)
+		last = key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 	tx.Commit()
 
-	return users, nil
+	return page, nil
 }
 
-func (r *UserRepository) UpdateUser(ctx context.Context, u *model.User) error {
-	tx, err := r.db.Begin()
+func (r *UserRepository) UpdateUser(ctx context.Context, u *model.User, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE "user" SET first_name=$1, last_name=$2, email=$3 WHERE id = $4;`
+	stmt := `UPDATE "user" SET first_name=$1, last_name=$2, email=$3
+    WHERE id = $4 AND deleted_at IS NULL AND ($5 = 0 OR version = $5) RETURNING version;`
 
-	_, err = tx.ExecContext(ctx, stmt, u.FirstName, u.LastName, u.Email, u.ID)
+	err = tx.QueryRowContext(ctx, stmt, u.FirstName, u.LastName, u.Email, u.ID, version).Scan(&u.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return versionError(ctx, tx, userVersion, u.ID, version)
+	}
 	if err != nil {
 		return err
 	}
@@ -131,13 +193,16 @@ func (r *UserRepository) UpdateUser(ctx context.Context, u *model.User) error {
 }
 
 func (r *UserRepository) RestUserPassword(ctx context.Context, hash string, id int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `UPDATE "user" SET password=$1 WHERE id = $2;`
+	stmt := `UPDATE "user" SET password=$1 WHERE id = $2 AND deleted_at IS NULL;`
 
 	result, err := tx.ExecContext(ctx, stmt, hash, id)
 	if err != nil {
@@ -156,38 +221,47 @@ func (r *UserRepository) RestUserPassword(ctx context.Context, hash string, id i
 	return nil
 }
 
-func (r *UserRepository) GenerateNewUserAPIKey(ctx context.Context, id int) (string, error) {
-	tx, err := r.db.Begin()
+func (r *UserRepository) DeleteUser(ctx context.Context, id, version int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer tx.Rollback()
 
-	var key string
-
-	stmt := `UPDATE api_key SET key=uuid_generate_v4() WHERE user_id = $1 RETURNING key;`
-	err = tx.QueryRowContext(ctx, stmt, id).Scan(&key)
+	// keys, sessions and admin rights are kept for a restore, they stop working as the user can no longer be found
+	stmt := `UPDATE "user" SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2);`
+	result, err := tx.ExecContext(ctx, stmt, id, version)
 	if err != nil {
-		return "", err
+		return err
 	}
-	tx.Commit()
-	return key, nil
-}
 
-func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+	changes, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	stmt := `DELETE FROM api_key WHERE user_id = $1;`
-	_, err = tx.ExecContext(ctx, stmt, id)
+	if changes != 1 {
+		return versionError(ctx, tx, userVersion, id, version)
+	}
+	tx.Commit()
+
+	return nil
+}
+
+func (r *UserRepository) RestoreUser(ctx context.Context, id int) error {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	stmt = `DELETE FROM "user" WHERE id = $1;`
+	stmt := `UPDATE "user" SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL;`
 	result, err := tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
@@ -201,26 +275,56 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
 	if changes != 1 {
 		return model.ErrNoChange
 	}
+	tx.Commit()
 
-	stmt = `DELETE FROM admin WHERE EXISTS(SELECT * FROM admin WHERE id = $1);`
-	_, err = tx.ExecContext(ctx, stmt, id)
+	return nil
+}
+
+// PurgeUsers hard deletes users soft deleted before the cutoff along with their keys and admin rights.
+func (r *UserRepository) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"api_key", "admin"} {
+		stmt := `DELETE FROM ` + table + ` WHERE user_id IN (SELECT id FROM "user" WHERE deleted_at < $1);`
+		if _, err := tx.ExecContext(ctx, stmt, deletedBefore); err != nil {
+			return 0, err
+		}
+	}
+
+	stmt := `DELETE FROM "user" WHERE deleted_at < $1;`
+	result, err := tx.ExecContext(ctx, stmt, deletedBefore)
+	if err != nil {
+		return 0, err
 	}
 
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
 	tx.Commit()
 
-	return nil
+	return int(purged), nil
 }
 
 func (r *UserRepository) GiveAdminPrivileges(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt := `INSERT INTO admin (user_id) VALUES ($1);`
+	// granting privileges to an existing admin is a no-op
+	stmt := `INSERT INTO admin (user_id) SELECT $1 WHERE NOT EXISTS (SELECT 1 FROM admin WHERE user_id=$1);`
 	_, err = tx.ExecContext(ctx, stmt, id)
 	if err != nil {
 		return err
@@ -231,7 +335,10 @@ func (r *UserRepository) GiveAdminPrivileges(ctx context.Context, id int) error
 }
 
 func (r *UserRepository) RevokeAdminPrivileges(ctx context.Context, id int) error {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return err
 	}
@@ -256,30 +363,11 @@ func (r *UserRepository) RevokeAdminPrivileges(ctx context.Context, id int) erro
 	return nil
 }
 
-func (r *UserRepository) FindUserByAPIKey(ctx context.Context, key string) (*model.User, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
-	stmt := `SELECT  u.id, u.first_name, u.last_name, u.email, u.password, a.key, u.created_at, u.updated_at  FROM api_key AS a 
-    INNER JOIN "user" AS u ON u.id = a.user_id
-    WHERE a.key = $1;`
-
-	u := new(model.User)
-
-	err = tx.QueryRowContext(ctx, stmt, key).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Password, &u.APIKey, &u.CreatedAt, &u.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	tx.Commit()
-
-	return u, nil
-}
-
 func (r *UserRepository) IsAdmin(ctx context.Context, userID int) (int, error) {
-	tx, err := r.db.Begin()
+	ctx, end := startQuery(ctx)
+	defer end()
+
+	tx, err := begin(ctx, r.db)
 	if err != nil {
 		return 0, err
 	}
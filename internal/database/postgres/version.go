@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+// Versioned writes are guarded with ($n = 0 OR version = $n) so model.AnyVersion skips the check.
+
+// versionError explains why a versioned write matched no row, stmt selects the current version of the
+// row with id. It returns model.ErrNoChange when the row does not exist.
+func versionError(ctx context.Context, tx *dbTx, stmt string, id, expected int) error {
+	var current int
+	err := tx.QueryRowContext(ctx, stmt, id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return model.ErrNoChange
+	case err != nil:
+		return err
+	}
+	return &model.VersionConflictError{Expected: expected, Current: current}
+}
@@ -0,0 +1,44 @@
+// Package metrics holds the Prometheus registry the api's collectors are registered with and the
+// handler serving it for scraping.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry served at /metrics, it also collects the Go runtime and process metrics.
+var Default = prometheus.NewRegistry()
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics gathered from reg to scrapers sending token as a bearer token. The
+// metrics expose the routes and connection pool of the api so they are not served at all when token
+// is empty.
+func Handler(reg prometheus.Gatherer, token string) http.Handler {
+	h := promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		want := []byte("Bearer " + token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScopeAstronautsRead  = "astronauts:read"
+	ScopeAstronautsWrite = "astronauts:write"
+	ScopeMissionsRead    = "missions:read"
+	ScopeMissionsWrite   = "missions:write"
+	ScopeUsersRead       = "users:read"
+	ScopeUsersWrite      = "users:write"
+	ScopeUsersAdmin      = "users:admin"
+
+	// DefaultKeyName names the key created on registration and by the legacy key reset.
+	DefaultKeyName = "default"
+
+	// APIKeyPrefix starts every generated key, which has the form ak_<8 hex chars>_<43 char secret>.
+	// The first part up to the second (_) is stored in the clear to find the key.
+	APIKeyPrefix      = "ak_"
+	apiKeyPrefixLen   = len(APIKeyPrefix) + 8
+	apiKeySecretLen   = 43
+	legacyKeyPrefix   = "legacy_"
+	legacyKeyLen      = 36
+	legacyKeyPrefixID = 8
+)
+
+// AllScopes is every scope a key can hold, the default key is given all of them.
+var AllScopes = []string{
+	ScopeAstronautsRead,
+	ScopeAstronautsWrite,
+	ScopeMissionsRead,
+	ScopeMissionsWrite,
+	ScopeUsersRead,
+	ScopeUsersWrite,
+	ScopeUsersAdmin,
+}
+
+type (
+	// APIKey is one of a user's named keys. Only the prefix and a SHA-256 digest of the key are stored,
+	// Key is set when the key is created and never returned again.
+	APIKey struct {
+		ID         int        `json:"id"`
+		UserID     int        `json:"userId"`
+		Name       string     `json:"name"`
+		Prefix     string     `json:"prefix"`
+		Key        string     `json:"key,omitempty"`
+		KeyHash    string     `json:"-"`
+		Scopes     []string   `json:"scopes"`
+		ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+		LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+		RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+		CreatedAt  time.Time  `json:"createdAt"`
+	}
+
+	APIKeyRepository interface {
+		// CreateAPIKey stores k by its Prefix and KeyHash.
+		CreateAPIKey(ctx context.Context, k *APIKey) error
+		FindAPIKeys(ctx context.Context, userID int) ([]*APIKey, error)
+		// FindActiveAPIKeysByPrefix returns the unrevoked, unexpired keys with prefix, KeyHash included.
+		FindActiveAPIKeysByPrefix(ctx context.Context, prefix string) ([]*APIKey, error)
+		// TouchAPIKey records a key as used.
+		TouchAPIKey(ctx context.Context, id int) error
+		// RotateAPIKey stores next, given its Prefix and KeyHash, with the name and scopes of an active
+		// key. The old key stays usable until graceUntil.
+		RotateAPIKey(ctx context.Context, userID, keyID int, next *APIKey, graceUntil time.Time) error
+		RevokeAPIKey(ctx context.Context, userID, keyID int) error
+	}
+)
+
+func (k *APIKey) Valid() (Problems, bool) {
+	var problems Problems
+	if k.Name == "" {
+		problems.Add("name", CodeRequired, "name must not be empty")
+	} else if len(k.Name) > 255 {
+		problems.Add("name", CodeTooLong, "name must not be longer than 255 characters")
+	}
+	if len(k.Scopes) == 0 {
+		problems.Add("scopes", CodeRequired, "scopes must not be empty")
+	}
+	for _, s := range k.Scopes {
+		if !slices.Contains(AllScopes, s) {
+			problems.Add("scopes", CodeInvalidValue, "scopes must only contain known scopes")
+			break
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(time.Now()) {
+		problems.Add("expiresAt", CodeInvalidValue, "expiresAt must be in the future")
+	}
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// APIKeyLookupPrefix returns the stored prefix a presented key is found by. Keys issued before keys
+// were hashed are uuids and are found by their first 8 characters.
+func APIKeyLookupPrefix(key string) (string, bool) {
+	switch {
+	case strings.HasPrefix(key, APIKeyPrefix):
+		if len(key) != apiKeyPrefixLen+1+apiKeySecretLen || key[apiKeyPrefixLen] != '_' {
+			return "", false
+		}
+		if _, err := hex.DecodeString(key[len(APIKeyPrefix):apiKeyPrefixLen]); err != nil {
+			return "", false
+		}
+		return key[:apiKeyPrefixLen], true
+
+	case len(key) == legacyKeyLen:
+		if _, err := uuid.Parse(key); err != nil {
+			return "", false
+		}
+		return legacyKeyPrefix + strings.ToLower(key[:legacyKeyPrefixID]), true
+
+	default:
+		return "", false
+	}
+}
+
+type requestKey struct{}
+
+// ContextWithAPIKey records the key a request was authenticated with.
+func ContextWithAPIKey(ctx context.Context, k *APIKey) context.Context {
+	return context.WithValue(ctx, requestKey{}, k)
+}
+
+// APIKeyFromContext returns the key the request was authenticated with, requests authenticated
+// with an access token have none.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	k, ok := ctx.Value(requestKey{}).(*APIKey)
+	return k, ok
+}
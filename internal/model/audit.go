@@ -0,0 +1,129 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	// AuditPurge is recorded once per purge run with the number of rows hard deleted.
+	AuditPurge = "purge"
+	// AuditImport is recorded for each astronaut a dataset import creates, with the imported record
+	// holding the logs, education and missions created along with it.
+	AuditImport = "import"
+)
+
+// entity types recorded in the audit trail, named after the table the change was made to
+const (
+	EntityAstronaut               = "astronaut"
+	EntityAstronautLog            = "astronaut_log"
+	EntityMilitaryLog             = "military_history"
+	EntityMission                 = "mission"
+	EntityMissionCrew             = "astronaut_mission"
+	EntityMajor                   = "major"
+	EntityAlmaMater               = "alma_mater"
+	EntityAstronautUndergradMajor = "astronaut_undergrad_major"
+	EntityAstronautGradMajor      = "astronaut_grad_major"
+	EntityAstronautAlmaMater      = "astronaut_alma_mater"
+	EntityUser                    = "user"
+	EntityUserPassword            = "user_password"
+	EntityAdmin                   = "admin"
+	EntityAPIKey                  = "api_key"
+)
+
+var auditEntities = []string{
+	EntityAstronaut,
+	EntityAstronautLog,
+	EntityMilitaryLog,
+	EntityMission,
+	EntityMissionCrew,
+	EntityMajor,
+	EntityAlmaMater,
+	EntityAstronautUndergradMajor,
+	EntityAstronautGradMajor,
+	EntityAstronautAlmaMater,
+	EntityUser,
+	EntityUserPassword,
+	EntityAdmin,
+	EntityAPIKey,
+}
+
+type (
+	// AuditEvent records a single change. ActorID is nil for changes made without credentials, such as
+	// registration. Links between two records have an EntityID of both ids joined by (/).
+	AuditEvent struct {
+		ID         int             `json:"id"`
+		ActorID    *int            `json:"actorId"`
+		Action     string          `json:"action"`
+		EntityType string          `json:"entityType"`
+		EntityID   string          `json:"entityId"`
+		Before     json.RawMessage `json:"before,omitempty"`
+		After      json.RawMessage `json:"after,omitempty"`
+		RequestID  string          `json:"requestId,omitempty"`
+		CreatedAt  time.Time       `json:"createdAt"`
+	}
+
+	// AuditFilter narrows the audit trail. From and To take either a date yyyy-mm-dd or an RFC 3339
+	// timestamp, a date To includes the whole day.
+	AuditFilter struct {
+		EntityType string
+		EntityID   string
+		ActorID    int
+		From       string
+		To         string
+	}
+
+	AuditRepository interface {
+		// WithinTx runs fn in a transaction that repositories called with the ctx fn is given write
+		// through, so a change and the events recorded for it are saved together or not at all.
+		WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+		CreateAuditEvent(ctx context.Context, e *AuditEvent) error
+		FindAuditEvents(ctx context.Context, opts QueryOptions, f AuditFilter) (*Page[*AuditEvent], error)
+	}
+)
+
+func (f AuditFilter) Valid() (Problems, bool) {
+	var problems Problems
+	if f.EntityType != "" && !slices.Contains(auditEntities, f.EntityType) {
+		problems.Add("entity", CodeInvalidValue, "entity must be a known entity type")
+	}
+	if f.EntityID != "" && f.EntityType == "" {
+		problems.Add("entityId", CodeInvalidValue, "entityId requires entity to be set")
+	}
+	if f.ActorID < 0 {
+		problems.Add("actor", CodeInvalidValue, "actor must be a user id")
+	}
+
+	from, fromErr := parseAuditTime(f.From)
+	if fromErr != nil {
+		problems.Add("from", CodeInvalidFormat, "from must be a valid date yyyy-mm-dd or RFC 3339 timestamp")
+	}
+	to, toErr := parseAuditTime(f.To)
+	if toErr != nil {
+		problems.Add("to", CodeInvalidFormat, "to must be a valid date yyyy-mm-dd or RFC 3339 timestamp")
+	}
+	if fromErr == nil && toErr == nil && !from.IsZero() && !to.IsZero() && from.After(to) {
+		problems.Add("from", CodeInvalidRange, "from must not be after to")
+	}
+
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if len(s) == len(time.DateOnly) {
+		return time.Parse(time.DateOnly, s)
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	requestUserKey struct{}
+	requestIDKey   struct{}
+	loggerKey      struct{}
+)
+
+// ContextWithUser records the user a request was authenticated as.
+func ContextWithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, requestUserKey{}, u)
+}
+
+// UserFromContext returns the user the request was authenticated as, public requests have none.
+func UserFromContext(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(requestUserKey{}).(*User)
+	return u, ok
+}
+
+// ContextWithRequestID records the id a request is identified by in responses and logs.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextWithLogger records the logger a request logs with, carrying its request and trace ids.
+func ContextWithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFromContext returns the logger of the request in ctx so handlers, services and repositories
+// log lines that can be correlated with it, or the default logger outside of a request.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	ImportInserted = "inserted"
+	ImportSkipped  = "skipped"
+	ImportFailed   = "failed"
+)
+
+// datasetDate is the date layout used by the NASA astronaut dataset e.g. 12/27/1949
+const datasetDate = "1/2/2006"
+
+type (
+	// ImportRow is the outcome of importing a single record, AstronautID is the astronaut it was
+	// inserted as or skipped for. Row is the line the record starts on in an imported csv, counting the
+	// header, or its position counting from 1 in an imported JSON array.
+	ImportRow struct {
+		Row         int      `json:"row"`
+		Name        string   `json:"name"`
+		Status      string   `json:"status"`
+		AstronautID int      `json:"astronautId,omitempty"`
+		Error       string   `json:"error,omitempty"`
+		Errors      Problems `json:"errors,omitempty"`
+	}
+
+	ImportReport struct {
+		Inserted int          `json:"inserted"`
+		Skipped  int          `json:"skipped"`
+		Failed   int          `json:"failed"`
+		Rows     []*ImportRow `json:"rows"`
+	}
+
+	DatasetRepository interface {
+		// ImportAstronautData writes every record in a single transaction, returning one ImportRow per record
+		// in the order given. A failing record is rolled back on its own without aborting the others.
+		ImportAstronautData(ctx context.Context, data []*AstronautData) ([]*ImportRow, error)
+		// StreamAstronautData calls fn for every astronaut in id order, stopping at the first error fn returns.
+		StreamAstronautData(ctx context.Context, fn func(*AstronautData) error) error
+	}
+)
+
+// Normalize converts dataset formatted values ("Male", "12/27/1949", "Retired") to the formats
+// used by the rest of the API and tidies multi-valued fields.
+func (d *AstronautData) Normalize() {
+	d.Name = strings.TrimSpace(d.Name)
+	d.BirthPlace = strings.TrimSpace(d.BirthPlace)
+	d.MilitaryRank = strings.TrimSpace(d.MilitaryRank)
+	d.MilitaryBranch = strings.TrimSpace(d.MilitaryBranch)
+	d.DeathMission = strings.TrimSpace(d.DeathMission)
+	d.Status = strings.ToLower(strings.TrimSpace(d.Status))
+	d.BirthDate = normalizeDate(d.BirthDate)
+	d.DeathDate = normalizeDate(d.DeathDate)
+
+	switch strings.ToLower(strings.TrimSpace(d.Gender)) {
+	case "m", "male":
+		d.Gender = "M"
+	case "f", "female":
+		d.Gender = "F"
+	}
+
+	d.AlmaMater = cleanList(d.AlmaMater)
+	d.UndergraduateMajor = cleanList(d.UndergraduateMajor)
+	d.GraduateMajor = cleanList(d.GraduateMajor)
+	d.Missions = cleanList(d.Missions)
+}
+
+func (d *AstronautData) Valid() (Problems, bool) {
+	problems := append(Problems(nil), d.Problems...)
+
+	if len(strings.Fields(d.Name)) < 2 {
+		problems.Add("name", CodeInvalidFormat, "name must contain a first and last name")
+	}
+
+	a := d.Astronaut()
+	if p, ok := a.Valid(); !ok {
+		for _, e := range p {
+			if e.Field == "firstName" || e.Field == "lastName" {
+				continue
+			}
+			problems = append(problems, e)
+		}
+	}
+
+	if p, ok := d.AstronautLog(0).Valid(); !ok {
+		for _, e := range p {
+			if e.Field == "astronautId" {
+				continue
+			}
+			problems = append(problems, e)
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+// Astronaut splits Name on its final space so middle names and initials stay with the first name.
+func (d *AstronautData) Astronaut() *Astronaut {
+	first, last := d.Name, ""
+	if i := strings.LastIndex(d.Name, " "); i != -1 {
+		first, last = d.Name[:i], d.Name[i+1:]
+	}
+
+	return &Astronaut{
+		FirstName:  first,
+		LastName:   last,
+		Gender:     d.Gender,
+		BirthDate:  d.BirthDate,
+		BirthPlace: d.BirthPlace,
+	}
+}
+
+func (d *AstronautData) AstronautLog(astronautID int) *AstronautLog {
+	return &AstronautLog{
+		AstronautID:      astronautID,
+		SpaceFlights:     d.SpaceFlights,
+		SpaceFlightHours: d.SpaceFlightHours,
+		SpaceWalks:       d.SpaceWalks,
+		SpaceWalkHours:   d.SpaceWalkHours,
+		Status:           status(d.Status),
+		DeathDate:        d.DeathDate,
+	}
+}
+
+// MilitaryLog returns nil when the astronaut has no military branch. The dataset marks retired
+// service with a "(Retired)" suffix on the branch.
+func (d *AstronautData) MilitaryLog(astronautID int) *MilitaryLog {
+	if d.MilitaryBranch == "" {
+		return nil
+	}
+
+	branch, retired := strings.CutSuffix(d.MilitaryBranch, "(Retired)")
+
+	return &MilitaryLog{
+		AstronautID: astronautID,
+		Branch:      strings.TrimSpace(branch),
+		Rank:        d.MilitaryRank,
+		Retired:     retired,
+	}
+}
+
+func normalizeDate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+
+	t, err := time.Parse(datasetDate, s)
+	if err != nil {
+		return s
+	}
+	return t.Format(time.DateOnly)
+}
+
+func cleanList(values []string) []string {
+	var list []string
+	seen := make(map[string]bool)
+
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,34 @@
+package model
+
+import "context"
+
+const (
+	CheckOK = "ok"
+
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+type (
+	// ReadinessReport is the outcome of the readiness checks, Checks maps each check to CheckOK or the
+	// reason it failed.
+	ReadinessReport struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	// BuildInfo identifies the build of the running server.
+	BuildInfo struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit,omitempty"`
+		Modified  bool   `json:"modified"`
+		BuildTime string `json:"buildTime,omitempty"`
+		GoVersion string `json:"goVersion"`
+	}
+
+	HealthRepository interface {
+		Ping(ctx context.Context) error
+		// SchemaVersion returns the version of the last migration applied and whether it failed part way.
+		SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
+	}
+)
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type (
+	// Version is a record as it was from ValidFrom until ValidTo, ValidTo is nil for the current version.
+	Version[T any] struct {
+		Record    T          `json:"record"`
+		ValidFrom time.Time  `json:"validFrom"`
+		ValidTo   *time.Time `json:"validTo,omitempty"`
+	}
+
+	// AstronautHistory holds every version of an astronaut and their log and military records, oldest
+	// first.
+	AstronautHistory struct {
+		AstronautID int                       `json:"astronautId"`
+		Astronaut   []*Version[*Astronaut]    `json:"astronaut"`
+		Log         []*Version[*AstronautLog] `json:"log"`
+		Military    []*Version[*MilitaryLog]  `json:"military"`
+	}
+)
@@ -2,6 +2,7 @@ package model
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
@@ -14,9 +15,9 @@ const (
 	Deceased   status = "deceased"
 )
 
-// array (seperated by) -- missions (,), gradute major, undergrad, almamater (;) or refactor CSV
-//
-//	to fit my structs?
+// AstronautData is a single row of the public NASA astronaut dataset. In the CSV form
+// missions are separated by (,) while alma maters, undergraduate and graduate majors
+// are separated by (;).
 type AstronautData struct {
 	Name               string   `json:"name" csv:"Name"`
 	Year               int      `json:"year" csv:"Year"`
@@ -37,6 +38,11 @@ type AstronautData struct {
 	Missions           []string `json:"missions" csv:"Missions"`
 	DeathDate          string   `json:"deathDate" csv:"Death Date"`
 	DeathMission       string   `json:"deathMission" csv:"Death Mission"`
+	// Problems are the cells that could not be decoded, the record fails validation with them.
+	Problems Problems `json:"-" csv:"-"`
+	// Line is where the record starts in the csv it was decoded from, counting the header as line 1.
+	// It is zero for records decoded from JSON.
+	Line int `json:"-" csv:"-"`
 }
 
 type Astronaut struct {
@@ -46,30 +52,32 @@ type Astronaut struct {
 	Gender     string `json:"gender"`
 	BirthDate  string `json:"birthDate"`
 	BirthPlace string `json:"birthPlace"`
+	// Version is sent as the ETag rather than in the body.
+	Version int `json:"-"`
 }
 
-func (a *Astronaut) Valid() (map[string]string, bool) {
-	m := make(map[string]string)
+func (a *Astronaut) Valid() (Problems, bool) {
+	var m Problems
 	if a.FirstName == "" {
-		m["FirstName"] = "first name must not be empty"
+		m.Add("firstName", CodeRequired, "first name must not be empty")
 	}
 	if a.LastName == "" {
-		m["LastName"] = "last name must not be empty"
+		m.Add("lastName", CodeRequired, "last name must not be empty")
 	}
 	if a.Gender != "F" && a.Gender != "M" {
-		m["Gender"] = "gender must be either 'M' or 'F'"
+		m.Add("gender", CodeInvalidValue, "gender must be either 'M' or 'F'")
 	}
 	if a.BirthDate == "" {
-		m["BirthDate"] = "birth date must not be empty"
+		m.Add("birthDate", CodeRequired, "birth date must not be empty")
 	} else if a.BirthDate != "" {
 		_, err := time.Parse(time.DateOnly, a.BirthDate)
 		if err != nil {
-			m["BirthDate"] = "birth date must be a valid date yyyy-mm-dd"
+			m.Add("birthDate", CodeInvalidFormat, "birth date must be a valid date yyyy-mm-dd")
 		}
 
 	}
 	if a.BirthPlace == "" {
-		m["BirthPlace"] = "birth place must not be empty"
+		m.Add("birthPlace", CodeRequired, "birth place must not be empty")
 	}
 
 	if len(m) > 0 {
@@ -84,19 +92,19 @@ type Mission struct {
 	Alias         string `json:"alias"`
 	DateOfMission string `json:"dateOfMission"`
 	Successful    bool   `json:"successful"`
+	Version       int    `json:"-"`
 }
 
-func (m *Mission) Valid() (map[string]string, bool) {
-	problems := make(map[string]string)
+func (m *Mission) Valid() (Problems, bool) {
+	var problems Problems
 	if m.Name == "" {
-		problems["Name"] = "name must not be empty"
+		problems.Add("name", CodeRequired, "name must not be empty")
 	}
-	if m.DateOfMission == "" {
-		problems["DateOfMission"] = "dateOfMission must not be empty"
-	} else if m.DateOfMission != "" {
+	// missions imported from the dataset have no date, one is only checked when it is given
+	if m.DateOfMission != "" {
 		_, err := time.Parse(time.DateOnly, m.DateOfMission)
 		if err != nil {
-			problems["DateOfMission"] = "dateOfMission must be a valid date yyyy-mm-dd"
+			problems.Add("dateOfMission", CodeInvalidFormat, "dateOfMission must be a valid date yyyy-mm-dd")
 		}
 	}
 	if len(problems) > 0 {
@@ -106,29 +114,30 @@ func (m *Mission) Valid() (map[string]string, bool) {
 }
 
 type AstronautLog struct {
-	AstronautID      int
-	SpaceFlights     int
-	SpaceFlightHours int
-	SpaceWalks       int
-	SpaceWalkHours   int
-	Status           status
-	DeathDate        string
+	AstronautID      int    `json:"astronautId"`
+	SpaceFlights     int    `json:"spaceFlights"`
+	SpaceFlightHours int    `json:"spaceFlightHours"`
+	SpaceWalks       int    `json:"spaceWalks"`
+	SpaceWalkHours   int    `json:"spaceWalkHours"`
+	Status           status `json:"status"`
+	DeathDate        string `json:"deathDate"`
+	Version          int    `json:"-"`
 }
 
-func (a *AstronautLog) Valid() (map[string]string, bool) {
-	m := make(map[string]string)
+func (a *AstronautLog) Valid() (Problems, bool) {
+	var m Problems
 
 	if a.AstronautID == 0 {
-		m["astronaut_id"] = "astronaut_id must not be empty"
+		m.Add("astronautId", CodeRequired, "astronaut_id must not be empty")
 	}
 	if a.Status != Active && a.Status != Retired && a.Status != Management && a.Status != Deceased {
-		m["status"] = "status must be one of active, retired, management or deceased"
+		m.Add("status", CodeInvalidValue, "status must be one of active, retired, management or deceased")
 	}
 
 	if a.DeathDate != "" {
 		_, err := time.Parse(time.DateOnly, a.DeathDate)
 		if err != nil {
-			m["death_date"] = "death_date must be a valid date yyyy-mm-dd"
+			m.Add("deathDate", CodeInvalidFormat, "death_date must be a valid date yyyy-mm-dd")
 		}
 	}
 
@@ -139,25 +148,26 @@ func (a *AstronautLog) Valid() (map[string]string, bool) {
 }
 
 type MilitaryLog struct {
-	AstronautID int
-	Branch      string
-	Rank        string
-	Retired     bool
+	AstronautID int    `json:"astronautId"`
+	Branch      string `json:"branch"`
+	Rank        string `json:"rank"`
+	Retired     bool   `json:"retired"`
+	Version     int    `json:"-"`
 }
 
-func (m *MilitaryLog) Valid() (map[string]string, bool) {
-	problems := make(map[string]string)
+func (m *MilitaryLog) Valid() (Problems, bool) {
+	var problems Problems
 
 	if m.AstronautID == 0 {
-		problems["astronaut_id"] = "astronaut_id must not be empty"
+		problems.Add("astronautId", CodeRequired, "astronaut_id must not be empty")
 	}
 
 	if m.Branch == "" {
-		problems["branch"] = "branch must not be empty"
+		problems.Add("branch", CodeRequired, "branch must not be empty")
 	}
 
 	if m.Rank == "" {
-		problems["rank"] = "rank must not be empty"
+		problems.Add("rank", CodeRequired, "rank must not be empty")
 	}
 
 	if len(problems) > 0 {
@@ -168,32 +178,34 @@ func (m *MilitaryLog) Valid() (map[string]string, bool) {
 }
 
 type Major struct {
-	ID     int
-	Course string
+	ID      int    `json:"id"`
+	Course  string `json:"course"`
+	Version int    `json:"-"`
 }
 
-func (m *Major) Valid() (map[string]string, bool) {
-	problems := make(map[string]string)
+func (m *Major) Valid() (Problems, bool) {
+	var problems Problems
 	if m.Course == "" {
-		problems["course"] = "course must not be empty"
+		problems.Add("course", CodeRequired, "course must not be empty")
 	}
-	if m.ID >= 0 {
+	if len(problems) > 0 {
 		return problems, false
 	}
 	return nil, true
 }
 
 type AlmaMater struct {
-	ID     int
-	School string
+	ID      int    `json:"id"`
+	School  string `json:"school"`
+	Version int    `json:"-"`
 }
 
-func (m *AlmaMater) Valid() (map[string]string, bool) {
-	problems := make(map[string]string)
+func (m *AlmaMater) Valid() (Problems, bool) {
+	var problems Problems
 	if m.School == "" {
-		problems["school"] = "school must not be empty"
+		problems.Add("school", CodeRequired, "school must not be empty")
 	}
-	if m.ID >= 0 {
+	if len(problems) > 0 {
 		return problems, false
 	}
 	return nil, true
@@ -208,26 +220,36 @@ type User struct {
 	APIKey    string `json:"apiKey,omitempty"`
 	CreatedAt string `json:"createdAt"`
 	UpdatedAt string `json:"updatedAt"`
+	Version   int    `json:"-"`
+}
+
+// MarshalJSON leaves out the password, which is only ever read from a request. A user found in the
+// database holds the password's bcrypt hash.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	v := user(u)
+	v.Password = ""
+	return json.Marshal(v)
 }
 
-func (u *User) Valid() (map[string]string, bool) {
-	problems := make(map[string]string)
+func (u *User) Valid() (Problems, bool) {
+	var problems Problems
 	if u.FirstName == "" {
-		problems["firstName"] = "firstName must not be empty"
+		problems.Add("firstName", CodeRequired, "firstName must not be empty")
 	}
 	if u.LastName == "" {
-		problems["lastName"] = "lastName must not be empty"
+		problems.Add("lastName", CodeRequired, "lastName must not be empty")
 	}
 	if u.Email == "" || !emailRegex.MatchString(u.Email) {
-		problems["email"] = "email must be a valid email"
+		problems.Add("email", CodeInvalidFormat, "email must be a valid email")
 	}
 
 	if u.Password == "" {
-		problems["password"] = errInvalidPassword.Error()
+		problems.Add("password", CodeRequired, errInvalidPassword.Error())
 	} else {
 		err := ValidatePassword(u.Password)
 		if err != nil {
-			problems["password"] = err.Error()
+			problems.Add("password", CodeWeakPassword, err.Error())
 		}
 	}
 	if len(problems) > 0 {
@@ -238,33 +260,54 @@ func (u *User) Valid() (map[string]string, bool) {
 
 type (
 	AcademicLog struct {
-		AstronautID     int
-		AlmaMaters      []*AlmaMater
-		UnderGradMajors []*Major
-		GradMajors      []*Major
+		AstronautID     int          `json:"astronautId"`
+		AlmaMaters      []*AlmaMater `json:"almaMaters"`
+		UnderGradMajors []*Major     `json:"undergradMajors"`
+		GradMajors      []*Major     `json:"gradMajors"`
+	}
+
+	// PurgeReport counts the soft deleted rows hard deleted by a purge run, along with the daily quota
+	// counts of days before the cutoff it pruned.
+	PurgeReport struct {
+		DeletedBefore time.Time `json:"deletedBefore"`
+		Astronauts    int       `json:"astronauts"`
+		Missions      int       `json:"missions"`
+		Users         int       `json:"users"`
+		Quotas        int       `json:"quotas"`
 	}
 
 	AstronautRepository interface {
 		CreateAstronaut(ctx context.Context, a *Astronaut) error
 		FindAstronautByID(ctx context.Context, id int) (*Astronaut, error)
-		UpdateAstronaut(ctx context.Context, a *Astronaut) error
-		DeleteAstronaut(ctx context.Context, id int) error
-		FindAstronauts(ctx context.Context) ([]*Astronaut, error)
+		// UpdateAstronaut writes a when the row is still at version, or any version for AnyVersion, setting
+		// the new version on a. It returns a *VersionConflictError when the row has since changed and
+		// ErrNoChange when there is no such astronaut, the other versioned writes behave the same way.
+		UpdateAstronaut(ctx context.Context, a *Astronaut, version int) error
+		// DeleteAstronaut soft deletes an astronaut, hiding them and their records until restored or purged.
+		DeleteAstronaut(ctx context.Context, id, version int) error
+		RestoreAstronaut(ctx context.Context, id int) error
+		// PurgeAstronauts hard deletes astronauts soft deleted before deletedBefore, returning how many were removed.
+		PurgeAstronauts(ctx context.Context, deletedBefore time.Time) (int, error)
+		FindAstronauts(ctx context.Context, opts QueryOptions, f AstronautFilter) (*Page[*Astronaut], error)
 		FindAstronautByName(ctx context.Context, name string) ([]*Astronaut, error)
+		// FindAstronautAsOf returns the version of an astronaut current at asOf.
+		FindAstronautAsOf(ctx context.Context, id int, asOf time.Time) (*Astronaut, error)
+		FindAstronautVersions(ctx context.Context, id int) ([]*Version[*Astronaut], error)
 	}
 
 	UserRepository interface {
-		CreateUser(ctx context.Context, u *User) error
+		// CreateUser stores the user along with their first API key.
+		CreateUser(ctx context.Context, u *User, k *APIKey) error
 		FindUserByID(ctx context.Context, id int) (*User, error)
 		FindUserByEmail(ctx context.Context, email string) (*User, error)
-		FindAllUsers(ctx context.Context) ([]*User, error)
-		UpdateUser(ctx context.Context, u *User) error
-		DeleteUser(ctx context.Context, id int) error
+		FindAllUsers(ctx context.Context, opts QueryOptions, f UserFilter) (*Page[*User], error)
+		UpdateUser(ctx context.Context, u *User, version int) error
+		DeleteUser(ctx context.Context, id, version int) error
+		RestoreUser(ctx context.Context, id int) error
+		PurgeUsers(ctx context.Context, deletedBefore time.Time) (int, error)
 		RestUserPassword(ctx context.Context, hash string, id int) error
-		GenerateNewUserAPIKey(ctx context.Context, id int) (string, error)
 		GiveAdminPrivileges(ctx context.Context, id int) error
 		RevokeAdminPrivileges(ctx context.Context, id int) error
-		FindUserByAPIKey(ctx context.Context, key string) (*User, error)
 		IsAdmin(ctx context.Context, userID int) (int, error)
 	}
 
@@ -272,20 +315,25 @@ type (
 		CreateMission(ctx context.Context, m *Mission) error
 		FindMissionByID(ctx context.Context, id int) (*Mission, error)
 		FindMissionByNameOrAlias(ctx context.Context, target string) ([]*Mission, error)
-		FindAllMissions(ctx context.Context) ([]*Mission, error)
-		UpdateMission(ctx context.Context, m *Mission) error
+		FindAllMissions(ctx context.Context, opts QueryOptions, f MissionFilter) (*Page[*Mission], error)
+		UpdateMission(ctx context.Context, m *Mission, version int) error
 		CreateAstronautMission(ctx context.Context, astronautID, missionID int) error
 		FindMissionsByAstronaut(ctx context.Context, astronautID int) ([]*Mission, error)
+		FindAstronautsByMission(ctx context.Context, missionID int) ([]*Astronaut, error)
 		DeleteAstronautMission(ctx context.Context, astronautID, missionID int) error
-		DeleteMission(ctx context.Context, missionID int) error
+		DeleteMission(ctx context.Context, missionID, version int) error
+		RestoreMission(ctx context.Context, missionID int) error
+		PurgeMissions(ctx context.Context, deletedBefore time.Time) (int, error)
 	}
 
 	MilitaryLogRepository interface {
 		CreateMilitaryLog(ctx context.Context, m *MilitaryLog) error
 		FindMilitaryLog(ctx context.Context, astronautID int) (*MilitaryLog, error)
 		FindAllMilitaryLogs(ctx context.Context) ([]*MilitaryLog, error)
-		UpdateMilitaryLog(ctx context.Context, m *MilitaryLog) error
-		DeleteMilitaryLog(ctx context.Context, astronautID int) error
+		UpdateMilitaryLog(ctx context.Context, m *MilitaryLog, version int) error
+		DeleteMilitaryLog(ctx context.Context, astronautID, version int) error
+		FindMilitaryLogAsOf(ctx context.Context, astronautID int, asOf time.Time) (*MilitaryLog, error)
+		FindMilitaryLogVersions(ctx context.Context, astronautID int) ([]*Version[*MilitaryLog], error)
 	}
 
 	AcademicLogRepository interface {
@@ -294,18 +342,20 @@ type (
 		AddUnderGradMajor(ctx context.Context, astronautID, majorID int) error
 		AddGradMajor(ctx context.Context, astronautID, majorID int) error
 		AddAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error
-		UpdateMajor(ctx context.Context, m *Major) error
-		UpdateAlmaMater(ctx context.Context, a *AlmaMater) error
+		UpdateMajor(ctx context.Context, m *Major, version int) error
+		UpdateAlmaMater(ctx context.Context, a *AlmaMater, version int) error
 		FindMajorByID(ctx context.Context, id int) (*Major, error)
 		FindAlmaMaterByID(ctx context.Context, id int) (*AlmaMater, error)
+		FindAllMajors(ctx context.Context) ([]*Major, error)
+		FindAllAlmaMaters(ctx context.Context) ([]*AlmaMater, error)
 		FindAstronautUnderGradMajors(ctx context.Context, astronautID int) ([]*Major, error)
 		FindAstronautGradMajors(ctx context.Context, astronautID int) ([]*Major, error)
 		FindAstronautAlmaMaters(ctx context.Context, astronautID int) ([]*AlmaMater, error)
-		DeleteMajor(ctx context.Context, id int) error
+		DeleteMajor(ctx context.Context, id, version int) error
 		DeleteAstronautUnderGradMajor(ctx context.Context, astronautID, majorID int) error
 		DeleteAstronautGradMajor(ctx context.Context, astronautID, majorID int) error
-		DeleteAlmaMater(ctx context.Context, id int) error
-		DeleteAstronautAlmaMater(ctx context.Context, astronautID, majorID int) error
+		DeleteAlmaMater(ctx context.Context, id, version int) error
+		DeleteAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error
 		GetAcademicLog(ctx context.Context, astronautID int) (*AcademicLog, error)
 	}
 
@@ -313,7 +363,20 @@ type (
 		CreateAstronautLog(ctx context.Context, a *AstronautLog) error
 		FindAstronautLogById(ctx context.Context, astronautID int) (*AstronautLog, error)
 		FindAstronautLogs(ctx context.Context) ([]*AstronautLog, error)
-		UpdateAstronautLog(ctx context.Context, a *AstronautLog) error
-		DeleteAstronautLog(ctx context.Context, astronautID int) error
+		UpdateAstronautLog(ctx context.Context, a *AstronautLog, version int) error
+		DeleteAstronautLog(ctx context.Context, astronautID, version int) error
+		FindAstronautLogAsOf(ctx context.Context, astronautID int, asOf time.Time) (*AstronautLog, error)
+		FindAstronautLogVersions(ctx context.Context, astronautID int) ([]*Version[*AstronautLog], error)
 	}
 )
+
+// AstronautProfile combines every record held on an astronaut. Sections that could not be loaded are
+// left empty with the reason recorded in Errors under the section's json name.
+type AstronautProfile struct {
+	Astronaut *Astronaut        `json:"astronaut"`
+	Log       *AstronautLog     `json:"log"`
+	Military  *MilitaryLog      `json:"military"`
+	Education *AcademicLog      `json:"education"`
+	Missions  []*Mission        `json:"missions"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
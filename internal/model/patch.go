@@ -0,0 +1,328 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchType is a JSON Merge Patch (RFC 7386) body, fields set to null are cleared.
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is a JSON Patch (RFC 6902) body, a list of operations applied in order.
+	JSONPatchType = "application/json-patch+json"
+)
+
+// ErrPatchTestFailed is returned when a JSON Patch test operation does not match the document.
+var ErrPatchTestFailed = errors.New("patch test operation failed")
+
+// Patch is a PATCH request body in one of the supported formats.
+type Patch struct {
+	ContentType string
+	Body        []byte
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply patches v, which must be a pointer to a struct. v is decoded from the zero value so a
+// patch that removes a field clears it.
+func (p Patch) Apply(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+
+	switch p.ContentType {
+	case MergePatchType:
+		var patch any
+		if err := json.Unmarshal(p.Body, &patch); err != nil {
+			return err
+		}
+		doc = mergePatch(doc, patch)
+
+	case JSONPatchType:
+		var ops []patchOperation
+		if err := json.Unmarshal(p.Body, &ops); err != nil {
+			return err
+		}
+		for i, op := range ops {
+			if doc, err = op.apply(doc); err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported patch type %q", p.ContentType)
+	}
+
+	if b, err = json.Marshal(doc); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+	return json.Unmarshal(b, v)
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any)
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+func (op patchOperation) apply(doc any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%s requires a value", op.Op)
+		}
+		var value any
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add":
+			return setPointer(doc, path, value, true)
+		case "replace":
+			return setPointer(doc, path, value, false)
+		}
+
+		current, err := getPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w at %s", ErrPatchTestFailed, op.Path)
+		}
+		return doc, nil
+
+	case "remove":
+		return removePointer(doc, path)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getPointer(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			return setPointer(doc, path, copyValue(value), true)
+		}
+
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into one of its children", op.From)
+		}
+		if doc, err = removePointer(doc, from); err != nil {
+			return nil, err
+		}
+		return setPointer(doc, path, value, true)
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func getPointer(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			doc = v
+
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+
+		default:
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+	}
+	return doc, nil
+}
+
+// setPointer sets the value at path, inserting it when insert is true or replacing an existing
+// value otherwise, and returns the updated document.
+func setPointer(doc any, path []string, value any, insert bool) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, last := path[0], len(path) == 1
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok && !(last && insert) {
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+		if last {
+			node[token] = value
+			return node, nil
+		}
+
+		child, err := setPointer(child, path[1:], value, insert)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []any:
+		if last && insert {
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			node[i] = value
+			return node, nil
+		}
+
+		child, err := setPointer(node[i], path[1:], value, insert)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("path member %q not found", token)
+}
+
+func removePointer(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	token, last := path[0], len(path) == 1
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+		if last {
+			delete(node, token)
+			return node, nil
+		}
+
+		child, err := removePointer(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return append(node[:i], node[i+1:]...), nil
+		}
+
+		child, err := removePointer(node[i], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("path member %q not found", token)
+}
+
+// arrayIndex parses an array reference token, which must be between 0 and max.
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func copyValue(v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(node))
+		for k, child := range node {
+			m[k] = copyValue(child)
+		}
+		return m
+	case []any:
+		s := make([]any, len(node))
+		for i, child := range node {
+			s[i] = copyValue(child)
+		}
+		return s
+	}
+	return v
+}
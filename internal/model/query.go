@@ -0,0 +1,181 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidQuery is returned by list queries given an unknown sort field or a malformed cursor.
+var ErrInvalidQuery = errors.New("invalid query")
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// QueryOptions is shared by every list method of the repositories. Results are paged by keyset,
+// Cursor holds the sort key of the last row of the previous page and is empty for the first page.
+type QueryOptions struct {
+	Limit  int
+	Cursor string
+	Sort   []SortField
+}
+
+// ParseSort reads a sort param in the form field,-field where a leading (-) sorts descending.
+func ParseSort(s string) []SortField {
+	var fields []SortField
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		desc := strings.HasPrefix(f, "-")
+		f = strings.TrimPrefix(f, "-")
+		if f == "" {
+			continue
+		}
+		fields = append(fields, SortField{Field: f, Desc: desc})
+	}
+	return fields
+}
+
+func (q QueryOptions) sortKey() string {
+	fields := make([]string, len(q.Sort))
+	for i, f := range q.Sort {
+		if f.Desc {
+			fields[i] = "-" + f.Field
+			continue
+		}
+		fields[i] = f.Field
+	}
+	return strings.Join(fields, ",")
+}
+
+func (q QueryOptions) Valid() (Problems, bool) {
+	var problems Problems
+	if q.Limit < 1 || q.Limit > MaxLimit {
+		problems.Add("limit", CodeOutOfRange, fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+	}
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+type cursor struct {
+	Sort string   `json:"s"`
+	Keys []string `json:"k"`
+}
+
+// EncodeCursor packs the sort key values of the last row of a page into an opaque cursor.
+func (q QueryOptions) EncodeCursor(keys []string) string {
+	b, _ := json.Marshal(cursor{Sort: q.sortKey(), Keys: keys})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns the sort key values held by the cursor, a cursor issued under a different
+// sort order is rejected.
+func (q QueryOptions) DecodeCursor(n int) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != q.sortKey() || len(c.Keys) != n {
+		return nil, fmt.Errorf("%w: cursor does not match sort", ErrInvalidQuery)
+	}
+	return c.Keys, nil
+}
+
+// Page is a single page of a list, Next is empty on the last page.
+type Page[T any] struct {
+	Data  []T    `json:"data"`
+	Next  string `json:"next,omitempty"`
+	Total int    `json:"total"`
+}
+
+type AstronautFilter struct {
+	Gender        string
+	BirthYearFrom int
+	BirthYearTo   int
+	Status        string
+}
+
+func (f AstronautFilter) Valid() (Problems, bool) {
+	var problems Problems
+	if f.Gender != "" && f.Gender != "F" && f.Gender != "M" {
+		problems.Add("gender", CodeInvalidValue, "gender must be either 'M' or 'F'")
+	}
+	if f.BirthYearFrom != 0 && f.BirthYearTo != 0 && f.BirthYearFrom > f.BirthYearTo {
+		problems.Add("birthYear", CodeInvalidRange, "birthYearFrom must not be after birthYearTo")
+	}
+	switch status(f.Status) {
+	case "", Active, Retired, Management, Deceased:
+	default:
+		problems.Add("status", CodeInvalidValue, "status must be one of active, retired, management or deceased")
+	}
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+type MissionFilter struct {
+	From       string
+	To         string
+	Successful *bool
+}
+
+func (f MissionFilter) Valid() (Problems, bool) {
+	problems := validDateRange("from", f.From, "to", f.To)
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+type UserFilter struct {
+	CreatedFrom string
+	CreatedTo   string
+}
+
+func (f UserFilter) Valid() (Problems, bool) {
+	problems := validDateRange("createdFrom", f.CreatedFrom, "createdTo", f.CreatedTo)
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+// validDateRange checks the optional yyyy-mm-dd dates from and to, problems are reported on the
+// params fromName and toName they were given in.
+func validDateRange(fromName, from, toName, to string) Problems {
+	var problems Problems
+
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.Parse(time.DateOnly, from); err != nil {
+			problems.Add(fromName, CodeInvalidFormat, fromName+" must be a valid date yyyy-mm-dd")
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse(time.DateOnly, to); err != nil {
+			problems.Add(toName, CodeInvalidFormat, toName+" must be a valid date yyyy-mm-dd")
+		}
+	}
+	if len(problems) == 0 && from != "" && to != "" && start.After(end) {
+		problems.Add(fromName, CodeInvalidRange, fromName+" must not be after "+toName)
+	}
+	return problems
+}
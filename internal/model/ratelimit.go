@@ -0,0 +1,51 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// RateLimit is a token bucket refilled at Rate tokens per second holding at most Burst tokens.
+	RateLimit struct {
+		Rate  float64
+		Burst int
+	}
+
+	// RateLimits sets the bucket used for each route scope, routes without an entry in Scopes and
+	// public routes use Default. DailyQuota caps the requests a client makes per UTC day, zero is
+	// unlimited.
+	RateLimits struct {
+		Default    RateLimit
+		Scopes     map[string]RateLimit
+		DailyQuota int
+	}
+
+	// RateLimitStatus is the outcome of taking a token for a request.
+	RateLimitStatus struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Reset is how long until the bucket is full again.
+		Reset time.Duration
+		// RetryAfter is how long until a rejected request can be retried.
+		RetryAfter time.Duration
+
+		QuotaLimit     int
+		QuotaRemaining int
+	}
+
+	QuotaRepository interface {
+		// IncrementQuota counts a request by subject on day and returns the day's count including it.
+		IncrementQuota(ctx context.Context, subject string, day time.Time) (int, error)
+		// PurgeQuotas deletes the counts of days before before, returning how many were removed.
+		PurgeQuotas(ctx context.Context, before time.Time) (int, error)
+	}
+)
+
+func (l RateLimits) For(scope string) RateLimit {
+	if rl, ok := l.Scopes[scope]; ok {
+		return rl
+	}
+	return l.Default
+}
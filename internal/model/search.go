@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	SearchAstronaut = "astronaut"
+	SearchMission   = "mission"
+	SearchAlmaMater = "alma_mater"
+	SearchMajor     = "major"
+
+	maxSearchLength = 200
+)
+
+type (
+	// SearchResult is a single ranked match, Snippet holds the matched text html escaped with the
+	// matching terms wrapped in <b></b>, so it is safe to render as html.
+	SearchResult struct {
+		Type    string  `json:"type"`
+		ID      int     `json:"id"`
+		Title   string  `json:"title"`
+		Detail  string  `json:"detail,omitempty"`
+		Snippet string  `json:"snippet"`
+		Rank    float64 `json:"rank"`
+	}
+
+	SearchQuery struct {
+		Text  string
+		Types []string
+		Limit int
+	}
+
+	SearchRepository interface {
+		// Search matches the query against astronauts, missions, alma maters and majors by full text
+		// and trigram similarity, returning results best match first.
+		Search(ctx context.Context, q SearchQuery) ([]*SearchResult, error)
+	}
+)
+
+func (q SearchQuery) Valid() (Problems, bool) {
+	var problems Problems
+	text := strings.TrimSpace(q.Text)
+	if text == "" {
+		problems.Add("q", CodeRequired, "q must not be empty")
+	} else if len(text) > maxSearchLength {
+		problems.Add("q", CodeTooLong, fmt.Sprintf("q must not be longer than %d characters", maxSearchLength))
+	}
+	for _, t := range q.Types {
+		if t != SearchAstronaut && t != SearchMission && t != SearchAlmaMater && t != SearchMajor {
+			problems.Add("type", CodeInvalidValue, "type must be one of astronaut, mission, alma_mater or major")
+			break
+		}
+	}
+	if q.Limit < 1 || q.Limit > MaxLimit {
+		problems.Add("limit", CodeOutOfRange, fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+	}
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	Credentials struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	// TokenPair is issued on login and refresh. The access token is a short-lived HS256 JWT sent as a
+	// bearer token, the refresh token is an opaque value exchanged for a new pair.
+	TokenPair struct {
+		AccessToken  string `json:"accessToken"`
+		TokenType    string `json:"tokenType"`
+		ExpiresIn    int    `json:"expiresIn"`
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	RefreshRequest struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	// Session is a single login. Revoking it invalidates both its refresh token and every access token
+	// issued under it.
+	Session struct {
+		ID        string
+		UserID    int
+		ExpiresAt time.Time
+		Revoked   bool
+	}
+
+	SessionRepository interface {
+		CreateSession(ctx context.Context, s *Session, refreshTokenHash string) error
+		FindSessionByID(ctx context.Context, id string) (*Session, error)
+		FindSessionByRefreshToken(ctx context.Context, refreshTokenHash string) (*Session, error)
+		// RotateRefreshToken replaces the refresh token of an active session, returning ErrNoChange
+		// if oldHash is no longer the session's current token.
+		RotateRefreshToken(ctx context.Context, id, oldHash, newHash string, expiresAt time.Time) error
+		RevokeSession(ctx context.Context, id string) error
+	}
+)
+
+func (c *Credentials) Valid() (Problems, bool) {
+	var problems Problems
+	if c.Email == "" {
+		problems.Add("email", CodeRequired, "email must not be empty")
+	}
+	if c.Password == "" {
+		problems.Add("password", CodeRequired, "password must not be empty")
+	}
+	if len(problems) > 0 {
+		return problems, false
+	}
+	return nil, true
+}
+
+// Active reports whether the session can still be used at t.
+func (s *Session) Active(t time.Time) bool {
+	return !s.Revoked && t.Before(s.ExpiresAt)
+}
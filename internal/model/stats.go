@@ -0,0 +1,10 @@
+package model
+
+import "context"
+
+type StatsRepository interface {
+	// CountAstronautsByStatus counts the active astronauts by the status of their log, astronauts
+	// without a log are counted under "unknown".
+	CountAstronautsByStatus(ctx context.Context) (map[string]int, error)
+	CountMissionsBySuccess(ctx context.Context) (map[bool]int, error)
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"unicode"
 )
@@ -10,10 +11,50 @@ var ErrNoChange = errors.New("sql: no rows effected")
 var errInvalidPassword = errors.New("password must be at least 8 characters containing at least one uppercase, lowercase, number and special character")
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
+// AnyVersion is the expected version of a write made with If-Match: *, it applies to whatever version is current.
+const AnyVersion = 0
+
+// VersionConflictError is returned by a write made against a version of a row that is no longer current.
+type VersionConflictError struct {
+	Expected int
+	Current  int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("expected version %d but the current version is %d", e.Expected, e.Current)
+}
+
+// Codes identify why a field failed validation, they are stable for clients to match on.
+const (
+	CodeRequired      = "required"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidValue  = "invalid_value"
+	CodeInvalidRange  = "invalid_range"
+	CodeOutOfRange    = "out_of_range"
+	CodeTooLong       = "too_long"
+	CodeWeakPassword  = "weak_password"
+)
+
+// FieldError is a single field that failed validation, Field is its name in requests.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problems are the fields that failed validation, in the order they were checked.
+type Problems []FieldError
+
+func (p *Problems) Add(field, code, message string) {
+	*p = append(*p, FieldError{Field: field, Code: code, Message: message})
+}
+
 type APIError struct {
 	Message   string
 	Code      int
 	Exception string
+	// Problems are the fields that failed validation, if that is why the request was rejected.
+	Problems Problems
 }
 
 func (e APIError) Error() string {
@@ -21,7 +62,7 @@ func (e APIError) Error() string {
 }
 
 type Validator interface {
-	Valid() (map[string]string, bool)
+	Valid() (Problems, bool)
 }
 
 func ValidatePassword(password string) error {
@@ -8,10 +8,14 @@ import (
 	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
 	"github.com/lib/pq"
 )
 
 func AddMajor(ctx context.Context, repository model.AcademicLogRepository, major *model.Major) (*model.Major, error) {
+	ctx, span := tracing.Start(ctx, "service.AddMajor")
+	defer span.End()
+
 	if err := validate(major, "Major"); err != nil {
 		return nil, err
 	}
@@ -38,6 +42,9 @@ func AddMajor(ctx context.Context, repository model.AcademicLogRepository, major
 }
 
 func AddAlmaMater(ctx context.Context, repository model.AcademicLogRepository, almaMater *model.AlmaMater) (*model.AlmaMater, error) {
+	ctx, span := tracing.Start(ctx, "service.AddAlmaMater")
+	defer span.End()
+
 	if err := validate(almaMater, "Alma Mater"); err != nil {
 		return nil, err
 	}
@@ -64,11 +71,22 @@ func AddAlmaMater(ctx context.Context, repository model.AcademicLogRepository, a
 }
 
 func AddAstronautUndergradMajor(ctx context.Context, repository model.AcademicLogRepository, astronautID, majorID int) error {
+	ctx, span := tracing.Start(ctx, "service.AddAstronautUndergradMajor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := repository.AddUnderGradMajor(ctx, astronautID, majorID); err != nil {
-		return model.APIError{
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Major and/or Astronaut not found",
+				Exception: pgErr.Message,
+			}
+		}
+		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to add Astronaut Undergrad Major",
 			Exception: err.Error(),
@@ -78,11 +96,22 @@ func AddAstronautUndergradMajor(ctx context.Context, repository model.AcademicLo
 }
 
 func AddAstronautGradMajor(ctx context.Context, repository model.AcademicLogRepository, astronautID, majorID int) error {
+	ctx, span := tracing.Start(ctx, "service.AddAstronautGradMajor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := repository.AddGradMajor(ctx, astronautID, majorID); err != nil {
-		return model.APIError{
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Major and/or Astronaut not found",
+				Exception: pgErr.Message,
+			}
+		}
+		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to add Astronaut Grad Major",
 			Exception: err.Error(),
@@ -92,11 +121,22 @@ func AddAstronautGradMajor(ctx context.Context, repository model.AcademicLogRepo
 }
 
 func AddAstronautAlmaMater(ctx context.Context, repository model.AcademicLogRepository, astronautID, almaMaterID int) error {
+	ctx, span := tracing.Start(ctx, "service.AddAstronautAlmaMater")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := repository.AddAstronautAlmaMater(ctx, astronautID, almaMaterID); err != nil {
-		return model.APIError{
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Alma Mater and/or Astronaut not found",
+				Exception: pgErr.Message,
+			}
+		}
+		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to add Astronaut Alma Mater",
 			Exception: err.Error(),
@@ -105,14 +145,17 @@ func AddAstronautAlmaMater(ctx context.Context, repository model.AcademicLogRepo
 	return nil
 }
 
-func UpdateMajor(ctx context.Context, repository model.AcademicLogRepository, major *model.Major) error {
+func UpdateMajor(ctx context.Context, repository model.AcademicLogRepository, major *model.Major, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateMajor")
+	defer span.End()
+
 	if err := validate(major, "Major"); err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := repository.UpdateMajor(ctx, major); err != nil {
+	if err := repository.UpdateMajor(ctx, major, version); err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return &model.APIError{
@@ -122,6 +165,11 @@ func UpdateMajor(ctx context.Context, repository model.AcademicLogRepository, ma
 			}
 		}
 
+		var conflict *model.VersionConflictError
+		if errors.As(err, &conflict) {
+			return preconditionFailed(conflict, "Major")
+		}
+
 		if errors.Is(err, model.ErrNoChange) {
 			return &model.APIError{
 				Code:      http.StatusBadRequest,
@@ -138,16 +186,19 @@ func UpdateMajor(ctx context.Context, repository model.AcademicLogRepository, ma
 	return nil
 }
 
-func UpdateAlaMater(ctx context.Context, repository model.AcademicLogRepository, almaMater *model.AlmaMater) error {
+func UpdateAlaMater(ctx context.Context, repository model.AcademicLogRepository, almaMater *model.AlmaMater, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateAlaMater")
+	defer span.End()
+
 	if err := validate(almaMater, "Alma Mater"); err != nil {
 		return err
 	}
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := repository.UpdateAlmaMater(ctx, almaMater); err != nil {
+	if err := repository.UpdateAlmaMater(ctx, almaMater, version); err != nil {
 		var pgErr *pq.Error
-		if errors.As(err, &pgErr) {
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return &model.APIError{
 				Code:      http.StatusBadRequest,
 				Message:   "Alma Mater already exists",
@@ -155,6 +206,11 @@ func UpdateAlaMater(ctx context.Context, repository model.AcademicLogRepository,
 			}
 		}
 
+		var conflict *model.VersionConflictError
+		if errors.As(err, &conflict) {
+			return preconditionFailed(conflict, "Alma Mater")
+		}
+
 		if errors.Is(err, model.ErrNoChange) {
 			return &model.APIError{
 				Code:      http.StatusBadRequest,
@@ -172,6 +228,9 @@ func UpdateAlaMater(ctx context.Context, repository model.AcademicLogRepository,
 }
 
 func GetMajorByID(ctx context.Context, repository model.AcademicLogRepository, id int) (*model.Major, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMajorByID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -195,6 +254,9 @@ func GetMajorByID(ctx context.Context, repository model.AcademicLogRepository, i
 }
 
 func GetAlmaMaterByID(ctx context.Context, repository model.AcademicLogRepository, id int) (*model.AlmaMater, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAlmaMaterByID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -217,7 +279,46 @@ func GetAlmaMaterByID(ctx context.Context, repository model.AcademicLogRepositor
 	}
 }
 
+func GetMajors(ctx context.Context, repository model.AcademicLogRepository) ([]*model.Major, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMajors")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ms, err := repository.FindAllMajors(ctx)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to find Majors",
+			Exception: err.Error(),
+		}
+	}
+	return ms, nil
+}
+
+func GetAlmaMaters(ctx context.Context, repository model.AcademicLogRepository) ([]*model.AlmaMater, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAlmaMaters")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	as, err := repository.FindAllAlmaMaters(ctx)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to find Alma Maters",
+			Exception: err.Error(),
+		}
+	}
+	return as, nil
+}
+
 func GetAstronautUndergradMajors(ctx context.Context, repository model.AcademicLogRepository, astronautID int) ([]*model.Major, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautUndergradMajors")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -233,6 +334,9 @@ func GetAstronautUndergradMajors(ctx context.Context, repository model.AcademicL
 }
 
 func GetAstronautGradMajors(ctx context.Context, repository model.AcademicLogRepository, astronautID int) ([]*model.Major, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautGradMajors")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -248,6 +352,9 @@ func GetAstronautGradMajors(ctx context.Context, repository model.AcademicLogRep
 }
 
 func GetAstronautAlmaMaters(ctx context.Context, repository model.AcademicLogRepository, astronautID int) ([]*model.AlmaMater, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautAlmaMaters")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -262,12 +369,18 @@ func GetAstronautAlmaMaters(ctx context.Context, repository model.AcademicLogRep
 	return as, nil
 }
 
-func DeleteMajor(ctx context.Context, repository model.AcademicLogRepository, id int) error {
+func DeleteMajor(ctx context.Context, repository model.AcademicLogRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteMajor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := repository.DeleteMajor(ctx, id)
+	err := repository.DeleteMajor(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Major")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -286,6 +399,9 @@ func DeleteMajor(ctx context.Context, repository model.AcademicLogRepository, id
 }
 
 func DeleteUnderGradMajor(ctx context.Context, repository model.AcademicLogRepository, astronautID, majorID int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteUnderGradMajor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -301,6 +417,9 @@ func DeleteUnderGradMajor(ctx context.Context, repository model.AcademicLogRepos
 }
 
 func DeleteGradeMajor(ctx context.Context, repository model.AcademicLogRepository, astronautID, majorID int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteGradeMajor")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -315,12 +434,18 @@ func DeleteGradeMajor(ctx context.Context, repository model.AcademicLogRepositor
 	return nil
 }
 
-func DeleteAlmaMater(ctx context.Context, repository model.AcademicLogRepository, id int) error {
+func DeleteAlmaMater(ctx context.Context, repository model.AcademicLogRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteAlmaMater")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := repository.DeleteAlmaMater(ctx, id)
+	err := repository.DeleteAlmaMater(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Alma Mater")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusBadRequest,
@@ -339,6 +464,9 @@ func DeleteAlmaMater(ctx context.Context, repository model.AcademicLogRepository
 }
 
 func DeleteAstronautAlmaMater(ctx context.Context, repository model.AcademicLogRepository, astronautID, almaMaterID int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteAstronautAlmaMater")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -353,6 +481,9 @@ func DeleteAstronautAlmaMater(ctx context.Context, repository model.AcademicLogR
 }
 
 func GetAstronautAcademicLog(ctx context.Context, repository model.AcademicLogRepository, astronautID int) (*model.AcademicLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautAcademicLog")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
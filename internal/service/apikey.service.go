@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultRotationGrace is how long a rotated key keeps working when no grace window is given.
+	DefaultRotationGrace = 24 * time.Hour
+	MaxRotationGrace     = 30 * 24 * time.Hour
+)
+
+// newAPIKey generates a key with the given name and scopes, setting the key along with the prefix
+// and digest it is stored by.
+func newAPIKey(name string, scopes []string) (*model.APIKey, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	prefix := model.APIKeyPrefix + hex.EncodeToString(id)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return &model.APIKey{
+		Name:    name,
+		Prefix:  prefix,
+		Key:     key,
+		KeyHash: hashToken(key),
+		Scopes:  scopes,
+	}, nil
+}
+
+// SearchAPIKey returns the user holding an active key along with the key itself.
+func SearchAPIKey(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	apiKeyRepository model.APIKeyRepository,
+	key string,
+) (*model.User, *model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchAPIKey")
+	defer span.End()
+
+	notFound := &model.APIError{
+		Code:      http.StatusNotFound,
+		Message:   "User not found",
+		Exception: "unknown api key",
+	}
+
+	prefix, ok := model.APIKeyLookupPrefix(key)
+	if !ok {
+		return nil, nil, notFound
+	}
+	if !strings.HasPrefix(key, model.APIKeyPrefix) {
+		// legacy uuid keys were digested in their lowercase text form
+		key = strings.ToLower(key)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	candidates, err := apiKeyRepository.FindActiveAPIKeysByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get user",
+			Exception: err.Error(),
+		}
+	}
+
+	hash := []byte(hashToken(key))
+
+	var k *model.APIKey
+	for _, c := range candidates {
+		if subtle.ConstantTimeCompare(hash, []byte(c.KeyHash)) == 1 {
+			k = c
+		}
+	}
+	if k == nil {
+		return nil, nil, notFound
+	}
+
+	if err := apiKeyRepository.TouchAPIKey(ctx, k.ID); err != nil {
+		return nil, nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get user",
+			Exception: err.Error(),
+		}
+	}
+
+	usr, err := userRepository.FindUserByID(ctx, k.UserID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "User not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get user",
+			Exception: err.Error(),
+		}
+	}
+	return usr, k, nil
+}
+
+// CreateAPIKey adds a key for the user. A request authenticated with an API key can only hand out
+// scopes its own key holds.
+func CreateAPIKey(ctx context.Context, repository model.APIKeyRepository, userID int, k *model.APIKey) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateAPIKey")
+	defer span.End()
+
+	if err := validate(k, "API key"); err != nil {
+		return nil, err
+	}
+
+	if requester, ok := model.APIKeyFromContext(ctx); ok {
+		for _, s := range k.Scopes {
+			if !requester.HasScope(s) {
+				return nil, &model.APIError{
+					Code:      http.StatusForbidden,
+					Message:   fmt.Sprintf("cannot grant scope %s not held by the requesting key", s),
+					Exception: "requested scope not held by requesting key",
+				}
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	created, err := newAPIKey(k.Name, k.Scopes)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to create API key",
+			Exception: err.Error(),
+		}
+	}
+	created.UserID = userID
+	created.ExpiresAt = k.ExpiresAt
+
+	err = repository.CreateAPIKey(ctx, created)
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "User not found",
+				Exception: pgErr.Message,
+			}
+		}
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to create API key",
+			Exception: err.Error(),
+		}
+	}
+	return created, nil
+}
+
+func GetAPIKeys(ctx context.Context, repository model.APIKeyRepository, userID int) ([]*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAPIKeys")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	keys, err := repository.FindAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get API keys",
+			Exception: err.Error(),
+		}
+	}
+	return keys, nil
+}
+
+// RotateAPIKey replaces an active key with a new one holding the same name and scopes. The old key
+// keeps working for the grace window so integrations can move over without downtime.
+func RotateAPIKey(ctx context.Context, repository model.APIKeyRepository, userID, keyID int, grace time.Duration) (*model.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "service.RotateAPIKey")
+	defer span.End()
+
+	if grace < 0 || grace > MaxRotationGrace {
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   fmt.Sprintf("grace must be between 0s and %s", MaxRotationGrace),
+			Exception: "grace window out of range",
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	k, err := newAPIKey("", nil)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to rotate API key",
+			Exception: err.Error(),
+		}
+	}
+
+	err = repository.RotateAPIKey(ctx, userID, keyID, k, time.Now().Add(grace))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "API key not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to rotate API key",
+			Exception: err.Error(),
+		}
+	}
+	return k, nil
+}
+
+func RevokeAPIKey(ctx context.Context, repository model.APIKeyRepository, userID, keyID int) error {
+	ctx, span := tracing.Start(ctx, "service.RevokeAPIKey")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := repository.RevokeAPIKey(ctx, userID, keyID)
+	switch {
+	case errors.Is(err, model.ErrNoChange):
+		return &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "API key not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to revoke API key",
+			Exception: err.Error(),
+		}
+	}
+	return nil
+}
+
+// GenerateNewAPIKey rotates the user's default key, keeping the old one valid for the default grace
+// window. A user without an active default key is given a new one.
+func GenerateNewAPIKey(ctx context.Context, repository model.APIKeyRepository, userID int) (string, error) {
+	ctx, span := tracing.Start(ctx, "service.GenerateNewAPIKey")
+	defer span.End()
+
+	keys, err := GetAPIKeys(ctx, repository, userID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		k := keys[i]
+		if k.Name != model.DefaultKeyName || k.RevokedAt != nil || (k.ExpiresAt != nil && !k.ExpiresAt.After(now)) {
+			continue
+		}
+
+		k, err := RotateAPIKey(ctx, repository, userID, k.ID, DefaultRotationGrace)
+		if err != nil {
+			return "", err
+		}
+		return k.Key, nil
+	}
+
+	k, err := CreateAPIKey(ctx, repository, userID, &model.APIKey{Name: model.DefaultKeyName, Scopes: model.AllScopes})
+	if err != nil {
+		return "", err
+	}
+	return k.Key, nil
+}
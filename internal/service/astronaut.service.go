@@ -5,11 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
 	"net/http"
 	"time"
 )
 
 func AddAstronaut(ctx context.Context, a *model.Astronaut, r model.AstronautRepository) (*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.AddAstronaut")
+	defer span.End()
+
 	if err := validate(a, "Astronaut"); err != nil {
 		return nil, err
 	}
@@ -35,6 +39,9 @@ func GetAstronaut(
 	ar model.AstronautRepository,
 	id int,
 ) (*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronaut")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -57,12 +64,31 @@ func GetAstronaut(
 	}
 }
 
-func GetAstronauts(ctx context.Context, r model.AstronautRepository) ([]*model.Astronaut, error) {
+func GetAstronauts(
+	ctx context.Context,
+	r model.AstronautRepository,
+	opts model.QueryOptions,
+	f model.AstronautFilter,
+) (*model.Page[*model.Astronaut], error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronauts")
+	defer span.End()
+
+	if err := validateQuery(opts, f); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	astronauts, err := r.FindAstronauts(ctx)
-	if err != nil {
+	page, err := r.FindAstronauts(ctx, opts, f)
+	switch {
+	case errors.Is(err, model.ErrInvalidQuery):
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Exception: err.Error(),
+		}
+	case err != nil:
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to retrieve astronauts",
@@ -70,10 +96,13 @@ func GetAstronauts(ctx context.Context, r model.AstronautRepository) ([]*model.A
 		}
 	}
 
-	return astronauts, nil
+	return page, nil
 }
 
-func UpdateAstronaut(ctx context.Context, a *model.Astronaut, r model.AstronautRepository) error {
+func UpdateAstronaut(ctx context.Context, a *model.Astronaut, r model.AstronautRepository, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateAstronaut")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -81,8 +110,18 @@ func UpdateAstronaut(ctx context.Context, a *model.Astronaut, r model.AstronautR
 		return err
 	}
 
-	err := r.UpdateAstronaut(ctx, a)
-	if err != nil {
+	err := r.UpdateAstronaut(ctx, a, version)
+	var conflict *model.VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Astronaut")
+	case errors.Is(err, model.ErrNoChange):
+		return &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
 		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to update astronaut",
@@ -92,12 +131,39 @@ func UpdateAstronaut(ctx context.Context, a *model.Astronaut, r model.AstronautR
 	return nil
 }
 
-func DeleteAstronaut(ctx context.Context, r model.AstronautRepository, id int) error {
+// PatchAstronaut applies a merge or JSON patch to the astronaut and saves the result.
+func PatchAstronaut(ctx context.Context, r model.AstronautRepository, id int, patch model.Patch, version int) (*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.PatchAstronaut")
+	defer span.End()
+
+	a, err := GetAstronaut(ctx, r, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPatch(patch, a, "Astronaut"); err != nil {
+		return nil, err
+	}
+	a.ID = id
+
+	if err := UpdateAstronaut(ctx, a, r, version); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func DeleteAstronaut(ctx context.Context, r model.AstronautRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteAstronaut")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := r.DeleteAstronaut(ctx, id)
+	err := r.DeleteAstronaut(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Astronaut")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -115,7 +181,37 @@ func DeleteAstronaut(ctx context.Context, r model.AstronautRepository, id int) e
 	return nil
 }
 
+// RestoreAstronaut undoes a soft delete, bringing back the astronaut along with their records.
+func RestoreAstronaut(ctx context.Context, r model.AstronautRepository, id int) error {
+	ctx, span := tracing.Start(ctx, "service.RestoreAstronaut")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := r.RestoreAstronaut(ctx, id)
+	switch {
+	case errors.Is(err, model.ErrNoChange):
+		return &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "deleted astronaut not found",
+			Exception: err.Error(),
+		}
+
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to restore astronaut",
+			Exception: err.Error(),
+		}
+	}
+	return nil
+}
+
 func SearchAstronautByName(ctx context.Context, r model.AstronautRepository, name string) ([]*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchAstronautByName")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -5,11 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+	"github.com/lib/pq"
 	"net/http"
 	"time"
 )
 
 func AddAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, al *model.AstronautLog) (*model.AstronautLog, error) {
+	ctx, span := tracing.Start(ctx, "service.AddAstronautLog")
+	defer span.End()
+
 	if err := validate(al, "AstronautLog"); err != nil {
 		return nil, err
 	}
@@ -18,7 +23,14 @@ func AddAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogReposit
 	defer cancel()
 
 	err := astroLogRepo.CreateAstronautLog(ctx, al)
+	var pgErr *pq.Error
 	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "Astronaut not found",
+			Exception: pgErr.Message,
+		}
 	case err != nil:
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
@@ -32,6 +44,9 @@ func AddAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogReposit
 }
 
 func GetAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, id int) (*model.AstronautLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautLog")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -55,6 +70,9 @@ func GetAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogReposit
 }
 
 func GetAstronautLogs(ctx context.Context, astroLogRepo model.AstronautLogRepository) ([]*model.AstronautLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautLogs")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -69,7 +87,10 @@ func GetAstronautLogs(ctx context.Context, astroLogRepo model.AstronautLogReposi
 	return als, nil
 }
 
-func UpdateAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, al *model.AstronautLog) error {
+func UpdateAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, al *model.AstronautLog, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateAstronautLog")
+	defer span.End()
+
 	if err := validate(al, "AstronautLog"); err != nil {
 		return err
 	}
@@ -77,8 +98,11 @@ func UpdateAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepo
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := astroLogRepo.UpdateAstronautLog(ctx, al)
+	err := astroLogRepo.UpdateAstronautLog(ctx, al, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "AstronautLog")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -96,12 +120,39 @@ func UpdateAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepo
 	}
 }
 
-func DeleteAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, id int) error {
+// PatchAstronautLog applies a merge or JSON patch to the astronaut log and saves the result.
+func PatchAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, id int, patch model.Patch, version int) (*model.AstronautLog, error) {
+	ctx, span := tracing.Start(ctx, "service.PatchAstronautLog")
+	defer span.End()
+
+	al, err := GetAstronautLog(ctx, astroLogRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPatch(patch, al, "Astronaut log"); err != nil {
+		return nil, err
+	}
+	al.AstronautID = id
+
+	if err := UpdateAstronautLog(ctx, astroLogRepo, al, version); err != nil {
+		return nil, err
+	}
+	return al, nil
+}
+
+func DeleteAstronautLog(ctx context.Context, astroLogRepo model.AstronautLogRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteAstronautLog")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := astroLogRepo.DeleteAstronautLog(ctx, id)
+	err := astroLogRepo.DeleteAstronautLog(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "AstronautLog")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -0,0 +1,673 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+// auditor records changes made through the audited repositories below. Each wraps a repository,
+// reading the record before it is changed and recording the change once it has been made, so every
+// service writing through it is audited without knowing about it. The read, the change and its event
+// share a transaction, so a change is never saved without its event or recorded against a stale read.
+type auditor struct {
+	repository model.AuditRepository
+}
+
+// audit runs change, which makes a change and records it, in a single transaction.
+func (a auditor) audit(ctx context.Context, change func(ctx context.Context) error) error {
+	return a.repository.WithinTx(ctx, change)
+}
+
+// record stores an event for a change made on behalf of the request user in ctx. before and after
+// are stored as json, nil for a record that did not exist.
+func (a auditor) record(ctx context.Context, action, entityType, entityID string, before, after any) error {
+	e := &model.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  model.RequestIDFromContext(ctx),
+	}
+	if usr, ok := model.UserFromContext(ctx); ok {
+		e.ActorID = &usr.ID
+	}
+
+	var err error
+	if e.Before, err = marshalSnapshot(before); err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+	if e.After, err = marshalSnapshot(after); err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+
+	if err := a.repository.CreateAuditEvent(ctx, e); err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+	return nil
+}
+
+func marshalSnapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// snapshot returns the record read before a change, or nil when it could not be read, in which case
+// the change itself fails.
+func snapshot[T any](v T, err error) any {
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// userSnapshot copies u without its credentials.
+func userSnapshot(u *model.User) *model.User {
+	c := *u
+	c.Password, c.APIKey = "", ""
+	return &c
+}
+
+// apiKeySnapshot copies k without the key itself.
+func apiKeySnapshot(k *model.APIKey) *model.APIKey {
+	c := *k
+	c.Key, c.KeyHash = "", ""
+	return &c
+}
+
+func auditID(id int) string {
+	return strconv.Itoa(id)
+}
+
+// auditLink identifies a link between an astronaut and another record.
+func auditLink(astronautID, id int) string {
+	return fmt.Sprintf("%d/%d", astronautID, id)
+}
+
+// recordPurge records a purge run as a single event against all rows of the entity type, runs that
+// removed nothing are not recorded.
+func (a auditor) recordPurge(ctx context.Context, entityType string, purged int, deletedBefore time.Time) error {
+	if purged == 0 {
+		return nil
+	}
+	summary := map[string]any{"purged": purged, "deletedBefore": deletedBefore}
+	return a.record(ctx, model.AuditPurge, entityType, "*", nil, summary)
+}
+
+func GetAuditEvents(
+	ctx context.Context,
+	repository model.AuditRepository,
+	opts model.QueryOptions,
+	f model.AuditFilter,
+) (*model.Page[*model.AuditEvent], error) {
+	ctx, span := tracing.Start(ctx, "service.GetAuditEvents")
+	defer span.End()
+
+	if err := validateQuery(opts, f); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	page, err := repository.FindAuditEvents(ctx, opts, f)
+	switch {
+	case errors.Is(err, model.ErrInvalidQuery):
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to find audit events",
+			Exception: err.Error(),
+		}
+	}
+	return page, nil
+}
+
+type auditedAstronautRepo struct {
+	model.AstronautRepository
+	auditor
+}
+
+func NewAuditedAstronautRepo(r model.AstronautRepository, audit model.AuditRepository) model.AstronautRepository {
+	return &auditedAstronautRepo{r, auditor{audit}}
+}
+
+func (r *auditedAstronautRepo) CreateAstronaut(ctx context.Context, a *model.Astronaut) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AstronautRepository.CreateAstronaut(ctx, a); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAstronaut, auditID(a.ID), nil, a)
+	})
+}
+
+func (r *auditedAstronautRepo) UpdateAstronaut(ctx context.Context, a *model.Astronaut, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAstronautByID(ctx, a.ID))
+		if err := r.AstronautRepository.UpdateAstronaut(ctx, a, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityAstronaut, auditID(a.ID), before, a)
+	})
+}
+
+func (r *auditedAstronautRepo) DeleteAstronaut(ctx context.Context, id, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAstronautByID(ctx, id))
+		if err := r.AstronautRepository.DeleteAstronaut(ctx, id, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityAstronaut, auditID(id), before, nil)
+	})
+}
+
+func (r *auditedAstronautRepo) RestoreAstronaut(ctx context.Context, id int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AstronautRepository.RestoreAstronaut(ctx, id); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditRestore, model.EntityAstronaut, auditID(id), nil, snapshot(r.FindAstronautByID(ctx, id)))
+	})
+}
+
+func (r *auditedAstronautRepo) PurgeAstronauts(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := r.audit(ctx, func(ctx context.Context) (err error) {
+		if purged, err = r.AstronautRepository.PurgeAstronauts(ctx, deletedBefore); err != nil {
+			return err
+		}
+		return r.recordPurge(ctx, model.EntityAstronaut, purged, deletedBefore)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+type auditedAstronautLogRepo struct {
+	model.AstronautLogRepository
+	auditor
+}
+
+func NewAuditedAstronautLogRepo(r model.AstronautLogRepository, audit model.AuditRepository) model.AstronautLogRepository {
+	return &auditedAstronautLogRepo{r, auditor{audit}}
+}
+
+func (r *auditedAstronautLogRepo) CreateAstronautLog(ctx context.Context, a *model.AstronautLog) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AstronautLogRepository.CreateAstronautLog(ctx, a); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAstronautLog, auditID(a.AstronautID), nil, a)
+	})
+}
+
+func (r *auditedAstronautLogRepo) UpdateAstronautLog(ctx context.Context, a *model.AstronautLog, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAstronautLogById(ctx, a.AstronautID))
+		if err := r.AstronautLogRepository.UpdateAstronautLog(ctx, a, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityAstronautLog, auditID(a.AstronautID), before, a)
+	})
+}
+
+func (r *auditedAstronautLogRepo) DeleteAstronautLog(ctx context.Context, astronautID, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAstronautLogById(ctx, astronautID))
+		if err := r.AstronautLogRepository.DeleteAstronautLog(ctx, astronautID, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityAstronautLog, auditID(astronautID), before, nil)
+	})
+}
+
+type auditedMilitaryLogRepo struct {
+	model.MilitaryLogRepository
+	auditor
+}
+
+func NewAuditedMilitaryLogRepo(r model.MilitaryLogRepository, audit model.AuditRepository) model.MilitaryLogRepository {
+	return &auditedMilitaryLogRepo{r, auditor{audit}}
+}
+
+func (r *auditedMilitaryLogRepo) CreateMilitaryLog(ctx context.Context, m *model.MilitaryLog) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.MilitaryLogRepository.CreateMilitaryLog(ctx, m); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityMilitaryLog, auditID(m.AstronautID), nil, m)
+	})
+}
+
+func (r *auditedMilitaryLogRepo) UpdateMilitaryLog(ctx context.Context, m *model.MilitaryLog, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMilitaryLog(ctx, m.AstronautID))
+		if err := r.MilitaryLogRepository.UpdateMilitaryLog(ctx, m, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityMilitaryLog, auditID(m.AstronautID), before, m)
+	})
+}
+
+func (r *auditedMilitaryLogRepo) DeleteMilitaryLog(ctx context.Context, astronautID, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMilitaryLog(ctx, astronautID))
+		if err := r.MilitaryLogRepository.DeleteMilitaryLog(ctx, astronautID, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityMilitaryLog, auditID(astronautID), before, nil)
+	})
+}
+
+type auditedMissionRepo struct {
+	model.MissionRepository
+	auditor
+}
+
+func NewAuditedMissionRepo(r model.MissionRepository, audit model.AuditRepository) model.MissionRepository {
+	return &auditedMissionRepo{r, auditor{audit}}
+}
+
+func (r *auditedMissionRepo) CreateMission(ctx context.Context, m *model.Mission) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.MissionRepository.CreateMission(ctx, m); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityMission, auditID(m.ID), nil, m)
+	})
+}
+
+func (r *auditedMissionRepo) UpdateMission(ctx context.Context, m *model.Mission, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMissionByID(ctx, m.ID))
+		if err := r.MissionRepository.UpdateMission(ctx, m, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityMission, auditID(m.ID), before, m)
+	})
+}
+
+func (r *auditedMissionRepo) DeleteMission(ctx context.Context, missionID, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMissionByID(ctx, missionID))
+		if err := r.MissionRepository.DeleteMission(ctx, missionID, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityMission, auditID(missionID), before, nil)
+	})
+}
+
+func (r *auditedMissionRepo) RestoreMission(ctx context.Context, missionID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.MissionRepository.RestoreMission(ctx, missionID); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditRestore, model.EntityMission, auditID(missionID), nil, snapshot(r.FindMissionByID(ctx, missionID)))
+	})
+}
+
+func (r *auditedMissionRepo) PurgeMissions(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := r.audit(ctx, func(ctx context.Context) (err error) {
+		if purged, err = r.MissionRepository.PurgeMissions(ctx, deletedBefore); err != nil {
+			return err
+		}
+		return r.recordPurge(ctx, model.EntityMission, purged, deletedBefore)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (r *auditedMissionRepo) CreateAstronautMission(ctx context.Context, astronautID, missionID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.MissionRepository.CreateAstronautMission(ctx, astronautID, missionID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "missionId": missionID}
+		return r.record(ctx, model.AuditCreate, model.EntityMissionCrew, auditLink(astronautID, missionID), nil, link)
+	})
+}
+
+func (r *auditedMissionRepo) DeleteAstronautMission(ctx context.Context, astronautID, missionID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.MissionRepository.DeleteAstronautMission(ctx, astronautID, missionID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "missionId": missionID}
+		return r.record(ctx, model.AuditDelete, model.EntityMissionCrew, auditLink(astronautID, missionID), link, nil)
+	})
+}
+
+type auditedAcademicLogRepo struct {
+	model.AcademicLogRepository
+	auditor
+}
+
+func NewAuditedAcademicLogRepo(r model.AcademicLogRepository, audit model.AuditRepository) model.AcademicLogRepository {
+	return &auditedAcademicLogRepo{r, auditor{audit}}
+}
+
+func (r *auditedAcademicLogRepo) CreateMajor(ctx context.Context, m *model.Major) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.CreateMajor(ctx, m); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityMajor, auditID(m.ID), nil, m)
+	})
+}
+
+func (r *auditedAcademicLogRepo) UpdateMajor(ctx context.Context, m *model.Major, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMajorByID(ctx, m.ID))
+		if err := r.AcademicLogRepository.UpdateMajor(ctx, m, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityMajor, auditID(m.ID), before, m)
+	})
+}
+
+func (r *auditedAcademicLogRepo) DeleteMajor(ctx context.Context, id, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindMajorByID(ctx, id))
+		if err := r.AcademicLogRepository.DeleteMajor(ctx, id, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityMajor, auditID(id), before, nil)
+	})
+}
+
+func (r *auditedAcademicLogRepo) CreateAlmaMater(ctx context.Context, a *model.AlmaMater) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.CreateAlmaMater(ctx, a); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAlmaMater, auditID(a.ID), nil, a)
+	})
+}
+
+func (r *auditedAcademicLogRepo) UpdateAlmaMater(ctx context.Context, a *model.AlmaMater, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAlmaMaterByID(ctx, a.ID))
+		if err := r.AcademicLogRepository.UpdateAlmaMater(ctx, a, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityAlmaMater, auditID(a.ID), before, a)
+	})
+}
+
+func (r *auditedAcademicLogRepo) DeleteAlmaMater(ctx context.Context, id, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := snapshot(r.FindAlmaMaterByID(ctx, id))
+		if err := r.AcademicLogRepository.DeleteAlmaMater(ctx, id, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityAlmaMater, auditID(id), before, nil)
+	})
+}
+
+func (r *auditedAcademicLogRepo) AddUnderGradMajor(ctx context.Context, astronautID, majorID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.AddUnderGradMajor(ctx, astronautID, majorID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "majorId": majorID}
+		return r.record(ctx, model.AuditCreate, model.EntityAstronautUndergradMajor, auditLink(astronautID, majorID), nil, link)
+	})
+}
+
+func (r *auditedAcademicLogRepo) DeleteAstronautUnderGradMajor(ctx context.Context, astronautID, majorID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.DeleteAstronautUnderGradMajor(ctx, astronautID, majorID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "majorId": majorID}
+		return r.record(ctx, model.AuditDelete, model.EntityAstronautUndergradMajor, auditLink(astronautID, majorID), link, nil)
+	})
+}
+
+func (r *auditedAcademicLogRepo) AddGradMajor(ctx context.Context, astronautID, majorID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.AddGradMajor(ctx, astronautID, majorID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "majorId": majorID}
+		return r.record(ctx, model.AuditCreate, model.EntityAstronautGradMajor, auditLink(astronautID, majorID), nil, link)
+	})
+}
+
+func (r *auditedAcademicLogRepo) DeleteAstronautGradMajor(ctx context.Context, astronautID, majorID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.DeleteAstronautGradMajor(ctx, astronautID, majorID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "majorId": majorID}
+		return r.record(ctx, model.AuditDelete, model.EntityAstronautGradMajor, auditLink(astronautID, majorID), link, nil)
+	})
+}
+
+func (r *auditedAcademicLogRepo) AddAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.AddAstronautAlmaMater(ctx, astronautID, almaMaterID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "almaMaterId": almaMaterID}
+		return r.record(ctx, model.AuditCreate, model.EntityAstronautAlmaMater, auditLink(astronautID, almaMaterID), nil, link)
+	})
+}
+
+func (r *auditedAcademicLogRepo) DeleteAstronautAlmaMater(ctx context.Context, astronautID, almaMaterID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.AcademicLogRepository.DeleteAstronautAlmaMater(ctx, astronautID, almaMaterID); err != nil {
+			return err
+		}
+		link := map[string]int{"astronautId": astronautID, "almaMaterId": almaMaterID}
+		return r.record(ctx, model.AuditDelete, model.EntityAstronautAlmaMater, auditLink(astronautID, almaMaterID), link, nil)
+	})
+}
+
+type auditedUserRepo struct {
+	model.UserRepository
+	auditor
+}
+
+func NewAuditedUserRepo(r model.UserRepository, audit model.AuditRepository) model.UserRepository {
+	return &auditedUserRepo{r, auditor{audit}}
+}
+
+func (r *auditedUserRepo) CreateUser(ctx context.Context, u *model.User, k *model.APIKey) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.CreateUser(ctx, u, k); err != nil {
+			return err
+		}
+		if err := r.record(ctx, model.AuditCreate, model.EntityUser, auditID(u.ID), nil, userSnapshot(u)); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAPIKey, auditID(k.ID), nil, apiKeySnapshot(k))
+	})
+}
+
+func (r *auditedUserRepo) UpdateUser(ctx context.Context, u *model.User, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		var before any
+		if usr, err := r.FindUserByID(ctx, u.ID); err == nil {
+			before = userSnapshot(usr)
+		}
+		if err := r.UserRepository.UpdateUser(ctx, u, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityUser, auditID(u.ID), before, userSnapshot(u))
+	})
+}
+
+func (r *auditedUserRepo) DeleteUser(ctx context.Context, id, version int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		var before any
+		if usr, err := r.FindUserByID(ctx, id); err == nil {
+			before = userSnapshot(usr)
+		}
+		if err := r.UserRepository.DeleteUser(ctx, id, version); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityUser, auditID(id), before, nil)
+	})
+}
+
+func (r *auditedUserRepo) RestoreUser(ctx context.Context, id int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.RestoreUser(ctx, id); err != nil {
+			return err
+		}
+		var after any
+		if usr, err := r.FindUserByID(ctx, id); err == nil {
+			after = userSnapshot(usr)
+		}
+		return r.record(ctx, model.AuditRestore, model.EntityUser, auditID(id), nil, after)
+	})
+}
+
+func (r *auditedUserRepo) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var purged int
+	err := r.audit(ctx, func(ctx context.Context) (err error) {
+		if purged, err = r.UserRepository.PurgeUsers(ctx, deletedBefore); err != nil {
+			return err
+		}
+		return r.recordPurge(ctx, model.EntityUser, purged, deletedBefore)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// RestUserPassword records that the password changed, the hashes are never recorded.
+func (r *auditedUserRepo) RestUserPassword(ctx context.Context, hash string, id int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.RestUserPassword(ctx, hash, id); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditUpdate, model.EntityUserPassword, auditID(id), nil, nil)
+	})
+}
+
+func (r *auditedUserRepo) GiveAdminPrivileges(ctx context.Context, id int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.GiveAdminPrivileges(ctx, id); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAdmin, auditID(id), nil, map[string]int{"userId": id})
+	})
+}
+
+func (r *auditedUserRepo) RevokeAdminPrivileges(ctx context.Context, id int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.UserRepository.RevokeAdminPrivileges(ctx, id); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityAdmin, auditID(id), map[string]int{"userId": id}, nil)
+	})
+}
+
+type auditedAPIKeyRepo struct {
+	model.APIKeyRepository
+	auditor
+}
+
+// NewAuditedAPIKeyRepo audits changes to API keys, a key being used is not recorded.
+func NewAuditedAPIKeyRepo(r model.APIKeyRepository, audit model.AuditRepository) model.APIKeyRepository {
+	return &auditedAPIKeyRepo{r, auditor{audit}}
+}
+
+// keySnapshot reads the user's key with keyID, nil when it could not be read.
+func (r *auditedAPIKeyRepo) keySnapshot(ctx context.Context, userID, keyID int) any {
+	keys, err := r.FindAPIKeys(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	for _, k := range keys {
+		if k.ID == keyID {
+			return apiKeySnapshot(k)
+		}
+	}
+	return nil
+}
+
+func (r *auditedAPIKeyRepo) CreateAPIKey(ctx context.Context, k *model.APIKey) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		if err := r.APIKeyRepository.CreateAPIKey(ctx, k); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAPIKey, auditID(k.ID), nil, apiKeySnapshot(k))
+	})
+}
+
+// RotateAPIKey records the old key being cut short to the grace window and the new key replacing it.
+func (r *auditedAPIKeyRepo) RotateAPIKey(ctx context.Context, userID, keyID int, next *model.APIKey, graceUntil time.Time) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := r.keySnapshot(ctx, userID, keyID)
+		if err := r.APIKeyRepository.RotateAPIKey(ctx, userID, keyID, next, graceUntil); err != nil {
+			return err
+		}
+		if err := r.record(ctx, model.AuditUpdate, model.EntityAPIKey, auditID(keyID), before, r.keySnapshot(ctx, userID, keyID)); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditCreate, model.EntityAPIKey, auditID(next.ID), nil, apiKeySnapshot(next))
+	})
+}
+
+func (r *auditedAPIKeyRepo) RevokeAPIKey(ctx context.Context, userID, keyID int) error {
+	return r.audit(ctx, func(ctx context.Context) error {
+		before := r.keySnapshot(ctx, userID, keyID)
+		if err := r.APIKeyRepository.RevokeAPIKey(ctx, userID, keyID); err != nil {
+			return err
+		}
+		return r.record(ctx, model.AuditDelete, model.EntityAPIKey, auditID(keyID), before, nil)
+	})
+}
+
+type auditedDatasetRepo struct {
+	model.DatasetRepository
+	auditor
+}
+
+func NewAuditedDatasetRepo(r model.DatasetRepository, audit model.AuditRepository) model.DatasetRepository {
+	return &auditedDatasetRepo{r, auditor{audit}}
+}
+
+// ImportAstronautData records every astronaut the import creates, skipped and failed records change nothing.
+func (r *auditedDatasetRepo) ImportAstronautData(ctx context.Context, data []*model.AstronautData) ([]*model.ImportRow, error) {
+	var rows []*model.ImportRow
+	err := r.audit(ctx, func(ctx context.Context) (err error) {
+		if rows, err = r.DatasetRepository.ImportAstronautData(ctx, data); err != nil {
+			return err
+		}
+		for i, row := range rows {
+			if row.Status != model.ImportInserted {
+				continue
+			}
+			if err := r.record(ctx, model.AuditImport, model.EntityAstronaut, auditID(row.AstronautID), nil, data[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+// DatasetTimeout bounds a full import or export of the dataset, which takes far longer than a single lookup.
+const DatasetTimeout = 5 * time.Minute
+
+// datasetHeader lists the csv tags of model.AstronautData in dataset column order.
+var datasetHeader = []string{
+	"Name", "Year", "Group", "Status", "Birth Date", "Birth Place", "Gender", "Alma Mater",
+	"Undergraduate Major", "Graduate Major", "Military Rank", "Military Branch", "Space Flights",
+	"Space Flight (hr)", "Space Walks", "Space Walk (hr)", "Missions", "Death Date", "Death Mission",
+}
+
+// DecodeAstronautCSV reads NASA astronaut dataset rows, matching columns by the csv tags on
+// model.AstronautData so column order does not matter. Only a malformed csv is an error, a cell that
+// cannot be decoded is recorded on its record's Problems so the import reports that row as failed.
+func DecodeAstronautCSV(r io.Reader) ([]*model.AstronautData, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "astronaut data not provided",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "invalid astronaut csv",
+			Exception: err.Error(),
+		}
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimSpace(h)] = i
+	}
+	if _, ok := columns["Name"]; !ok {
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "invalid astronaut csv; missing Name column",
+			Exception: "csv header does not contain a Name column",
+		}
+	}
+
+	var data []*model.AstronautData
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			message := "invalid astronaut csv"
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				message = fmt.Sprintf("invalid astronaut csv on line %d", parseErr.StartLine)
+			}
+			return nil, &model.APIError{
+				Code:      http.StatusBadRequest,
+				Message:   message,
+				Exception: err.Error(),
+			}
+		}
+		// a quoted cell can span lines, so the line is taken from the reader rather than counted
+		line, _ := reader.FieldPos(0)
+
+		get := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		d := &model.AstronautData{
+			Name:               get("Name"),
+			Status:             get("Status"),
+			BirthDate:          get("Birth Date"),
+			BirthPlace:         get("Birth Place"),
+			Gender:             get("Gender"),
+			AlmaMater:          splitCell(get("Alma Mater"), ";"),
+			UndergraduateMajor: splitCell(get("Undergraduate Major"), ";"),
+			GraduateMajor:      splitCell(get("Graduate Major"), ";"),
+			MilitaryRank:       get("Military Rank"),
+			MilitaryBranch:     get("Military Branch"),
+			Missions:           splitCell(get("Missions"), ","),
+			DeathDate:          get("Death Date"),
+			DeathMission:       get("Death Mission"),
+			Line:               line,
+		}
+
+		// a bad number fails only its own record, which the import reports along with the others
+		numbers := []struct {
+			column string
+			field  string
+			value  *int
+		}{
+			{"Year", "year", &d.Year},
+			{"Group", "group", &d.Group},
+			{"Space Flights", "spaceFlights", &d.SpaceFlights},
+			{"Space Flight (hr)", "spaceFlightHours", &d.SpaceFlightHours},
+			{"Space Walks", "spaceWalks", &d.SpaceWalks},
+			{"Space Walk (hr)", "spaceWalkHours", &d.SpaceWalkHours},
+		}
+		for _, n := range numbers {
+			cell := get(n.column)
+			if *n.value, err = parseDatasetInt(cell); err != nil {
+				d.Problems.Add(n.field, model.CodeInvalidFormat, fmt.Sprintf("%s must be a number, got %q", n.column, cell))
+			}
+		}
+
+		data = append(data, d)
+	}
+
+	return data, nil
+}
+
+// splitCell splits a multi-valued cell dropping blank values.
+func splitCell(s, sep string) []string {
+	var values []string
+	for _, v := range strings.Split(s, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseDatasetInt accepts blank cells and float formatted numbers ("1996.0") found in the dataset.
+func parseDatasetInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int(f), nil
+}
+
+// ImportAstronautData validates each record and writes the valid ones, reporting the outcome of every
+// record in the order of data. Each row is numbered by the csv line its record was decoded from, or
+// by its position in data counting from 1 for records decoded from JSON.
+func ImportAstronautData(ctx context.Context, repository model.DatasetRepository, data []*model.AstronautData) (*model.ImportReport, error) {
+	ctx, span := tracing.Start(ctx, "service.ImportAstronautData")
+	defer span.End()
+
+	report := &model.ImportReport{Rows: make([]*model.ImportRow, len(data))}
+
+	var valid []*model.AstronautData
+	var index []int
+
+	for i, d := range data {
+		d.Normalize()
+
+		if err := validate(d, "Astronaut Data"); err != nil {
+			var apiErr *model.APIError
+			errors.As(err, &apiErr)
+			report.Rows[i] = &model.ImportRow{
+				Row:    importRowNumber(d, i),
+				Name:   d.Name,
+				Status: model.ImportFailed,
+				Error:  apiErr.Message,
+				Errors: apiErr.Problems,
+			}
+			continue
+		}
+
+		valid = append(valid, d)
+		index = append(index, i)
+	}
+
+	if len(valid) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, DatasetTimeout)
+		defer cancel()
+
+		rows, err := repository.ImportAstronautData(ctx, valid)
+		if err != nil {
+			return nil, &model.APIError{
+				Code:      http.StatusInternalServerError,
+				Message:   "failed to import astronaut data",
+				Exception: err.Error(),
+			}
+		}
+
+		for i, row := range rows {
+			row.Row = importRowNumber(valid[i], index[i])
+			report.Rows[index[i]] = row
+		}
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case model.ImportInserted:
+			report.Inserted++
+		case model.ImportSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// importRowNumber numbers the record d at index i of the imported data.
+func importRowNumber(d *model.AstronautData, i int) int {
+	if d.Line > 0 {
+		return d.Line
+	}
+	return i + 1
+}
+
+// DatasetEncoder writes astronaut data records to an underlying stream. Close must be called once every
+// record has been encoded to terminate the document.
+type DatasetEncoder interface {
+	Encode(d *model.AstronautData) error
+	Close() error
+}
+
+// NewDatasetEncoder returns an encoder for format (csv, json or ndjson) and the content type it writes.
+func NewDatasetEncoder(w io.Writer, format string) (DatasetEncoder, string, error) {
+	switch format {
+	case "", "csv":
+		return &csvEncoder{w: csv.NewWriter(w)}, "text/csv", nil
+	case "json":
+		return &jsonArrayEncoder{w: w, enc: json.NewEncoder(w)}, "application/json", nil
+	case "ndjson":
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, "application/x-ndjson", nil
+	default:
+		return nil, "", &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "format must be one of csv, json or ndjson",
+			Exception: fmt.Sprintf("unknown export format: %s", format),
+		}
+	}
+}
+
+type csvEncoder struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(d *model.AstronautData) error {
+	if !e.wroteHeader {
+		e.wroteHeader = true
+		if err := e.w.Write(datasetHeader); err != nil {
+			return err
+		}
+	}
+
+	record := []string{
+		d.Name, formatDatasetInt(d.Year), formatDatasetInt(d.Group), d.Status, d.BirthDate, d.BirthPlace,
+		d.Gender, strings.Join(d.AlmaMater, "; "), strings.Join(d.UndergraduateMajor, "; "),
+		strings.Join(d.GraduateMajor, "; "), d.MilitaryRank, d.MilitaryBranch, strconv.Itoa(d.SpaceFlights),
+		strconv.Itoa(d.SpaceFlightHours), strconv.Itoa(d.SpaceWalks), strconv.Itoa(d.SpaceWalkHours),
+		strings.Join(d.Missions, ", "), d.DeathDate, d.DeathMission,
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.wroteHeader {
+		e.wroteHeader = true
+		if err := e.w.Write(datasetHeader); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// formatDatasetInt leaves values the API does not store, such as selection year and group, blank.
+func formatDatasetInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+type jsonArrayEncoder struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func (e *jsonArrayEncoder) Encode(d *model.AstronautData) error {
+	sep := ","
+	if e.count == 0 {
+		sep = "["
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	return e.enc.Encode(d)
+}
+
+func (e *jsonArrayEncoder) Close() error {
+	end := "]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(d *model.AstronautData) error {
+	return e.enc.Encode(d)
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// ExportAstronautData streams every astronaut record into enc.
+func ExportAstronautData(ctx context.Context, repository model.DatasetRepository, enc DatasetEncoder) error {
+	ctx, span := tracing.Start(ctx, "service.ExportAstronautData")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, DatasetTimeout)
+	defer cancel()
+
+	if err := repository.StreamAstronautData(ctx, enc.Encode); err != nil {
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to export astronaut data",
+			Exception: err.Error(),
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to export astronaut data",
+			Exception: err.Error(),
+		}
+	}
+	return nil
+}
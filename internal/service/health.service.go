@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+// readinessTimeout bounds the database checks of a readiness probe.
+const readinessTimeout = 2 * time.Second
+
+// buildTime is set at build time with -ldflags "-X .../internal/service.buildTime=...", builds
+// without it report the time of their commit.
+var buildTime string
+
+// Readiness checks whether the server can take traffic, it stops being ready once Drain is called so
+// load balancers stop sending requests before the server shuts down.
+type Readiness struct {
+	repository model.HealthRepository
+	migrations uint
+	draining   atomic.Bool
+}
+
+// NewReadiness returns readiness checks expecting the database schema to be at migration version
+// migrations.
+func NewReadiness(repository model.HealthRepository, migrations uint) *Readiness {
+	return &Readiness{
+		repository: repository,
+		migrations: migrations,
+	}
+}
+
+// Drain marks the server as shutting down.
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
+
+// Check runs every readiness check, the report is ready only when all of them pass.
+func (r *Readiness) Check(ctx context.Context) *model.ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{
+		"draining":   model.CheckOK,
+		"database":   model.CheckOK,
+		"migrations": model.CheckOK,
+	}
+
+	if r.draining.Load() {
+		checks["draining"] = "server is shutting down"
+	}
+
+	if err := r.repository.Ping(ctx); err != nil {
+		checks["database"] = err.Error()
+		checks["migrations"] = "database unavailable"
+	} else {
+		version, dirty, err := r.repository.SchemaVersion(ctx)
+		switch {
+		case err != nil:
+			checks["migrations"] = err.Error()
+		case dirty:
+			checks["migrations"] = fmt.Sprintf("migration %d failed part way", version)
+		case version != r.migrations:
+			checks["migrations"] = fmt.Sprintf("schema is at version %d, expected %d", version, r.migrations)
+		}
+	}
+
+	report := &model.ReadinessReport{Status: model.StatusReady, Checks: checks}
+	for _, result := range checks {
+		if result != model.CheckOK {
+			report.Status = model.StatusNotReady
+		}
+	}
+	return report
+}
+
+// GetBuildInfo describes the running build from the module and version control information the Go
+// toolchain embeds in the binary.
+func GetBuildInfo() *model.BuildInfo {
+	info := &model.BuildInfo{Version: "unknown", BuildTime: buildTime}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.Version = bi.Main.Version
+	info.GoVersion = bi.GoVersion
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
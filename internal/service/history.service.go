@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+// GetAstronautAsOf returns the astronaut as they were recorded at asOf, only admins see a deleted
+// astronaut as they were before the deletion.
+func GetAstronautAsOf(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	r model.AstronautRepository,
+	id int,
+	asOf time.Time,
+) (*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautAsOf")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkAstronautVisible(ctx, userRepository, r, id); err != nil {
+		return nil, err
+	}
+
+	a, err := r.FindAstronautAsOf(ctx, id, asOf)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "not found as of " + asOf.Format(time.RFC3339),
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to retrieve astronaut",
+			Exception: err.Error(),
+		}
+	default:
+		return a, nil
+	}
+}
+
+func GetAstronautLogAsOf(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	astroLogRepo model.AstronautLogRepository,
+	id int,
+	asOf time.Time,
+) (*model.AstronautLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautLogAsOf")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkAstronautVisible(ctx, userRepository, astronautRepository, id); err != nil {
+		return nil, err
+	}
+
+	al, err := astroLogRepo.FindAstronautLogAsOf(ctx, id, asOf)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "AstronautLog not found as of " + asOf.Format(time.RFC3339),
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to find AstronautLog",
+			Exception: err.Error(),
+		}
+	default:
+		return al, nil
+	}
+}
+
+func GetMilitaryLogAsOf(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	militaryLogRepo model.MilitaryLogRepository,
+	astronautID int,
+	asOf time.Time,
+) (*model.MilitaryLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMilitaryLogAsOf")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkAstronautVisible(ctx, userRepository, astronautRepository, astronautID); err != nil {
+		return nil, err
+	}
+
+	ml, err := militaryLogRepo.FindMilitaryLogAsOf(ctx, astronautID, asOf)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "Military Log not found as of " + asOf.Format(time.RFC3339),
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get Astronaut Military Log",
+			Exception: err.Error(),
+		}
+	default:
+		return ml, nil
+	}
+}
+
+// GetAstronautHistory returns every recorded version of an astronaut and their log and military records,
+// only admins see the history of a deleted astronaut.
+func GetAstronautHistory(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+	astronautID int,
+) (*model.AstronautHistory, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautHistory")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkAstronautVisible(ctx, userRepository, astronautRepository, astronautID); err != nil {
+		return nil, err
+	}
+
+	h := &model.AstronautHistory{AstronautID: astronautID}
+
+	var err error
+	if h.Astronaut, err = astronautRepository.FindAstronautVersions(ctx, astronautID); err != nil {
+		return nil, historyError(err)
+	}
+	if h.Log, err = astronautLogRepository.FindAstronautLogVersions(ctx, astronautID); err != nil {
+		return nil, historyError(err)
+	}
+	if h.Military, err = militaryLogRepository.FindMilitaryLogVersions(ctx, astronautID); err != nil {
+		return nil, historyError(err)
+	}
+
+	if len(h.Astronaut) == 0 {
+		return nil, historyNotFound("astronaut has no recorded versions")
+	}
+	return h, nil
+}
+
+func historyNotFound(exception string) error {
+	return &model.APIError{
+		Code:      http.StatusNotFound,
+		Message:   "not found",
+		Exception: exception,
+	}
+}
+
+// checkAstronautVisible returns a not found error for a deleted astronaut unless the request user is an
+// admin, the past versions of a deleted astronaut and their records are only read by admins.
+func checkAstronautVisible(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	astronautID int,
+) error {
+	_, err := astronautRepository.FindAstronautByID(ctx, astronautID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		usr, _ := model.UserFromContext(ctx)
+		if usr == nil {
+			return historyNotFound("astronaut does not exist or has been deleted")
+		}
+		isAdmin, err := CheckAdminPermission(ctx, userRepository, usr.ID)
+		if err != nil {
+			return err
+		}
+		if !isAdmin {
+			return historyNotFound("astronaut does not exist or has been deleted")
+		}
+		return nil
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to retrieve astronaut",
+			Exception: err.Error(),
+		}
+	}
+	return nil
+}
+
+func historyError(err error) error {
+	return &model.APIError{
+		Code:      http.StatusInternalServerError,
+		Message:   "failed to retrieve astronaut history",
+		Exception: err.Error(),
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DomainMetrics are the astronaut and mission counts. They are counted by Refresh rather than on
+// every scrape, so scrapes never query the database.
+type DomainMetrics struct {
+	statsRepository model.StatsRepository
+	astronauts      *prometheus.GaugeVec
+	missions        *prometheus.GaugeVec
+}
+
+// NewDomainMetrics registers the astronaut and mission counts in reg, they are empty until the first
+// Refresh.
+func NewDomainMetrics(reg prometheus.Registerer, statsRepository model.StatsRepository) *DomainMetrics {
+	return &DomainMetrics{
+		statsRepository: statsRepository,
+		astronauts: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "astronauts",
+			Help: "Astronauts by the status of their log.",
+		}, []string{"status"}),
+		missions: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "missions",
+			Help: "Missions by whether they were successful.",
+		}, []string{"successful"}),
+	}
+}
+
+// Refresh counts the astronauts and missions, a count that fails keeps its last value.
+func (m *DomainMetrics) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var errs []error
+
+	astronauts, err := m.statsRepository.CountAstronautsByStatus(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("counting astronauts: %w", err))
+	} else {
+		m.astronauts.Reset()
+		for status, n := range astronauts {
+			m.astronauts.WithLabelValues(status).Set(float64(n))
+		}
+	}
+
+	missions, err := m.statsRepository.CountMissionsBySuccess(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("counting missions: %w", err))
+	} else {
+		m.missions.Reset()
+		for successful, n := range missions {
+			m.missions.WithLabelValues(strconv.FormatBool(successful)).Set(float64(n))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RunDomainMetrics refreshes m every interval until ctx is done, starting with an immediate refresh.
+func RunDomainMetrics(ctx context.Context, logger *slog.Logger, m *DomainMetrics, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Refresh(ctx); err != nil {
+			logger.Error("refreshing metrics failed", slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -8,9 +8,14 @@ import (
 	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+	"github.com/lib/pq"
 )
 
 func AddMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, ml *model.MilitaryLog) (*model.MilitaryLog, error) {
+	ctx, span := tracing.Start(ctx, "service.AddMilitaryLog")
+	defer span.End()
+
 	if err := validate(ml, "MilitaryLog"); err != nil {
 		return nil, err
 	}
@@ -20,6 +25,14 @@ func AddMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogReposi
 
 	err := militaryLogRepo.CreateMilitaryLog(ctx, ml)
 	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Astronaut not found",
+				Exception: pgErr.Message,
+			}
+		}
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to add Military Log",
@@ -30,6 +43,9 @@ func AddMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogReposi
 }
 
 func GetMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, astronautID int) (*model.MilitaryLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMilitaryLog")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -53,6 +69,9 @@ func GetMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogReposi
 }
 
 func GetMilitaryLogs(ctx context.Context, militaryLogRepo model.MilitaryLogRepository) ([]*model.MilitaryLog, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMilitaryLogs")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -67,7 +86,10 @@ func GetMilitaryLogs(ctx context.Context, militaryLogRepo model.MilitaryLogRepos
 	return mls, err
 }
 
-func UpdateMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, ml *model.MilitaryLog) error {
+func UpdateMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, ml *model.MilitaryLog, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateMilitaryLog")
+	defer span.End()
+
 	if err := validate(ml, "Military Log"); err != nil {
 		return err
 	}
@@ -75,8 +97,11 @@ func UpdateMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRep
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := militaryLogRepo.UpdateMilitaryLog(ctx, ml)
+	err := militaryLogRepo.UpdateMilitaryLog(ctx, ml, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Military Log")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -94,12 +119,39 @@ func UpdateMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRep
 	}
 }
 
-func DeleteMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, astronautID int) error {
+// PatchMilitaryLog applies a merge or JSON patch to the military log and saves the result.
+func PatchMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, astronautID int, patch model.Patch, version int) (*model.MilitaryLog, error) {
+	ctx, span := tracing.Start(ctx, "service.PatchMilitaryLog")
+	defer span.End()
+
+	ml, err := GetMilitaryLog(ctx, militaryLogRepo, astronautID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPatch(patch, ml, "Military log"); err != nil {
+		return nil, err
+	}
+	ml.AstronautID = astronautID
+
+	if err := UpdateMilitaryLog(ctx, militaryLogRepo, ml, version); err != nil {
+		return nil, err
+	}
+	return ml, nil
+}
+
+func DeleteMilitaryLog(ctx context.Context, militaryLogRepo model.MilitaryLogRepository, astronautID, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteMilitaryLog")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := militaryLogRepo.DeleteMilitaryLog(ctx, astronautID)
+	err := militaryLogRepo.DeleteMilitaryLog(ctx, astronautID, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Military Log")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
 	"github.com/lib/pq"
 	"net/http"
 	"time"
 )
 
 func AddMission(ctx context.Context, r model.MissionRepository, m *model.Mission) (*model.Mission, error) {
+	ctx, span := tracing.Start(ctx, "service.AddMission")
+	defer span.End()
+
 	if err := validate(m, "Mission"); err != nil {
 		return nil, err
 	}
@@ -39,6 +43,9 @@ func AddMission(ctx context.Context, r model.MissionRepository, m *model.Mission
 }
 
 func GetMission(ctx context.Context, r model.MissionRepository, id int) (*model.Mission, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMission")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -61,22 +68,44 @@ func GetMission(ctx context.Context, r model.MissionRepository, id int) (*model.
 	}
 }
 
-func GetMissions(ctx context.Context, r model.MissionRepository) ([]*model.Mission, error) {
+func GetMissions(
+	ctx context.Context,
+	r model.MissionRepository,
+	opts model.QueryOptions,
+	f model.MissionFilter,
+) (*model.Page[*model.Mission], error) {
+	ctx, span := tracing.Start(ctx, "service.GetMissions")
+	defer span.End()
+
+	if err := validateQuery(opts, f); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	missions, err := r.FindAllMissions(ctx)
-	if err != nil {
+	page, err := r.FindAllMissions(ctx, opts, f)
+	switch {
+	case errors.Is(err, model.ErrInvalidQuery):
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Exception: err.Error(),
+		}
+	case err != nil:
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "fail to get missions",
 			Exception: err.Error(),
 		}
 	}
-	return missions, nil
+	return page, nil
 }
 
 func SearchMissionName(ctx context.Context, r model.MissionRepository, target string) ([]*model.Mission, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchMissionName")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -91,7 +120,10 @@ func SearchMissionName(ctx context.Context, r model.MissionRepository, target st
 	return missions, nil
 }
 
-func UpdateMission(ctx context.Context, r model.MissionRepository, m *model.Mission) error {
+func UpdateMission(ctx context.Context, r model.MissionRepository, m *model.Mission, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateMission")
+	defer span.End()
+
 	if err := validate(m, "Mission"); err != nil {
 		return err
 	}
@@ -99,7 +131,28 @@ func UpdateMission(ctx context.Context, r model.MissionRepository, m *model.Miss
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := r.UpdateMission(ctx, m); err != nil {
+	if err := r.UpdateMission(ctx, m, version); err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return &model.APIError{
+				Code:      http.StatusBadRequest,
+				Message:   "Mission already exists",
+				Exception: pgErr.Message,
+			}
+		}
+
+		var conflict *model.VersionConflictError
+		if errors.As(err, &conflict) {
+			return preconditionFailed(conflict, "Mission")
+		}
+
+		if errors.Is(err, model.ErrNoChange) {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Mission not found",
+				Exception: err.Error(),
+			}
+		}
 		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "fail to update mission",
@@ -109,11 +162,40 @@ func UpdateMission(ctx context.Context, r model.MissionRepository, m *model.Miss
 	return nil
 }
 
+// PatchMission applies a merge or JSON patch to the mission and saves the result.
+func PatchMission(ctx context.Context, r model.MissionRepository, id int, patch model.Patch, version int) (*model.Mission, error) {
+	ctx, span := tracing.Start(ctx, "service.PatchMission")
+	defer span.End()
+
+	m, err := GetMission(ctx, r, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPatch(patch, m, "Mission"); err != nil {
+		return nil, err
+	}
+	m.ID = id
+
+	if err := UpdateMission(ctx, r, m, version); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func RegisterAstronautToMission(ctx context.Context, r model.MissionRepository, astronautID, missionID int) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := r.CreateAstronautMission(ctx, astronautID, missionID); err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "Mission and/or Astronaut not found",
+				Exception: pgErr.Message,
+			}
+		}
 		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to register astronaut to mission",
@@ -124,6 +206,9 @@ func RegisterAstronautToMission(ctx context.Context, r model.MissionRepository,
 }
 
 func GetMissionsByAstronaut(ctx context.Context, r model.MissionRepository, astronautID int) ([]*model.Mission, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMissionsByAstronaut")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -138,7 +223,28 @@ func GetMissionsByAstronaut(ctx context.Context, r model.MissionRepository, astr
 	return missions, nil
 }
 
+func GetMissionCrew(ctx context.Context, r model.MissionRepository, missionID int) ([]*model.Astronaut, error) {
+	ctx, span := tracing.Start(ctx, "service.GetMissionCrew")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	astronauts, err := r.FindAstronautsByMission(ctx, missionID)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to get mission crew",
+			Exception: err.Error(),
+		}
+	}
+	return astronauts, nil
+}
+
 func RemoveAstronautFromMission(ctx context.Context, r model.MissionRepository, astronautID, missionID int) error {
+	ctx, span := tracing.Start(ctx, "service.RemoveAstronautFromMission")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -161,12 +267,18 @@ func RemoveAstronautFromMission(ctx context.Context, r model.MissionRepository,
 	}
 }
 
-func DeleteMission(ctx context.Context, r model.MissionRepository, id int) error {
+func DeleteMission(ctx context.Context, r model.MissionRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteMission")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := r.DeleteMission(ctx, id)
+	err := r.DeleteMission(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "Mission")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusNotFound,
@@ -184,3 +296,37 @@ func DeleteMission(ctx context.Context, r model.MissionRepository, id int) error
 
 	}
 }
+
+// RestoreMission undoes a soft delete, bringing back the mission along with its crew.
+func RestoreMission(ctx context.Context, r model.MissionRepository, id int) error {
+	ctx, span := tracing.Start(ctx, "service.RestoreMission")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := r.RestoreMission(ctx, id)
+	var pgErr *pq.Error
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "Mission name now in use by another mission",
+			Exception: pgErr.Message,
+		}
+	case errors.Is(err, model.ErrNoChange):
+		return &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "Deleted mission not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "fail to restore mission",
+			Exception: err.Error(),
+		}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+// GetAstronautProfile loads every section of an astronaut profile concurrently under one deadline.
+// Only a failure to load the astronaut itself fails the request, other sections report their error
+// on the profile. A section with no record, such as an astronaut without military service, is left
+// empty without an error.
+func GetAstronautProfile(
+	ctx context.Context,
+	astronautRepo model.AstronautRepository,
+	astroLogRepo model.AstronautLogRepository,
+	militaryLogRepo model.MilitaryLogRepository,
+	academicLogRepo model.AcademicLogRepository,
+	missionRepo model.MissionRepository,
+	astronautID int,
+) (*model.AstronautProfile, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAstronautProfile")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	p := new(model.AstronautProfile)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	section := func(name string, load func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := load()
+			if err == nil {
+				return
+			}
+
+			var apiErr *model.APIError
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+				return
+			}
+
+			exception := err.Error()
+			if apiErr != nil {
+				exception = apiErr.Exception
+			}
+			model.LoggerFromContext(ctx).Error("loading profile section",
+				slog.String("section", name),
+				slog.Int("astronaut_id", astronautID),
+				slog.String("exception", exception),
+			)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if p.Errors == nil {
+				p.Errors = make(map[string]string)
+			}
+			if apiErr != nil {
+				p.Errors[name] = apiErr.Message
+			} else {
+				p.Errors[name] = err.Error()
+			}
+		}()
+	}
+
+	var astronautErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Astronaut, astronautErr = GetAstronaut(ctx, astronautRepo, astronautID)
+	}()
+
+	section("log", func() (err error) {
+		p.Log, err = GetAstronautLog(ctx, astroLogRepo, astronautID)
+		return err
+	})
+	section("military", func() (err error) {
+		p.Military, err = GetMilitaryLog(ctx, militaryLogRepo, astronautID)
+		return err
+	})
+	section("education", func() (err error) {
+		p.Education, err = GetAstronautAcademicLog(ctx, academicLogRepo, astronautID)
+		return err
+	})
+	section("missions", func() (err error) {
+		p.Missions, err = GetMissionsByAstronaut(ctx, missionRepo, astronautID)
+		return err
+	})
+
+	wg.Wait()
+
+	if astronautErr != nil {
+		return nil, astronautErr
+	}
+	return p, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+// PurgeDeleted hard deletes the astronauts, missions and users soft deleted before deletedBefore, and
+// prunes the daily quota counts of the days before it.
+func PurgeDeleted(
+	ctx context.Context,
+	astronautRepository model.AstronautRepository,
+	missionRepository model.MissionRepository,
+	userRepository model.UserRepository,
+	quotaRepository model.QuotaRepository,
+	deletedBefore time.Time,
+) (*model.PurgeReport, error) {
+	ctx, span := tracing.Start(ctx, "service.PurgeDeleted")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	report := &model.PurgeReport{DeletedBefore: deletedBefore}
+
+	var err error
+	if report.Astronauts, err = astronautRepository.PurgeAstronauts(ctx, deletedBefore); err != nil {
+		return report, purgeError(err)
+	}
+	if report.Missions, err = missionRepository.PurgeMissions(ctx, deletedBefore); err != nil {
+		return report, purgeError(err)
+	}
+	if report.Users, err = userRepository.PurgeUsers(ctx, deletedBefore); err != nil {
+		return report, purgeError(err)
+	}
+	if report.Quotas, err = quotaRepository.PurgeQuotas(ctx, deletedBefore); err != nil {
+		return report, purgeError(err)
+	}
+	return report, nil
+}
+
+// RunPurge purges rows that have been soft deleted for longer than retention, and quota counts older
+// than it, every interval until ctx is done, starting with an immediate run.
+func RunPurge(
+	ctx context.Context,
+	logger *slog.Logger,
+	astronautRepository model.AstronautRepository,
+	missionRepository model.MissionRepository,
+	userRepository model.UserRepository,
+	quotaRepository model.QuotaRepository,
+	retention, interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := PurgeDeleted(ctx, astronautRepository, missionRepository, userRepository, quotaRepository, time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("purge failed", slog.Any("error", err), slog.Any("report", report))
+		} else {
+			logger.Info("purge complete", slog.Any("report", report))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeError(err error) error {
+	return &model.APIError{
+		Code:      http.StatusInternalServerError,
+		Message:   "failed to purge deleted records",
+		Exception: err.Error(),
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+)
+
+// sweepInterval is how often buckets that have refilled are dropped, a full bucket is the same as none.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// RateLimiter hands out tokens from a bucket per client and scope and counts requests against the
+// client's daily quota. Buckets are held in memory, quotas are persisted so they survive restarts.
+type RateLimiter struct {
+	repository model.QuotaRepository
+	limits     model.RateLimits
+	now        func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewRateLimiter returns a limiter reading the time from now, time.Now is used when now is nil.
+func NewRateLimiter(repository model.QuotaRepository, limits model.RateLimits, now func() time.Time) *RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+
+	return &RateLimiter{
+		repository: repository,
+		limits:     limits,
+		now:        now,
+		buckets:    make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token for a request by subject to a route requiring scope.
+func (l *RateLimiter) Allow(ctx context.Context, subject, scope string) (*model.RateLimitStatus, error) {
+	now := l.now()
+	status := l.take(subject, scope, now)
+
+	quota := l.limits.DailyQuota
+	if !status.Allowed || quota <= 0 {
+		return status, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	used, err := l.repository.IncrementQuota(ctx, subject, now)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to check rate limit",
+			Exception: err.Error(),
+		}
+	}
+
+	status.QuotaLimit = quota
+	status.QuotaRemaining = max(quota-used, 0)
+	if used > quota {
+		day := now.UTC().Truncate(24 * time.Hour)
+		status.Allowed = false
+		status.RetryAfter = day.Add(24 * time.Hour).Sub(now)
+	}
+	return status, nil
+}
+
+func (l *RateLimiter) take(subject, scope string, now time.Time) *model.RateLimitStatus {
+	rl := l.limits.For(scope)
+	burst := float64(rl.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= sweepInterval {
+		for k, b := range l.buckets {
+			if !now.Before(b.full) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	key := subject + " " + scope
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*rl.Rate)
+		b.updated = now
+	}
+
+	status := &model.RateLimitStatus{Limit: rl.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = seconds((1 - b.tokens) / rl.Rate)
+	}
+
+	status.Remaining = int(b.tokens)
+	status.Reset = seconds((burst - b.tokens) / rl.Rate)
+	b.full = now.Add(status.Reset)
+
+	return status
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
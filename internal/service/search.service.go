@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+func Search(ctx context.Context, r model.SearchRepository, q model.SearchQuery) ([]*model.SearchResult, error) {
+	ctx, span := tracing.Start(ctx, "service.Search")
+	defer span.End()
+
+	if err := validate(q, "search"); err != nil {
+		return nil, err
+	}
+	q.Text = strings.TrimSpace(q.Text)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	results, err := r.Search(ctx, q)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to search",
+			Exception: err.Error(),
+		}
+	}
+	return results, nil
+}
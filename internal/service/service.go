@@ -11,17 +11,56 @@ import (
 
 const hashingCost = 12
 
+// dummyHash is a bcrypt hash at hashingCost that no password matches, used to keep failed logins
+// for unknown emails as slow as those for a wrong password.
+const dummyHash = "$2a$12$dbWfVL20/4XhCO9IIHw.mOU7cKZl1gIf1peKZrb9I4j4I2hVWCSku"
+
 func validate(validator model.Validator, name string) error {
 	problems, isValid := validator.Valid()
 	if !isValid {
-		msg := fmt.Sprintf("Invalid %s input", name)
-		for _, problem := range problems {
-			msg += fmt.Sprintf("; %s", problem)
-		}
 		return &model.APIError{
 			Code:      http.StatusBadRequest,
-			Message:   msg,
+			Message:   fmt.Sprintf("Invalid %s input", name),
 			Exception: fmt.Sprintf("Invalid %s input", name),
+			Problems:  problems,
+		}
+	}
+	return nil
+}
+
+// validateQuery checks the paging options and filter of a list request.
+func validateQuery(opts model.QueryOptions, filter model.Validator) error {
+	if err := validate(opts, "query"); err != nil {
+		return err
+	}
+	return validate(filter, "filter")
+}
+
+// preconditionFailed reports a write made against a version of name that has since changed, the client
+// has to read it again to get the current ETag.
+func preconditionFailed(conflict *model.VersionConflictError, name string) error {
+	return &model.APIError{
+		Code:      http.StatusPreconditionFailed,
+		Message:   fmt.Sprintf("%s has been modified since it was read", name),
+		Exception: conflict.Error(),
+	}
+}
+
+// applyPatch applies a PATCH body to v, which still has to be validated before it is saved.
+func applyPatch(patch model.Patch, v any, name string) error {
+	err := patch.Apply(v)
+	switch {
+	case errors.Is(err, model.ErrPatchTestFailed):
+		return &model.APIError{
+			Code:      http.StatusConflict,
+			Message:   fmt.Sprintf("%s does not match the patch test", name),
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   fmt.Sprintf("Invalid %s patch", name),
+			Exception: err.Error(),
 		}
 	}
 	return nil
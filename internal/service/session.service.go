@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+)
+
+const tokenIssuer = "astronaut-api"
+
+var errInvalidToken = errors.New("invalid access token")
+
+// jwtHeader is the only header issued and accepted, tokens signed with any other algorithm are rejected.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Tokens signs and verifies session access tokens and sets the lifetime of access and refresh tokens.
+type Tokens struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokens(secret string, accessTTL, refreshTTL time.Duration) *Tokens {
+	return &Tokens{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (t *Tokens) sign(c claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.signature(unsigned), nil
+}
+
+func (t *Tokens) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (t *Tokens) verify(token string, now time.Time) (*claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, errInvalidToken
+	}
+
+	if !hmac.Equal([]byte(parts[2]), []byte(t.signature(parts[0]+"."+parts[1]))) {
+		return nil, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	c := new(claims)
+	if err := json.Unmarshal(payload, c); err != nil {
+		return nil, errInvalidToken
+	}
+
+	if c.Issuer != tokenIssuer || now.Unix() >= c.ExpiresAt {
+		return nil, errInvalidToken
+	}
+	return c, nil
+}
+
+// issue returns a new token pair for the session along with the hash of the refresh token to store.
+func (t *Tokens) issue(s *model.Session, refreshToken string, now time.Time) (*model.TokenPair, error) {
+	access, err := t.sign(claims{
+		Issuer:    tokenIssuer,
+		Subject:   strconv.Itoa(s.UserID),
+		SessionID: s.ID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(t.accessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken digests refresh tokens and API keys for storage, both are random enough that a fast
+// unsalted hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func unauthorised(exception string) error {
+	return &model.APIError{
+		Code:      http.StatusUnauthorized,
+		Message:   "User unathorised to make request",
+		Exception: exception,
+	}
+}
+
+func Login(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	tokens *Tokens,
+	c *model.Credentials,
+) (*model.TokenPair, error) {
+	ctx, span := tracing.Start(ctx, "service.Login")
+	defer span.End()
+
+	if err := validate(c, "Credentials"); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	usr, err := userRepository.FindUserByEmail(ctx, c.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// compare against a dummy hash so unknown emails take as long as wrong passwords
+		validatePasswordHash(dummyHash, c.Password)
+		return nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "invalid email or password",
+			Exception: "unknown email",
+		}
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to login",
+			Exception: err.Error(),
+		}
+	}
+
+	if !validatePasswordHash(usr.Password, c.Password) {
+		return nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "invalid email or password",
+			Exception: "password mismatch",
+		}
+	}
+
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to login",
+			Exception: err.Error(),
+		}
+	}
+
+	now := time.Now()
+	s := &model.Session{UserID: usr.ID, ExpiresAt: now.Add(tokens.refreshTTL)}
+
+	if err := sessionRepository.CreateSession(ctx, s, hash); err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to create session",
+			Exception: err.Error(),
+		}
+	}
+
+	pair, err := tokens.issue(s, refresh, now)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to login",
+			Exception: err.Error(),
+		}
+	}
+	return pair, nil
+}
+
+// RefreshSession exchanges a refresh token for a new token pair. The refresh token is rotated, so each
+// one can only be used once.
+func RefreshSession(
+	ctx context.Context,
+	sessionRepository model.SessionRepository,
+	tokens *Tokens,
+	refreshToken string,
+) (*model.TokenPair, error) {
+	ctx, span := tracing.Start(ctx, "service.RefreshSession")
+	defer span.End()
+
+	if refreshToken == "" {
+		return nil, unauthorised("refresh token not supplied")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	oldHash := hashToken(refreshToken)
+
+	s, err := sessionRepository.FindSessionByRefreshToken(ctx, oldHash)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, unauthorised("unknown refresh token")
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to refresh session",
+			Exception: err.Error(),
+		}
+	}
+
+	now := time.Now()
+	if !s.Active(now) {
+		return nil, unauthorised("session expired or revoked")
+	}
+
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to refresh session",
+			Exception: err.Error(),
+		}
+	}
+
+	s.ExpiresAt = now.Add(tokens.refreshTTL)
+
+	err = sessionRepository.RotateRefreshToken(ctx, s.ID, oldHash, hash, s.ExpiresAt)
+	switch {
+	case errors.Is(err, model.ErrNoChange):
+		return nil, unauthorised("refresh token already used")
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to refresh session",
+			Exception: err.Error(),
+		}
+	}
+
+	pair, err := tokens.issue(s, refresh, now)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to refresh session",
+			Exception: err.Error(),
+		}
+	}
+	return pair, nil
+}
+
+// Logout revokes the session the refresh token belongs to, ending every access token issued under it.
+func Logout(ctx context.Context, sessionRepository model.SessionRepository, refreshToken string) error {
+	ctx, span := tracing.Start(ctx, "service.Logout")
+	defer span.End()
+
+	if refreshToken == "" {
+		return unauthorised("refresh token not supplied")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := sessionRepository.FindSessionByRefreshToken(ctx, hashToken(refreshToken))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return unauthorised("unknown refresh token")
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to logout",
+			Exception: err.Error(),
+		}
+	}
+
+	err = sessionRepository.RevokeSession(ctx, s.ID)
+	if err != nil && !errors.Is(err, model.ErrNoChange) {
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to logout",
+			Exception: err.Error(),
+		}
+	}
+	return nil
+}
+
+// VerifyAccessToken returns the user a bearer token was issued to, provided its session is still active.
+func VerifyAccessToken(
+	ctx context.Context,
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	tokens *Tokens,
+	token string,
+) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "service.VerifyAccessToken")
+	defer span.End()
+
+	now := time.Now()
+
+	c, err := tokens.verify(token, now)
+	if err != nil {
+		return nil, unauthorised(err.Error())
+	}
+
+	uid, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return nil, unauthorised(errInvalidToken.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := sessionRepository.FindSessionByID(ctx, c.SessionID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, unauthorised("unknown session")
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to verify access token",
+			Exception: err.Error(),
+		}
+	}
+
+	if !s.Active(now) || s.UserID != uid {
+		return nil, unauthorised("session expired or revoked")
+	}
+
+	usr, err := userRepository.FindUserByID(ctx, uid)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, unauthorised("unknown user")
+	case err != nil:
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to verify access token",
+			Exception: err.Error(),
+		}
+	}
+	return usr, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
 	"github.com/lib/pq"
 )
 
@@ -29,7 +30,16 @@ func RegisterUser(ctx context.Context, repository model.UserRepository, user *mo
 		}
 	}
 
-	err = repository.CreateUser(ctx, user)
+	k, err := newAPIKey(model.DefaultKeyName, model.AllScopes)
+	if err != nil {
+		return nil, &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to register user",
+			Exception: err.Error(),
+		}
+	}
+
+	err = repository.CreateUser(ctx, user, k)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -45,10 +55,16 @@ func RegisterUser(ctx context.Context, repository model.UserRepository, user *mo
 			Exception: err.Error(),
 		}
 	}
+
+	// the key is only ever shown here, only its digest is stored
+	user.APIKey = k.Key
 	return user, nil
 }
 
 func SearchUserID(ctx context.Context, repository model.UserRepository, id int) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchUserID")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -72,6 +88,9 @@ func SearchUserID(ctx context.Context, repository model.UserRepository, id int)
 }
 
 func SearchUserEmail(ctx context.Context, repository model.UserRepository, email string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchUserEmail")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -94,22 +113,44 @@ func SearchUserEmail(ctx context.Context, repository model.UserRepository, email
 	}
 }
 
-func GetUsers(ctx context.Context, repository model.UserRepository) ([]*model.User, error) {
+func GetUsers(
+	ctx context.Context,
+	repository model.UserRepository,
+	opts model.QueryOptions,
+	f model.UserFilter,
+) (*model.Page[*model.User], error) {
+	ctx, span := tracing.Start(ctx, "service.GetUsers")
+	defer span.End()
+
+	if err := validateQuery(opts, f); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	us, err := repository.FindAllUsers(ctx)
-	if err != nil {
+	page, err := repository.FindAllUsers(ctx, opts, f)
+	switch {
+	case errors.Is(err, model.ErrInvalidQuery):
+		return nil, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   err.Error(),
+			Exception: err.Error(),
+		}
+	case err != nil:
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to find Users",
 			Exception: err.Error(),
 		}
 	}
-	return us, nil
+	return page, nil
 }
 
-func UpdateUser(ctx context.Context, repository model.UserRepository, user *model.User) error {
+func UpdateUser(ctx context.Context, repository model.UserRepository, user *model.User, version int) error {
+	ctx, span := tracing.Start(ctx, "service.UpdateUser")
+	defer span.End()
+
 	if err := validate(user, "User"); err != nil {
 		return err
 	}
@@ -117,7 +158,7 @@ func UpdateUser(ctx context.Context, repository model.UserRepository, user *mode
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := repository.UpdateUser(ctx, user)
+	err := repository.UpdateUser(ctx, user, version)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -128,6 +169,11 @@ func UpdateUser(ctx context.Context, repository model.UserRepository, user *mode
 			}
 		}
 
+		var conflict *model.VersionConflictError
+		if errors.As(err, &conflict) {
+			return preconditionFailed(conflict, "User")
+		}
+
 		if errors.Is(err, model.ErrNoChange) {
 			return &model.APIError{
 				Code:      http.StatusBadRequest,
@@ -145,12 +191,41 @@ func UpdateUser(ctx context.Context, repository model.UserRepository, user *mode
 	return nil
 }
 
-func DeleteUser(ctx context.Context, repository model.UserRepository, id int) error {
+// PatchUser applies a merge or JSON patch to the user and saves the result.
+func PatchUser(ctx context.Context, repository model.UserRepository, id int, patch model.Patch, version int) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "service.PatchUser")
+	defer span.End()
+
+	u, err := SearchUserID(ctx, repository, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the patched user is decoded from its JSON, which never holds the password hash
+	hash := u.Password
+	if err := applyPatch(patch, u, "User"); err != nil {
+		return nil, err
+	}
+	u.ID, u.Password = id, hash
+
+	if err := UpdateUser(ctx, repository, u, version); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func DeleteUser(ctx context.Context, repository model.UserRepository, id, version int) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteUser")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := repository.DeleteUser(ctx, id)
+	err := repository.DeleteUser(ctx, id, version)
+	var conflict *model.VersionConflictError
 	switch {
+	case errors.As(err, &conflict):
+		return preconditionFailed(conflict, "User")
 	case errors.Is(err, model.ErrNoChange):
 		return &model.APIError{
 			Code:      http.StatusBadRequest,
@@ -169,7 +244,44 @@ func DeleteUser(ctx context.Context, repository model.UserRepository, id int) er
 	}
 }
 
+// RestoreUser undoes a soft delete, the user's keys, sessions and admin rights work again.
+func RestoreUser(ctx context.Context, repository model.UserRepository, id int) error {
+	ctx, span := tracing.Start(ctx, "service.RestoreUser")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := repository.RestoreUser(ctx, id)
+	var pgErr *pq.Error
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "Email now in use by another User",
+			Exception: pgErr.Message,
+		}
+	case errors.Is(err, model.ErrNoChange):
+		return &model.APIError{
+			Code:      http.StatusNotFound,
+			Message:   "Deleted User not found",
+			Exception: err.Error(),
+		}
+	case err != nil:
+		return &model.APIError{
+			Code:      http.StatusInternalServerError,
+			Message:   "failed to restore User",
+			Exception: err.Error(),
+		}
+	default:
+		return nil
+	}
+}
+
 func ResetPassword(ctx context.Context, repository model.UserRepository, password string, userID int) error {
+	ctx, span := tracing.Start(ctx, "service.ResetPassword")
+	defer span.End()
+
 	if err := model.ValidatePassword(password); err != nil {
 		return &model.APIError{
 			Code:      http.StatusBadRequest,
@@ -210,26 +322,22 @@ func ResetPassword(ctx context.Context, repository model.UserRepository, passwor
 	}
 }
 
-func GenerateNewAPIKey(ctx context.Context, repository model.UserRepository, userID int) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	key, err := repository.GenerateNewUserAPIKey(ctx, userID)
-	if err != nil {
-		return "", &model.APIError{
-			Code:      http.StatusInternalServerError,
-			Message:   "failed to generate new API key",
-			Exception: err.Error(),
-		}
-	}
-	return key, nil
-}
-
 func CreateAdmin(ctx context.Context, repository model.UserRepository, userID int) error {
+	ctx, span := tracing.Start(ctx, "service.CreateAdmin")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if err := repository.GiveAdminPrivileges(ctx, userID); err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return &model.APIError{
+				Code:      http.StatusNotFound,
+				Message:   "User not found",
+				Exception: pgErr.Message,
+			}
+		}
 		return &model.APIError{
 			Code:      http.StatusInternalServerError,
 			Message:   "failed to add admin",
@@ -240,6 +348,9 @@ func CreateAdmin(ctx context.Context, repository model.UserRepository, userID in
 }
 
 func RemoveAdmin(ctx context.Context, repository model.UserRepository, userID int) error {
+	ctx, span := tracing.Start(ctx, "service.RemoveAdmin")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -260,30 +371,10 @@ func RemoveAdmin(ctx context.Context, repository model.UserRepository, userID in
 	return nil
 }
 
-func SearchAPIKey(ctx context.Context, repository model.UserRepository, key string) (*model.User, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	usr, err := repository.FindUserByAPIKey(ctx, key)
-	switch {
-	case errors.Is(err, sql.ErrNoRows):
-		return nil, &model.APIError{
-			Code:      http.StatusNotFound,
-			Message:   "User not found",
-			Exception: err.Error(),
-		}
-	case err != nil:
-		return nil, &model.APIError{
-			Code:      http.StatusInternalServerError,
-			Message:   "failed to get user",
-			Exception: err.Error(),
-		}
-	default:
-		return usr, nil
-	}
-}
-
 func CheckAdminPermission(ctx context.Context, repository model.UserRepository, userID int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "service.CheckAdminPermission")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddMajor(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	t.Run("returns an error for invalid major input", func(t *testing.T) {
+		m, err := service.AddMajor(ctx, academicRepo, &model.Major{})
+		if err == nil {
+			t.Errorf("Expected error for invalid major data")
+		}
+		assert.Nil(t, m)
+	})
+
+	t.Run("adds a new major", func(t *testing.T) {
+		m, err := service.AddMajor(ctx, academicRepo, &model.Major{Course: "Physics"})
+		if err != nil {
+			t.Fatalf("Unexpected error adding major: %v", err)
+		}
+		assert.NotEqual(t, 0, m.ID)
+	})
+
+	t.Run("returns an error if major already exists", func(t *testing.T) {
+		_, err := service.AddMajor(ctx, academicRepo, &model.Major{Course: "Physics"})
+		if err == nil {
+			t.Errorf("Expected error for duplicate major")
+		}
+	})
+
+	t.Run("returns a list of majors", func(t *testing.T) {
+		ms, err := service.GetMajors(ctx, academicRepo)
+		if err != nil {
+			t.Fatalf("Unexpected error getting majors: %v", err)
+		}
+		assert.Len(t, ms, 1)
+	})
+}
+
+func TestGetAstronautAcademicLog(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	astronauts := make([]*model.Astronaut, 2)
+	for i, name := range []string{"smith", "jones"} {
+		a := &model.Astronaut{
+			FirstName:  "alex",
+			LastName:   name,
+			Gender:     "M",
+			BirthDate:  "1970-01-01",
+			BirthPlace: "houston,tx",
+		}
+		astronauts[i], err = service.AddAstronaut(ctx, a, astroRepo)
+		if err != nil {
+			t.Fatalf("Unexpected error adding Astronaut: %v", err)
+		}
+	}
+
+	school, err := service.AddAlmaMater(ctx, academicRepo, &model.AlmaMater{School: "Purdue University"})
+	if err != nil {
+		t.Fatalf("Unexpected error adding alma mater: %v", err)
+	}
+
+	major, err := service.AddMajor(ctx, academicRepo, &model.Major{Course: "Aeronautical Engineering"})
+	if err != nil {
+		t.Fatalf("Unexpected error adding major: %v", err)
+	}
+
+	if err := service.AddAstronautAlmaMater(ctx, academicRepo, astronauts[0].ID, school.ID); err != nil {
+		t.Fatalf("Unexpected error adding astronaut alma mater: %v", err)
+	}
+	if err := service.AddAstronautUndergradMajor(ctx, academicRepo, astronauts[0].ID, major.ID); err != nil {
+		t.Fatalf("Unexpected error adding astronaut undergrad major: %v", err)
+	}
+
+	t.Run("returns an error linking an unknown astronaut", func(t *testing.T) {
+		if err := service.AddAstronautGradMajor(ctx, academicRepo, 99, major.ID); err == nil {
+			t.Errorf("Expected error for unknown astronaut")
+		}
+	})
+
+	t.Run("returns only the astronauts own records", func(t *testing.T) {
+		al, err := service.GetAstronautAcademicLog(ctx, academicRepo, astronauts[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting academic log: %v", err)
+		}
+		assert.Len(t, al.AlmaMaters, 1)
+		assert.Len(t, al.UnderGradMajors, 1)
+		assert.Len(t, al.GradMajors, 0)
+
+		al, err = service.GetAstronautAcademicLog(ctx, academicRepo, astronauts[1].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting academic log: %v", err)
+		}
+		assert.Len(t, al.AlmaMaters, 0)
+	})
+
+	t.Run("deletes an alma mater linked to an astronaut", func(t *testing.T) {
+		if err := service.DeleteAlmaMater(ctx, academicRepo, school.ID, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error deleting alma mater: %v", err)
+		}
+
+		al, err := service.GetAstronautAcademicLog(ctx, academicRepo, astronauts[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting academic log: %v", err)
+		}
+		assert.Len(t, al.AlmaMaters, 0)
+	})
+}
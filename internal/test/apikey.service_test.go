@@ -0,0 +1,114 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+	srv := newServer()
+
+	u, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "john",
+		LastName:  "doe",
+		Email:     "john@email.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering a user: %v", err)
+	}
+
+	do := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-KEY", key)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("returns an error for unknown scopes", func(t *testing.T) {
+		k, err := service.CreateAPIKey(ctx, apiKeyRepo, u.ID, &model.APIKey{Name: "ci", Scopes: []string{"rockets:launch"}})
+		if err == nil {
+			t.Errorf("expected error for unknown scope")
+		}
+		assert.Nil(t, k)
+	})
+
+	readOnly, err := service.CreateAPIKey(ctx, apiKeyRepo, u.ID, &model.APIKey{
+		Name:   "dashboard",
+		Scopes: []string{model.ScopeMissionsRead},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating API key: %v", err)
+	}
+
+	t.Run("enforces the scopes of a key", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/missions", readOnly.Key))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/search?q=apollo", readOnly.Key))
+	})
+
+	t.Run("does not let a key grant scopes it does not hold", func(t *testing.T) {
+		keyCtx := model.ContextWithAPIKey(ctx, readOnly)
+		_, err := service.CreateAPIKey(keyCtx, apiKeyRepo, u.ID, &model.APIKey{Name: "escalate", Scopes: []string{model.ScopeUsersAdmin}})
+		if err == nil {
+			t.Errorf("expected error granting a scope the requesting key does not hold")
+		}
+	})
+
+	t.Run("lists every key of the user without the key value", func(t *testing.T) {
+		keys, err := service.GetAPIKeys(ctx, apiKeyRepo, u.ID)
+		if err != nil {
+			t.Fatalf("unexpected error getting API keys: %v", err)
+		}
+		if assert.Len(t, keys, 2) {
+			assert.Empty(t, keys[1].Key)
+			assert.True(t, strings.HasPrefix(readOnly.Key, keys[1].Prefix+"_"))
+			assert.Equal(t, "dashboard", keys[1].Name)
+			assert.NotNil(t, keys[1].LastUsedAt)
+		}
+	})
+
+	t.Run("rotates a key keeping its scopes", func(t *testing.T) {
+		k, err := service.RotateAPIKey(ctx, apiKeyRepo, u.ID, readOnly.ID, 0)
+		if err != nil {
+			t.Fatalf("unexpected error rotating API key: %v", err)
+		}
+		assert.Equal(t, readOnly.Scopes, k.Scopes)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/missions", k.Key))
+
+		// a zero grace window ends the old key immediately
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/missions", readOnly.Key))
+
+		readOnly = k
+	})
+
+	t.Run("returns an error rotating an unknown key", func(t *testing.T) {
+		if _, err := service.RotateAPIKey(ctx, apiKeyRepo, u.ID, 999, time.Hour); err == nil {
+			t.Errorf("expected error rotating unknown key")
+		}
+	})
+
+	t.Run("revokes a key", func(t *testing.T) {
+		if err := service.RevokeAPIKey(ctx, apiKeyRepo, u.ID, readOnly.ID); err != nil {
+			t.Fatalf("unexpected error revoking API key: %v", err)
+		}
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/missions", readOnly.Key))
+
+		if err := service.RevokeAPIKey(ctx, apiKeyRepo, u.ID, readOnly.ID); err == nil {
+			t.Errorf("expected error revoking a revoked key")
+		}
+	})
+}
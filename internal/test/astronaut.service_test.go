@@ -6,6 +6,7 @@ import (
 	"github.com/LaQuannT/astronaut-api/internal/model"
 	"github.com/LaQuannT/astronaut-api/internal/service"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"testing"
 )
 
@@ -102,12 +103,15 @@ func TestGetAstronauts(t *testing.T) {
 	}
 	ctx := context.TODO()
 
+	opts := model.QueryOptions{Limit: model.DefaultLimit}
+
 	t.Run("returns nil if no astronauts found", func(t *testing.T) {
-		astronauts, err := service.GetAstronauts(ctx, astroRepo)
+		page, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{})
 		if err != nil {
 			t.Errorf("Unexpected error getting Astronauts: %v", err)
 		}
-		assert.Nil(t, astronauts)
+		assert.Nil(t, page.Data)
+		assert.Equal(t, 0, page.Total)
 	})
 
 	t.Run("returns an list of astronauts", func(t *testing.T) {
@@ -134,11 +138,65 @@ func TestGetAstronauts(t *testing.T) {
 			}
 		}
 
-		astronauts, err := service.GetAstronauts(ctx, astroRepo)
+		page, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{})
 		if err != nil {
 			t.Errorf("Unexpected error getting Astronauts: %v", err)
 		}
-		assert.Equal(t, 2, len(astronauts))
+		assert.Equal(t, 2, len(page.Data))
+		assert.Empty(t, page.Next)
+	})
+
+	t.Run("pages through astronauts with a cursor", func(t *testing.T) {
+		opts := model.QueryOptions{Limit: 1, Sort: model.ParseSort("-firstName")}
+
+		first, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{})
+		if err != nil {
+			t.Fatalf("Unexpected error getting Astronauts: %v", err)
+		}
+		assert.Len(t, first.Data, 1)
+		assert.Equal(t, 2, first.Total)
+		assert.Equal(t, "john", first.Data[0].FirstName)
+		assert.NotEmpty(t, first.Next)
+
+		opts.Cursor = first.Next
+		second, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{})
+		if err != nil {
+			t.Fatalf("Unexpected error getting Astronauts: %v", err)
+		}
+		assert.Len(t, second.Data, 1)
+		assert.Equal(t, "jane", second.Data[0].FirstName)
+		assert.Empty(t, second.Next)
+	})
+
+	t.Run("filters astronauts by gender", func(t *testing.T) {
+		page, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{Gender: "F"})
+		if err != nil {
+			t.Fatalf("Unexpected error getting Astronauts: %v", err)
+		}
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, 1, page.Total)
+	})
+
+	t.Run("returns an error for an unknown sort field", func(t *testing.T) {
+		opts := model.QueryOptions{Limit: 1, Sort: model.ParseSort("height")}
+
+		page, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{})
+		if err == nil {
+			t.Errorf("Expected error for unknown sort field")
+		}
+		assert.Nil(t, page)
+	})
+
+	t.Run("returns an error for a cursor from another sort", func(t *testing.T) {
+		first, err := service.GetAstronauts(ctx, astroRepo, model.QueryOptions{Limit: 1}, model.AstronautFilter{})
+		if err != nil {
+			t.Fatalf("Unexpected error getting Astronauts: %v", err)
+		}
+
+		opts := model.QueryOptions{Limit: 1, Cursor: first.Next, Sort: model.ParseSort("birthDate")}
+		if _, err := service.GetAstronauts(ctx, astroRepo, opts, model.AstronautFilter{}); err == nil {
+			t.Errorf("Expected error for mismatched cursor")
+		}
 	})
 }
 
@@ -203,7 +261,7 @@ func TestUpdateAstronaut(t *testing.T) {
 			BirthDate: a.BirthDate,
 		}
 
-		if err := service.UpdateAstronaut(ctx, astronaut, astroRepo); err == nil {
+		if err := service.UpdateAstronaut(ctx, astronaut, astroRepo, model.AnyVersion); err == nil {
 			t.Errorf("Expected error for invalid update astronaut")
 		}
 	})
@@ -218,7 +276,7 @@ func TestUpdateAstronaut(t *testing.T) {
 			BirthPlace: "salford,uk",
 		}
 
-		if err := service.UpdateAstronaut(ctx, astronaut, astroRepo); err != nil {
+		if err := service.UpdateAstronaut(ctx, astronaut, astroRepo, model.AnyVersion); err != nil {
 			t.Errorf("Unexpected error updating Astronaut: %v", err)
 		}
 		a, err = service.GetAstronaut(ctx, astroRepo, astronaut.ID)
@@ -230,6 +288,28 @@ func TestUpdateAstronaut(t *testing.T) {
 		assert.Equal(t, astronaut.Gender, a.Gender)
 		assert.Equal(t, astronaut.BirthPlace, a.BirthPlace)
 	})
+
+	t.Run("returns a precondition failed error for a stale version", func(t *testing.T) {
+		stale := a.Version
+		a.BirthPlace = "manchester,uk"
+		if err := service.UpdateAstronaut(ctx, a, astroRepo, stale); err != nil {
+			t.Fatalf("Unexpected error updating Astronaut: %v", err)
+		}
+		assert.Equal(t, stale+1, a.Version)
+
+		a.BirthPlace = "london,uk"
+		err := service.UpdateAstronaut(ctx, a, astroRepo, stale)
+
+		var apiErr *model.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusPreconditionFailed, apiErr.Code)
+		}
+
+		var conflict *model.VersionConflictError
+		if assert.ErrorAs(t, astroRepo.DeleteAstronaut(ctx, a.ID, stale), &conflict) {
+			assert.Equal(t, stale+1, conflict.Current)
+		}
+	})
 }
 
 func TestDeleteAstronaut(t *testing.T) {
@@ -237,14 +317,14 @@ func TestDeleteAstronaut(t *testing.T) {
 
 	t.Run("returns an error for unknown astronaut ID", func(t *testing.T) {
 		id := 7
-		if err := service.DeleteAstronaut(ctx, astroRepo, id); err == nil {
+		if err := service.DeleteAstronaut(ctx, astroRepo, id, model.AnyVersion); err == nil {
 			t.Errorf("Expected error for unknown astronaut ID")
 		}
 	})
 
 	t.Run("deletes an astronaut", func(t *testing.T) {
 		id := 1
-		if err := service.DeleteAstronaut(ctx, astroRepo, id); err != nil {
+		if err := service.DeleteAstronaut(ctx, astroRepo, id, model.AnyVersion); err != nil {
 			t.Errorf("Unexpected error deleting Astronaut: %v", err)
 		}
 	})
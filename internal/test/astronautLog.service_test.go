@@ -5,6 +5,8 @@ import (
 	"github.com/LaQuannT/astronaut-api/internal/model"
 	"github.com/LaQuannT/astronaut-api/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
 	"testing"
 )
 
@@ -47,6 +49,10 @@ func TestAddAstronautLog(t *testing.T) {
 			t.Errorf("Expected error adding astronaut log")
 		}
 		assert.Nil(t, al)
+
+		var apiErr *model.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
 	})
 
 	t.Run("adds a new astronaut log", func(t *testing.T) {
@@ -196,7 +202,7 @@ func TestUpdateAstronautLog(t *testing.T) {
 			Status:      model.Active,
 		}
 
-		err := service.UpdateAstronautLog(ctx, astroLogRepo, l)
+		err := service.UpdateAstronautLog(ctx, astroLogRepo, l, model.AnyVersion)
 		if err == nil {
 			t.Errorf("Expected error updating astronaut log with unknown astronaut ID")
 		}
@@ -206,7 +212,7 @@ func TestUpdateAstronautLog(t *testing.T) {
 		l := &model.AstronautLog{
 			AstronautID: a.ID,
 		}
-		err := service.UpdateAstronautLog(ctx, astroLogRepo, l)
+		err := service.UpdateAstronautLog(ctx, astroLogRepo, l, model.AnyVersion)
 		if err == nil {
 			t.Errorf("Expected error updating astronaut log with invalid astronaut ID")
 		}
@@ -217,7 +223,7 @@ func TestUpdateAstronautLog(t *testing.T) {
 			AstronautID: a.ID,
 			Status:      model.Management,
 		}
-		err := service.UpdateAstronautLog(ctx, astroLogRepo, l)
+		err := service.UpdateAstronautLog(ctx, astroLogRepo, l, model.AnyVersion)
 		if err != nil {
 			t.Errorf("Unexpected error updating AstronautLog: %v", err)
 		}
@@ -235,7 +241,7 @@ func TestDeleteAstronautLog(t *testing.T) {
 
 	t.Run("returns an error deleting log with unknown astronaut ID", func(t *testing.T) {
 		astronautID := 99
-		err := service.DeleteAstronautLog(ctx, astroLogRepo, astronautID)
+		err := service.DeleteAstronautLog(ctx, astroLogRepo, astronautID, model.AnyVersion)
 		if err == nil {
 			t.Errorf("Expected error deleting astronaut log with unknown astronaut ID")
 		}
@@ -244,7 +250,7 @@ func TestDeleteAstronautLog(t *testing.T) {
 	t.Run("deletes an existing astronaut log", func(t *testing.T) {
 		astronautID := 1
 
-		err := service.DeleteAstronautLog(ctx, astroLogRepo, astronautID)
+		err := service.DeleteAstronautLog(ctx, astroLogRepo, astronautID, model.AnyVersion)
 		if err != nil {
 			t.Errorf("Unexpected error deleting AstronautLog: %v", err)
 		}
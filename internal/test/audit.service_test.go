@@ -0,0 +1,218 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/database/postgres"
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditTrail(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("error clearing tables: %v", err)
+	}
+
+	users := service.NewAuditedUserRepo(userRepo, auditRepo)
+	astronauts := service.NewAuditedAstronautRepo(astroRepo, auditRepo)
+
+	usr, err := service.RegisterUser(context.TODO(), users, &model.User{
+		FirstName: "john",
+		LastName:  "doe",
+		Email:     "john@email.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering a user: %v", err)
+	}
+
+	ctx := model.ContextWithRequestID(model.ContextWithUser(context.TODO(), usr), "req-1")
+	opts := model.QueryOptions{Limit: model.DefaultLimit}
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "sally",
+		LastName:   "ride",
+		Gender:     "F",
+		BirthDate:  "1951-05-26",
+		BirthPlace: "Los Angeles, CA",
+	}, astronauts)
+	if err != nil {
+		t.Fatalf("unexpected error adding astronaut: %v", err)
+	}
+	astronautID := strconv.Itoa(a.ID)
+
+	a.BirthPlace = "Encino, CA"
+	if err := service.UpdateAstronaut(ctx, a, astronauts, model.AnyVersion); err != nil {
+		t.Fatalf("unexpected error updating astronaut: %v", err)
+	}
+
+	if err := service.DeleteAstronaut(ctx, astronauts, a.ID, model.AnyVersion); err != nil {
+		t.Fatalf("unexpected error deleting astronaut: %v", err)
+	}
+
+	t.Run("records each change with its actor and request", func(t *testing.T) {
+		page, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{EntityType: model.EntityAstronaut, EntityID: astronautID})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		if !assert.Len(t, page.Data, 3) {
+			return
+		}
+
+		// newest first
+		assert.Equal(t, model.AuditDelete, page.Data[0].Action)
+		assert.Equal(t, model.AuditUpdate, page.Data[1].Action)
+		assert.Equal(t, model.AuditCreate, page.Data[2].Action)
+
+		update := page.Data[1]
+		if assert.NotNil(t, update.ActorID) {
+			assert.Equal(t, usr.ID, *update.ActorID)
+		}
+		assert.Equal(t, "req-1", update.RequestID)
+
+		var before, after model.Astronaut
+		if err := json.Unmarshal(update.Before, &before); err != nil {
+			t.Fatalf("unexpected error decoding before: %v", err)
+		}
+		if err := json.Unmarshal(update.After, &after); err != nil {
+			t.Fatalf("unexpected error decoding after: %v", err)
+		}
+		assert.Equal(t, "Los Angeles, CA", before.BirthPlace)
+		assert.Equal(t, "Encino, CA", after.BirthPlace)
+
+		assert.Nil(t, page.Data[0].After)
+		assert.Nil(t, page.Data[2].Before)
+	})
+
+	t.Run("records registration without an actor or credentials", func(t *testing.T) {
+		page, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{EntityType: model.EntityUser})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		if assert.Len(t, page.Data, 1) {
+			assert.Nil(t, page.Data[0].ActorID)
+			assert.NotContains(t, string(page.Data[0].After), "password")
+			assert.NotContains(t, string(page.Data[0].After), "apiKey")
+		}
+	})
+
+	t.Run("filters by actor and time range", func(t *testing.T) {
+		page, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{ActorID: usr.ID})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		assert.Equal(t, 3, page.Total)
+
+		tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+		page, err = service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{From: tomorrow})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		assert.Equal(t, 0, page.Total)
+	})
+
+	t.Run("records api key changes without the key", func(t *testing.T) {
+		keys := service.NewAuditedAPIKeyRepo(apiKeyRepo, auditRepo)
+
+		k, err := service.CreateAPIKey(ctx, keys, usr.ID, &model.APIKey{Name: "ci", Scopes: []string{model.ScopeAstronautsRead}})
+		if err != nil {
+			t.Fatalf("unexpected error creating api key: %v", err)
+		}
+		rotated, err := service.RotateAPIKey(ctx, keys, usr.ID, k.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error rotating api key: %v", err)
+		}
+		if err := service.RevokeAPIKey(ctx, keys, usr.ID, rotated.ID); err != nil {
+			t.Fatalf("unexpected error revoking api key: %v", err)
+		}
+
+		page, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{EntityType: model.EntityAPIKey, ActorID: usr.ID})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		if !assert.Len(t, page.Data, 4) {
+			return
+		}
+
+		// newest first: revoke, then the rotation's new key and the old key's shortened expiry, then create
+		assert.Equal(t, model.AuditDelete, page.Data[0].Action)
+		assert.Equal(t, strconv.Itoa(rotated.ID), page.Data[0].EntityID)
+		assert.Equal(t, model.AuditCreate, page.Data[1].Action)
+		assert.Equal(t, model.AuditUpdate, page.Data[2].Action)
+		assert.Equal(t, strconv.Itoa(k.ID), page.Data[2].EntityID)
+		assert.Equal(t, model.AuditCreate, page.Data[3].Action)
+
+		for _, e := range page.Data {
+			assert.NotContains(t, string(e.Before)+string(e.After), k.Key)
+			assert.NotContains(t, string(e.Before)+string(e.After), rotated.Key)
+		}
+	})
+
+	t.Run("records imported astronauts", func(t *testing.T) {
+		data, err := service.DecodeAstronautCSV(strings.NewReader(astronautCSV))
+		if err != nil {
+			t.Fatalf("unexpected error decoding csv: %v", err)
+		}
+
+		report, err := service.ImportAstronautData(ctx, service.NewAuditedDatasetRepo(datasetRepo, auditRepo), data)
+		if err != nil {
+			t.Fatalf("unexpected error importing astronaut data: %v", err)
+		}
+
+		page, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{
+			EntityType: model.EntityAstronaut,
+			EntityID:   strconv.Itoa(report.Rows[0].AstronautID),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error getting audit events: %v", err)
+		}
+		if assert.Len(t, page.Data, 1) {
+			assert.Equal(t, model.AuditImport, page.Data[0].Action)
+			assert.Contains(t, string(page.Data[0].After), "STS-119 (Discovery)")
+		}
+	})
+
+	t.Run("does not save a change when its event cannot be recorded", func(t *testing.T) {
+		astronauts := service.NewAuditedAstronautRepo(astroRepo, failingAuditRepo{auditRepo})
+
+		a, err := service.AddAstronaut(ctx, &model.Astronaut{
+			FirstName:  "judith",
+			LastName:   "resnik",
+			Gender:     "F",
+			BirthDate:  "1949-04-05",
+			BirthPlace: "Akron, OH",
+		}, astronauts)
+		if err == nil {
+			t.Fatal("expected error adding astronaut")
+		}
+		assert.Nil(t, a)
+
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: "resnik", Limit: model.DefaultLimit})
+		if err != nil {
+			t.Fatalf("unexpected error searching: %v", err)
+		}
+		assert.Empty(t, results)
+	})
+
+	t.Run("returns an error for an unknown entity", func(t *testing.T) {
+		if _, err := service.GetAuditEvents(ctx, auditRepo, opts, model.AuditFilter{EntityType: "rocket"}); err == nil {
+			t.Error("expected error filtering by unknown entity")
+		}
+	})
+}
+
+// failingAuditRepo cannot record events, as when the audit table is unavailable.
+type failingAuditRepo struct {
+	*postgres.AuditRepository
+}
+
+func (failingAuditRepo) CreateAuditEvent(context.Context, *model.AuditEvent) error {
+	return errors.New("audit_event: permission denied")
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+const astronautCSV = `Name,Year,Group,Status,Birth Date,Birth Place,Gender,Alma Mater,Undergraduate Major,Graduate Major,Military Rank,Military Branch,Space Flights,Space Flight (hr),Space Walks,Space Walk (hr),Missions,Death Date,Death Mission
+Joseph M. Acaba,2004.0,19.0,Active,5/17/1967,"Inglewood, CA",Male,University of California-Santa Barbara; University of Arizona,Geology,Geology,,,2,3307,2,13,"STS-119 (Discovery), ISS-31/32 (Soyuz)",,
+Michael P. Anderson,1995.0,15.0,Deceased,12/25/1959,"Plattsburgh, NY",Male,University of Washington; Creighton University,Physics/Astronomy,Physics,Lieutenant Colonel,US Air Force,2,594,0,0,"STS-89 (Endeavour), STS-107 (Columbia)",2/1/2003,STS-107 (Columbia)
+Jane Unknown,,,Unknown,not a date,,Female,,,,,,0,0,0,0,,,
+`
+
+func TestDecodeAstronautCSV(t *testing.T) {
+	t.Run("returns an error for an empty body", func(t *testing.T) {
+		data, err := service.DecodeAstronautCSV(strings.NewReader(""))
+		if err == nil {
+			t.Errorf("Expected error for empty csv")
+		}
+		assert.Nil(t, data)
+	})
+
+	t.Run("decodes multi-valued columns", func(t *testing.T) {
+		data, err := service.DecodeAstronautCSV(strings.NewReader(astronautCSV))
+		if err != nil {
+			t.Fatalf("Unexpected error decoding csv: %v", err)
+		}
+		assert.Len(t, data, 3)
+		assert.Equal(t, "Joseph M. Acaba", data[0].Name)
+		assert.Equal(t, 2004, data[0].Year)
+		assert.Equal(t, []string{"University of California-Santa Barbara", "University of Arizona"}, data[0].AlmaMater)
+		assert.Len(t, data[0].Missions, 2)
+		assert.Equal(t, 3307, data[0].SpaceFlightHours)
+	})
+
+	t.Run("fails only the row with a bad number", func(t *testing.T) {
+		csv := strings.Replace(astronautCSV, ",2,3307,2,13,", ",2,lots,2,13,", 1)
+
+		data, err := service.DecodeAstronautCSV(strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("Unexpected error decoding csv: %v", err)
+		}
+		assert.Len(t, data, 3)
+
+		report, err := service.ImportAstronautData(context.TODO(), nil, data[:1])
+		if err != nil {
+			t.Fatalf("Unexpected error importing astronaut data: %v", err)
+		}
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, model.ImportFailed, report.Rows[0].Status)
+		if assert.Len(t, report.Rows[0].Errors, 1) {
+			assert.Equal(t, "spaceFlightHours", report.Rows[0].Errors[0].Field)
+			assert.Equal(t, model.CodeInvalidFormat, report.Rows[0].Errors[0].Code)
+		}
+	})
+}
+
+func TestImportAstronautData(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	data, err := service.DecodeAstronautCSV(strings.NewReader(astronautCSV))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding csv: %v", err)
+	}
+
+	t.Run("imports valid rows and reports invalid rows", func(t *testing.T) {
+		report, err := service.ImportAstronautData(ctx, datasetRepo, data)
+		if err != nil {
+			t.Fatalf("Unexpected error importing astronaut data: %v", err)
+		}
+		assert.Equal(t, 2, report.Inserted)
+		assert.Equal(t, 0, report.Skipped)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, model.ImportFailed, report.Rows[2].Status)
+		// rows are numbered by their csv line, counting the header
+		assert.Equal(t, 4, report.Rows[2].Row)
+
+		astronauts, err := service.SearchAstronautByName(ctx, astroRepo, "anderson")
+		if err != nil {
+			t.Fatalf("Unexpected error searching astronauts: %v", err)
+		}
+		assert.Len(t, astronauts, 1)
+		assert.Equal(t, "Michael P.", astronauts[0].FirstName)
+
+		ml, err := service.GetMilitaryLog(ctx, militaryRepo, astronauts[0].ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting military log: %v", err)
+		}
+		assert.Equal(t, "US Air Force", ml.Branch)
+
+		missions, err := service.SearchMissionName(ctx, missionRepo, "STS-107")
+		if err != nil {
+			t.Fatalf("Unexpected error searching missions: %v", err)
+		}
+		assert.Len(t, missions, 1)
+		assert.False(t, missions[0].Successful)
+	})
+
+	t.Run("skips astronauts that already exist", func(t *testing.T) {
+		report, err := service.ImportAstronautData(ctx, datasetRepo, data[:2])
+		if err != nil {
+			t.Fatalf("Unexpected error importing astronaut data: %v", err)
+		}
+		assert.Equal(t, 0, report.Inserted)
+		assert.Equal(t, 2, report.Skipped)
+	})
+}
+
+func TestExportAstronautData(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	data, err := service.DecodeAstronautCSV(strings.NewReader(astronautCSV))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding csv: %v", err)
+	}
+
+	_, err = service.ImportAstronautData(ctx, datasetRepo, data)
+	if err != nil {
+		t.Fatalf("Unexpected error importing astronaut data: %v", err)
+	}
+
+	t.Run("returns an error for an unknown format", func(t *testing.T) {
+		_, _, err := service.NewDatasetEncoder(&strings.Builder{}, "xml")
+		if err == nil {
+			t.Errorf("Expected error for unknown format")
+		}
+	})
+
+	t.Run("round trips exported csv", func(t *testing.T) {
+		var b strings.Builder
+		enc, _, err := service.NewDatasetEncoder(&b, "csv")
+		if err != nil {
+			t.Fatalf("Unexpected error creating encoder: %v", err)
+		}
+
+		if err := service.ExportAstronautData(ctx, datasetRepo, enc); err != nil {
+			t.Fatalf("Unexpected error exporting astronaut data: %v", err)
+		}
+
+		exported, err := service.DecodeAstronautCSV(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("Unexpected error decoding exported csv: %v", err)
+		}
+		assert.Len(t, exported, 2)
+		assert.Equal(t, "Michael P. Anderson", exported[1].Name)
+		assert.Equal(t, "US Air Force", exported[1].MilitaryBranch)
+		assert.Equal(t, "STS-107 (Columbia)", exported[1].DeathMission)
+		assert.Equal(t, []string{"Creighton University", "University of Washington"}, exported[1].AlmaMater)
+
+		report, err := service.ImportAstronautData(ctx, datasetRepo, exported)
+		if err != nil {
+			t.Fatalf("Unexpected error importing exported data: %v", err)
+		}
+		assert.Equal(t, 2, report.Skipped)
+	})
+
+	t.Run("writes an empty json array when there is no data", func(t *testing.T) {
+		if err := clearTables(dbConn); err != nil {
+			t.Fatalf("Error clearing tables: %v", err)
+		}
+
+		var b strings.Builder
+		enc, _, err := service.NewDatasetEncoder(&b, "json")
+		if err != nil {
+			t.Fatalf("Unexpected error creating encoder: %v", err)
+		}
+
+		if err := service.ExportAstronautData(ctx, datasetRepo, enc); err != nil {
+			t.Fatalf("Unexpected error exporting astronaut data: %v", err)
+		}
+		assert.Equal(t, "[]\n", b.String())
+	})
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAstronautHistory(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "ryan",
+		LastName:   "thomas",
+		Gender:     "M",
+		BirthDate:  "1960-01-01",
+		BirthPlace: "usa",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+
+	ml, err := service.AddMilitaryLog(ctx, militaryRepo, &model.MilitaryLog{AstronautID: a.ID, Branch: "navy", Rank: "captain"})
+	if err != nil {
+		t.Fatalf("Unexpected error adding military log: %v", err)
+	}
+
+	ml.Rank = "rear admiral"
+	if err := service.UpdateMilitaryLog(ctx, militaryRepo, ml, model.AnyVersion); err != nil {
+		t.Fatalf("Unexpected error updating military log: %v", err)
+	}
+
+	// an update changing nothing does not start a new version
+	if err := service.UpdateMilitaryLog(ctx, militaryRepo, ml, model.AnyVersion); err != nil {
+		t.Fatalf("Unexpected error updating military log: %v", err)
+	}
+
+	h, err := service.GetAstronautHistory(ctx, userRepo, astroRepo, astroLogRepo, militaryRepo, a.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error getting astronaut history: %v", err)
+	}
+
+	t.Run("keeps every version", func(t *testing.T) {
+		assert.Len(t, h.Astronaut, 1)
+		assert.Empty(t, h.Log)
+		if assert.Len(t, h.Military, 2) {
+			assert.Equal(t, "captain", h.Military[0].Record.Rank)
+			assert.NotNil(t, h.Military[0].ValidTo)
+			assert.Equal(t, "rear admiral", h.Military[1].Record.Rank)
+			assert.Nil(t, h.Military[1].ValidTo)
+		}
+	})
+
+	t.Run("returns the version current at a point in time", func(t *testing.T) {
+		if len(h.Military) != 2 {
+			t.Skip("history not recorded")
+		}
+
+		m, err := service.GetMilitaryLogAsOf(ctx, userRepo, astroRepo, militaryRepo, a.ID, h.Military[0].ValidFrom)
+		if err != nil {
+			t.Fatalf("Unexpected error getting military log: %v", err)
+		}
+		assert.Equal(t, "captain", m.Rank)
+
+		m, err = service.GetMilitaryLogAsOf(ctx, userRepo, astroRepo, militaryRepo, a.ID, *h.Military[0].ValidTo)
+		if err != nil {
+			t.Fatalf("Unexpected error getting military log: %v", err)
+		}
+		assert.Equal(t, "rear admiral", m.Rank)
+	})
+
+	t.Run("returns an error before the record existed", func(t *testing.T) {
+		_, err := service.GetAstronautAsOf(ctx, userRepo, astroRepo, a.ID, time.Date(1995, time.January, 1, 0, 0, 0, 0, time.UTC))
+		if err == nil {
+			t.Error("Expected an error getting an astronaut before it was recorded")
+		}
+	})
+
+	t.Run("only returns the history and past versions of a deleted astronaut to admins", func(t *testing.T) {
+		if err := service.DeleteAstronaut(ctx, astroRepo, a.ID, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error deleting astronaut: %v", err)
+		}
+
+		usr, err := service.RegisterUser(ctx, userRepo, &model.User{
+			FirstName: "john",
+			LastName:  "doe",
+			Email:     "john@email.com",
+			Password:  plainPwd,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error registering user: %v", err)
+		}
+		userCtx := model.ContextWithUser(ctx, usr)
+
+		_, err = service.GetAstronautHistory(userCtx, userRepo, astroRepo, astroLogRepo, militaryRepo, a.ID)
+		var apiErr *model.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+
+		// nor can they read it as it was before the deletion
+		_, err = service.GetAstronautAsOf(userCtx, userRepo, astroRepo, a.ID, h.Astronaut[0].ValidFrom)
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+
+		_, err = service.GetMilitaryLogAsOf(userCtx, userRepo, astroRepo, militaryRepo, a.ID, h.Military[0].ValidFrom)
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+
+		_, err = service.GetAstronautLogAsOf(userCtx, userRepo, astroRepo, astroLogRepo, a.ID, h.Astronaut[0].ValidFrom)
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+
+		if err := service.CreateAdmin(ctx, userRepo, usr.ID); err != nil {
+			t.Fatalf("Unexpected error creating admin: %v", err)
+		}
+
+		deleted, err := service.GetAstronautHistory(userCtx, userRepo, astroRepo, astroLogRepo, militaryRepo, a.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting astronaut history: %v", err)
+		}
+		if assert.Len(t, deleted.Military, 2) {
+			// the deletion ends the military record along with the astronaut
+			assert.NotNil(t, deleted.Military[1].ValidTo)
+		}
+
+		before, err := service.GetAstronautAsOf(userCtx, userRepo, astroRepo, a.ID, h.Astronaut[0].ValidFrom)
+		if err != nil {
+			t.Fatalf("Unexpected error getting astronaut: %v", err)
+		}
+		assert.Equal(t, "ryan", before.FirstName)
+
+		_, err = service.GetMilitaryLogAsOf(userCtx, userRepo, astroRepo, militaryRepo, a.ID, time.Now())
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusNotFound, apiErr.Code)
+	})
+
+	t.Run("returns an error for the history of an unknown astronaut", func(t *testing.T) {
+		if _, err := service.GetAstronautHistory(ctx, userRepo, astroRepo, astroLogRepo, militaryRepo, 999); err == nil {
+			t.Error("Expected an error getting history of unknown astronaut")
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/LaQuannT/astronaut-api/internal/database/postgres"
+	schema "github.com/LaQuannT/astronaut-api/migration"
 	"github.com/joho/godotenv"
 )
 
@@ -18,6 +19,17 @@ var (
 	militaryRepo *postgres.MilitaryLogRepository
 	missionRepo  *postgres.MissionRepository
 	userRepo     *postgres.UserRepository
+	datasetRepo  *postgres.DatasetRepository
+	searchRepo   *postgres.SearchRepository
+	sessionRepo  *postgres.SessionRepository
+	apiKeyRepo   *postgres.APIKeyRepository
+	quotaRepo    *postgres.QuotaRepository
+	auditRepo    *postgres.AuditRepository
+	statsRepo    *postgres.StatsRepository
+	healthRepo   *postgres.HealthRepository
+
+	// latestMigration is the version of the newest embedded migration
+	latestMigration uint
 )
 
 func TestMain(m *testing.M) {
@@ -36,6 +48,14 @@ func TestMain(m *testing.M) {
 	}
 
 	astroRepo, astroLogRepo, academicRepo, militaryRepo, missionRepo, userRepo = postgres.InitializeRepositories(dbConn)
+	datasetRepo = postgres.NewDatasetRepo(dbConn)
+	searchRepo = postgres.NewSearchRepo(dbConn)
+	sessionRepo = postgres.NewSessionRepo(dbConn)
+	apiKeyRepo = postgres.NewAPIKeyRepo(dbConn)
+	quotaRepo = postgres.NewQuotaRepo(dbConn)
+	auditRepo = postgres.NewAuditRepo(dbConn)
+	statsRepo = postgres.NewStatsRepo(dbConn)
+	healthRepo = postgres.NewHealthRepo(dbConn)
 
 	// ensures tables are built
 	err = migration("file://../../migration", connStr, "up")
@@ -43,6 +63,13 @@ func TestMain(m *testing.M) {
 		fmt.Fprintf(os.Stderr, "Error running up migration: %v\n", err)
 		os.Exit(1)
 	}
+
+	latestMigration, err = schema.Latest()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading embedded migrations: %v\n", err)
+		os.Exit(1)
+	}
+
 	exitCode := m.Run()
 	os.Exit(exitCode)
 }
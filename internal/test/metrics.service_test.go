@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainMetrics(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	for _, a := range []*model.Astronaut{
+		{FirstName: "neil", LastName: "armstrong", Gender: "M", BirthDate: "1930-08-05", BirthPlace: "Wapakoneta, OH"},
+		{FirstName: "buzz", LastName: "aldrin", Gender: "M", BirthDate: "1930-01-20", BirthPlace: "Glen Ridge, NJ"},
+	} {
+		if _, err := service.AddAstronaut(ctx, a, astroRepo); err != nil {
+			t.Fatalf("Unexpected error adding astronaut: %v", err)
+		}
+	}
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "sally",
+		LastName:   "ride",
+		Gender:     "F",
+		BirthDate:  "1951-05-26",
+		BirthPlace: "Los Angeles, CA",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+	if _, err := service.AddAstronautLog(ctx, astroLogRepo, &model.AstronautLog{AstronautID: a.ID, Status: model.Retired}); err != nil {
+		t.Fatalf("Unexpected error adding astronaut log: %v", err)
+	}
+
+	if _, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "apollo 11", DateOfMission: "1969-07-16", Successful: true}); err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+
+	reg := prometheus.NewRegistry()
+	stats := &flakyStatsRepo{StatsRepository: statsRepo}
+	m := service.NewDomainMetrics(reg, stats)
+	if err := m.Refresh(ctx); err != nil {
+		t.Fatalf("Unexpected error refreshing metrics: %v", err)
+	}
+
+	// scrapes read the counts of the last refresh, so this mission is only counted by the next one
+	if _, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "apollo 13", DateOfMission: "1970-04-11"}); err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`# HELP astronauts Astronauts by the status of their log.
+# TYPE astronauts gauge
+astronauts{status="retired"} 1
+astronauts{status="unknown"} 2
+# HELP missions Missions by whether they were successful.
+# TYPE missions gauge
+missions{successful="true"} 1
+`), "astronauts", "missions"))
+
+	t.Run("keeps the last counts when counting fails", func(t *testing.T) {
+		stats.fail = true
+		assert.Error(t, m.Refresh(ctx))
+		assert.Equal(t, 2, testutil.CollectAndCount(reg, "astronauts"))
+		assert.Equal(t, 1, testutil.CollectAndCount(reg, "missions"))
+
+		stats.fail = false
+		assert.NoError(t, m.Refresh(ctx))
+		assert.Equal(t, 2, testutil.CollectAndCount(reg, "missions"))
+	})
+}
+
+// flakyStatsRepo fails to count missions while fail is set.
+type flakyStatsRepo struct {
+	model.StatsRepository
+	fail bool
+}
+
+func (r *flakyStatsRepo) CountMissionsBySuccess(ctx context.Context) (map[bool]int, error) {
+	if r.fail {
+		return nil, errors.New("connection reset")
+	}
+	return r.StatsRepository.CountMissionsBySuccess(ctx)
+}
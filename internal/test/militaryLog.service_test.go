@@ -227,7 +227,7 @@ func TestUpdateMilitaryLog(t *testing.T) {
 			Rank:        "major",
 			Retired:     false,
 		}
-		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml)
+		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml, model.AnyVersion)
 		if err == nil {
 			t.Error("Expected an error updating military log")
 		}
@@ -237,7 +237,7 @@ func TestUpdateMilitaryLog(t *testing.T) {
 		ml := &model.MilitaryLog{
 			AstronautID: a.ID,
 		}
-		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml)
+		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml, model.AnyVersion)
 		if err == nil {
 			t.Error("Expected an error updating military log")
 		}
@@ -250,7 +250,7 @@ func TestUpdateMilitaryLog(t *testing.T) {
 			Rank:        "sergeant",
 			Retired:     false,
 		}
-		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml)
+		err = service.UpdateMilitaryLog(ctx, militaryRepo, ml, model.AnyVersion)
 		if err != nil {
 			t.Errorf("Unexpected error updating military log: %v", err)
 		}
@@ -270,7 +270,7 @@ func TestDeleteMilitaryLog(t *testing.T) {
 
 	t.Run("returns error when trying to delete an unknown military log", func(t *testing.T) {
 		astronautID := 67
-		err := service.DeleteMilitaryLog(ctx, militaryRepo, astronautID)
+		err := service.DeleteMilitaryLog(ctx, militaryRepo, astronautID, model.AnyVersion)
 		if err == nil {
 			t.Error("Expected an error deleting military log")
 		}
@@ -278,7 +278,7 @@ func TestDeleteMilitaryLog(t *testing.T) {
 
 	t.Run("deletes an existing military log", func(t *testing.T) {
 		astronautID := 1
-		err := service.DeleteMilitaryLog(ctx, militaryRepo, astronautID)
+		err := service.DeleteMilitaryLog(ctx, militaryRepo, astronautID, model.AnyVersion)
 		if err != nil {
 			t.Errorf("Unexpected error deleting military log: %v", err)
 		}
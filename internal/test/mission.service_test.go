@@ -100,12 +100,14 @@ func TestGetMissions(t *testing.T) {
 	}
 	ctx := context.TODO()
 
+	opts := model.QueryOptions{Limit: model.DefaultLimit}
+
 	t.Run("returns nil if no missions found", func(t *testing.T) {
-		missions, err := service.GetMissions(ctx, missionRepo)
+		page, err := service.GetMissions(ctx, missionRepo, opts, model.MissionFilter{})
 		if err != nil {
 			t.Fatalf("Unexpected error getting missions: %v", err)
 		}
-		assert.Nil(t, missions)
+		assert.Nil(t, page.Data)
 	})
 
 	t.Run("returns a list of missions", func(t *testing.T) {
@@ -129,11 +131,28 @@ func TestGetMissions(t *testing.T) {
 		}
 	})
 
-	missions, err := service.GetMissions(ctx, missionRepo)
+	page, err := service.GetMissions(ctx, missionRepo, opts, model.MissionFilter{})
 	if err != nil {
 		t.Fatalf("Unexpected error getting missions: %v", err)
 	}
-	assert.Len(t, missions, 2)
+	assert.Len(t, page.Data, 2)
+
+	t.Run("filters missions by outcome", func(t *testing.T) {
+		failed := false
+		page, err := service.GetMissions(ctx, missionRepo, opts, model.MissionFilter{Successful: &failed})
+		if err != nil {
+			t.Fatalf("Unexpected error getting missions: %v", err)
+		}
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, "flight y", page.Data[0].Name)
+	})
+
+	t.Run("returns an error for an invalid date range", func(t *testing.T) {
+		_, err := service.GetMissions(ctx, missionRepo, opts, model.MissionFilter{From: "2023-01-01", To: "2022-01-01"})
+		if err == nil {
+			t.Errorf("Expected error for invalid date range")
+		}
+	})
 }
 
 func TestSearchMissionName(t *testing.T) {
@@ -198,7 +217,7 @@ func TestUpdateMission(t *testing.T) {
 			Successful:    false,
 		}
 
-		if err := service.UpdateMission(ctx, missionRepo, mission); err == nil {
+		if err := service.UpdateMission(ctx, missionRepo, mission, model.AnyVersion); err == nil {
 			t.Errorf("Expected error for invalid mission data")
 		}
 	})
@@ -212,7 +231,7 @@ func TestUpdateMission(t *testing.T) {
 			Successful:    m.Successful,
 		}
 
-		if err := service.UpdateMission(ctx, missionRepo, mission); err != nil {
+		if err := service.UpdateMission(ctx, missionRepo, mission, model.AnyVersion); err != nil {
 			t.Errorf("Unexpected error updating mission: %v", err)
 		}
 
@@ -223,6 +242,34 @@ func TestUpdateMission(t *testing.T) {
 		assert.Equal(t, mission.Name, m.Name)
 		assert.Equal(t, mission.Alias, m.Alias)
 	})
+
+	t.Run("updates a mission without a date", func(t *testing.T) {
+		mission := &model.Mission{
+			ID:         1,
+			Name:       "SpaceForce Flight 1",
+			Alias:      "force 12",
+			Successful: true,
+		}
+
+		if err := service.UpdateMission(ctx, missionRepo, mission, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error updating mission: %v", err)
+		}
+
+		m, err := service.GetMission(ctx, missionRepo, mission.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting mission: %v", err)
+		}
+		assert.Equal(t, "", m.DateOfMission)
+		assert.True(t, m.Successful)
+	})
+
+	t.Run("returns an error for a badly formatted date", func(t *testing.T) {
+		mission := &model.Mission{ID: 1, Name: "SpaceForce Flight 1", DateOfMission: "01/02/2022"}
+
+		if err := service.UpdateMission(ctx, missionRepo, mission, model.AnyVersion); err == nil {
+			t.Errorf("Expected error for a badly formatted date")
+		}
+	})
 }
 
 func TestRegisterAstronautToMission(t *testing.T) {
@@ -435,14 +482,14 @@ func TestDeleteMission(t *testing.T) {
 
 	t.Run("returns nil for unknown mission", func(t *testing.T) {
 		missionID := 90
-		err := service.DeleteMission(ctx, missionRepo, missionID)
+		err := service.DeleteMission(ctx, missionRepo, missionID, model.AnyVersion)
 		if err == nil {
 			t.Errorf("Expected error deleting mission")
 		}
 	})
 
 	t.Run("deletes a mission", func(t *testing.T) {
-		err := service.DeleteMission(ctx, missionRepo, m.ID)
+		err := service.DeleteMission(ctx, missionRepo, m.ID, model.AnyVersion)
 		if err != nil {
 			t.Errorf("Unexpected error deleting mission: %v", err)
 		}
@@ -453,3 +500,58 @@ func TestDeleteMission(t *testing.T) {
 		assert.Nil(t, mission)
 	})
 }
+
+func TestGetMissionCrew(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	a := &model.Astronaut{
+		FirstName:  "sally",
+		LastName:   "ride",
+		Gender:     "F",
+		BirthDate:  "1951-05-26",
+		BirthPlace: "los angeles,ca",
+	}
+
+	a, err = service.AddAstronaut(ctx, a, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding Astronaut: %v", err)
+	}
+
+	m := &model.Mission{
+		Name:          "STS-7",
+		DateOfMission: "1983-06-18",
+		Successful:    true,
+	}
+
+	m, err = service.AddMission(ctx, missionRepo, m)
+	if err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+
+	t.Run("returns nil for a mission without crew", func(t *testing.T) {
+		crew, err := service.GetMissionCrew(ctx, missionRepo, m.ID)
+		if err != nil {
+			t.Errorf("Unexpected error getting mission crew: %v", err)
+		}
+		assert.Nil(t, crew)
+	})
+
+	t.Run("returns the astronauts registered to a mission once", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := service.RegisterAstronautToMission(ctx, missionRepo, a.ID, m.ID); err != nil {
+				t.Fatalf("Unexpected error registering astronaut to mission: %v", err)
+			}
+		}
+
+		crew, err := service.GetMissionCrew(ctx, missionRepo, m.ID)
+		if err != nil {
+			t.Errorf("Unexpected error getting mission crew: %v", err)
+		}
+		assert.Len(t, crew, 1)
+		assert.Equal(t, a.ID, crew[0].ID)
+	})
+}
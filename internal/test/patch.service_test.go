@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchAstronaut(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "sally",
+		LastName:   "ride",
+		Gender:     "F",
+		BirthDate:  "1951-05-26",
+		BirthPlace: "Los Angeles, CA",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+
+	t.Run("applies a merge patch", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.MergePatchType, Body: []byte(`{"birthPlace": "Encino, CA"}`)}
+
+		patched, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+		if err != nil {
+			t.Fatalf("Unexpected error patching astronaut: %v", err)
+		}
+		assert.Equal(t, "Encino, CA", patched.BirthPlace)
+		assert.Equal(t, a.FirstName, patched.FirstName)
+		assert.Equal(t, a.Version+1, patched.Version)
+	})
+
+	t.Run("re-validates a patch that clears a field", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.MergePatchType, Body: []byte(`{"lastName": null}`)}
+
+		_, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+
+		var apiErr *model.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+		}
+	})
+
+	t.Run("applies a JSON patch", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.JSONPatchType, Body: []byte(`[
+			{"op": "test", "path": "/lastName", "value": "ride"},
+			{"op": "replace", "path": "/firstName", "value": "sally k."},
+			{"op": "copy", "from": "/birthPlace", "path": "/lastName"},
+			{"op": "replace", "path": "/lastName", "value": "ride"}
+		]`)}
+
+		patched, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+		if err != nil {
+			t.Fatalf("Unexpected error patching astronaut: %v", err)
+		}
+		assert.Equal(t, "sally k.", patched.FirstName)
+		assert.Equal(t, "ride", patched.LastName)
+	})
+
+	t.Run("returns a conflict when a test operation fails", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.JSONPatchType, Body: []byte(`[
+			{"op": "test", "path": "/lastName", "value": "armstrong"},
+			{"op": "replace", "path": "/firstName", "value": "neil"}
+		]`)}
+
+		_, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+
+		var apiErr *model.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusConflict, apiErr.Code)
+		}
+	})
+
+	t.Run("cannot change the astronaut ID", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.MergePatchType, Body: []byte(`{"id": 999}`)}
+
+		patched, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+		if err != nil {
+			t.Fatalf("Unexpected error patching astronaut: %v", err)
+		}
+		assert.Equal(t, a.ID, patched.ID)
+	})
+
+	t.Run("returns an error for an invalid JSON patch", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.JSONPatchType, Body: []byte(`[{"op": "remove", "path": "/unknown"}]`)}
+
+		_, err := service.PatchAstronaut(ctx, astroRepo, a.ID, patch, model.AnyVersion)
+
+		var apiErr *model.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+		}
+	})
+}
+
+func TestPatchAstronautLog(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "john",
+		LastName:   "young",
+		Gender:     "M",
+		BirthDate:  "1930-09-24",
+		BirthPlace: "San Francisco, CA",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+
+	al, err := service.AddAstronautLog(ctx, astroLogRepo, &model.AstronautLog{
+		AstronautID:  a.ID,
+		SpaceFlights: 6,
+		Status:       model.Deceased,
+		DeathDate:    "2018-01-05",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut log: %v", err)
+	}
+
+	t.Run("clears a field set to null", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.MergePatchType, Body: []byte(`{"status": "retired", "deathDate": null}`)}
+
+		patched, err := service.PatchAstronautLog(ctx, astroLogRepo, a.ID, patch, al.Version)
+		if err != nil {
+			t.Fatalf("Unexpected error patching astronaut log: %v", err)
+		}
+		assert.Equal(t, model.Retired, patched.Status)
+		assert.Empty(t, patched.DeathDate)
+		assert.Equal(t, 6, patched.SpaceFlights)
+	})
+
+	t.Run("returns a precondition failed error for a stale version", func(t *testing.T) {
+		patch := model.Patch{ContentType: model.MergePatchType, Body: []byte(`{"spaceFlights": 7}`)}
+
+		_, err := service.PatchAstronautLog(ctx, astroLogRepo, a.ID, patch, al.Version)
+
+		var apiErr *model.APIError
+		if assert.ErrorAs(t, err, &apiErr) {
+			assert.Equal(t, http.StatusPreconditionFailed, apiErr.Code)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingMilitaryRepo fails every military log lookup to exercise partial profile failures.
+type failingMilitaryRepo struct {
+	model.MilitaryLogRepository
+}
+
+func (failingMilitaryRepo) FindMilitaryLog(ctx context.Context, astronautID int) (*model.MilitaryLog, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestGetAstronautProfile(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	a := &model.Astronaut{
+		FirstName:  "john",
+		LastName:   "glenn",
+		Gender:     "M",
+		BirthDate:  "1921-07-18",
+		BirthPlace: "cambridge,oh",
+	}
+	a, err = service.AddAstronaut(ctx, a, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding Astronaut: %v", err)
+	}
+
+	_, err = service.AddAstronautLog(ctx, astroLogRepo, &model.AstronautLog{AstronautID: a.ID, Status: model.Deceased})
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut log: %v", err)
+	}
+
+	m, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "Mercury-Atlas 6", DateOfMission: "1962-02-20", Successful: true})
+	if err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+	if err := service.RegisterAstronautToMission(ctx, missionRepo, a.ID, m.ID); err != nil {
+		t.Fatalf("Unexpected error registering astronaut to mission: %v", err)
+	}
+
+	t.Run("returns an error for an unknown astronaut", func(t *testing.T) {
+		p, err := service.GetAstronautProfile(ctx, astroRepo, astroLogRepo, militaryRepo, academicRepo, missionRepo, 99)
+		if err == nil {
+			t.Errorf("Expected error for unknown astronaut")
+		}
+		assert.Nil(t, p)
+	})
+
+	t.Run("returns every section of the profile", func(t *testing.T) {
+		p, err := service.GetAstronautProfile(ctx, astroRepo, astroLogRepo, militaryRepo, academicRepo, missionRepo, a.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting profile: %v", err)
+		}
+		assert.Equal(t, a.ID, p.Astronaut.ID)
+		assert.Equal(t, model.Deceased, p.Log.Status)
+		assert.Nil(t, p.Military)
+		assert.NotNil(t, p.Education)
+		assert.Len(t, p.Missions, 1)
+		assert.Empty(t, p.Errors)
+	})
+
+	t.Run("reports a failing section without failing the profile", func(t *testing.T) {
+		p, err := service.GetAstronautProfile(ctx, astroRepo, astroLogRepo, failingMilitaryRepo{}, academicRepo, missionRepo, a.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting profile: %v", err)
+		}
+		assert.Nil(t, p.Military)
+		assert.Contains(t, p.Errors, "military")
+		assert.Len(t, p.Missions, 1)
+	})
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDelete(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "judith",
+		LastName:   "resnik",
+		Gender:     "F",
+		BirthDate:  "1949-04-05",
+		BirthPlace: "Akron, OH",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+
+	if _, err := service.AddAstronautLog(ctx, astroLogRepo, &model.AstronautLog{AstronautID: a.ID, SpaceFlights: 2, Status: model.Deceased}); err != nil {
+		t.Fatalf("Unexpected error adding astronaut log: %v", err)
+	}
+	if _, err := service.AddMilitaryLog(ctx, militaryRepo, &model.MilitaryLog{AstronautID: a.ID, Branch: "none", Rank: "none"}); err != nil {
+		t.Fatalf("Unexpected error adding military log: %v", err)
+	}
+
+	m, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "STS-41-D", DateOfMission: "1984-08-30", Successful: true})
+	if err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+	if err := service.RegisterAstronautToMission(ctx, missionRepo, a.ID, m.ID); err != nil {
+		t.Fatalf("Unexpected error adding crew: %v", err)
+	}
+
+	t.Run("hides a deleted astronaut and their records", func(t *testing.T) {
+		if err := service.DeleteAstronaut(ctx, astroRepo, a.ID, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error deleting astronaut: %v", err)
+		}
+
+		_, err := service.GetAstronaut(ctx, astroRepo, a.ID)
+		assert.Error(t, err)
+		_, err = service.GetAstronautLog(ctx, astroLogRepo, a.ID)
+		assert.Error(t, err)
+		_, err = service.GetMilitaryLog(ctx, militaryRepo, a.ID)
+		assert.Error(t, err)
+
+		crew, err := service.GetMissionCrew(ctx, missionRepo, m.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting crew: %v", err)
+		}
+		assert.Empty(t, crew)
+
+		assert.Error(t, service.DeleteAstronaut(ctx, astroRepo, a.ID, model.AnyVersion), "deleting twice")
+	})
+
+	t.Run("restores an astronaut with their records", func(t *testing.T) {
+		if err := service.RestoreAstronaut(ctx, astroRepo, a.ID); err != nil {
+			t.Fatalf("Unexpected error restoring astronaut: %v", err)
+		}
+
+		_, err := service.GetAstronaut(ctx, astroRepo, a.ID)
+		assert.NoError(t, err)
+		al, err := service.GetAstronautLog(ctx, astroLogRepo, a.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, al.SpaceFlights)
+		}
+		_, err = service.GetMilitaryLog(ctx, militaryRepo, a.ID)
+		assert.NoError(t, err)
+
+		assert.Error(t, service.RestoreAstronaut(ctx, astroRepo, a.ID), "restoring an astronaut that is not deleted")
+	})
+
+	t.Run("keeps the crew of a deleted mission", func(t *testing.T) {
+		if err := service.DeleteMission(ctx, missionRepo, m.ID, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error deleting mission: %v", err)
+		}
+
+		missions, err := service.GetMissionsByAstronaut(ctx, missionRepo, a.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting missions: %v", err)
+		}
+		assert.Empty(t, missions)
+
+		if err := service.RestoreMission(ctx, missionRepo, m.ID); err != nil {
+			t.Fatalf("Unexpected error restoring mission: %v", err)
+		}
+
+		crew, err := service.GetMissionCrew(ctx, missionRepo, m.ID)
+		if err != nil {
+			t.Fatalf("Unexpected error getting crew: %v", err)
+		}
+		assert.Len(t, crew, 1)
+	})
+
+	t.Run("frees the email of a deleted user", func(t *testing.T) {
+		u := &model.User{FirstName: "test", LastName: "test", Email: "test@test.com", Password: plainPwd}
+
+		usr, err := service.RegisterUser(ctx, userRepo, u)
+		if err != nil {
+			t.Fatalf("Unexpected error registering user: %v", err)
+		}
+		if err := service.DeleteUser(ctx, userRepo, usr.ID, model.AnyVersion); err != nil {
+			t.Fatalf("Unexpected error deleting user: %v", err)
+		}
+
+		_, err = service.SearchUserEmail(ctx, userRepo, u.Email)
+		assert.Error(t, err)
+
+		u = &model.User{FirstName: "other", LastName: "test", Email: "test@test.com", Password: plainPwd}
+		if _, err := service.RegisterUser(ctx, userRepo, u); err != nil {
+			t.Fatalf("Unexpected error registering user with a deleted user's email: %v", err)
+		}
+
+		assert.Error(t, service.RestoreUser(ctx, userRepo, usr.ID), "restoring a user whose email is taken")
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+
+	ctx := context.TODO()
+
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "ronald",
+		LastName:   "mcnair",
+		Gender:     "M",
+		BirthDate:  "1950-10-21",
+		BirthPlace: "Lake City, SC",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+	if _, err := service.AddAstronautLog(ctx, astroLogRepo, &model.AstronautLog{AstronautID: a.ID, Status: model.Deceased}); err != nil {
+		t.Fatalf("Unexpected error adding astronaut log: %v", err)
+	}
+
+	m, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "STS-51-L", DateOfMission: "1986-01-28"})
+	if err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+	if err := service.RegisterAstronautToMission(ctx, missionRepo, a.ID, m.ID); err != nil {
+		t.Fatalf("Unexpected error adding crew: %v", err)
+	}
+
+	usr, err := service.RegisterUser(ctx, userRepo, &model.User{FirstName: "test", LastName: "test", Email: "test@test.com", Password: plainPwd})
+	if err != nil {
+		t.Fatalf("Unexpected error registering user: %v", err)
+	}
+
+	if err := service.DeleteAstronaut(ctx, astroRepo, a.ID, model.AnyVersion); err != nil {
+		t.Fatalf("Unexpected error deleting astronaut: %v", err)
+	}
+	if err := service.DeleteMission(ctx, missionRepo, m.ID, model.AnyVersion); err != nil {
+		t.Fatalf("Unexpected error deleting mission: %v", err)
+	}
+	if err := service.DeleteUser(ctx, userRepo, usr.ID, model.AnyVersion); err != nil {
+		t.Fatalf("Unexpected error deleting user: %v", err)
+	}
+
+	t.Run("keeps rows deleted within the retention period", func(t *testing.T) {
+		report, err := service.PurgeDeleted(ctx, astroRepo, missionRepo, userRepo, quotaRepo, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error purging: %v", err)
+		}
+		assert.Zero(t, report.Astronauts)
+		assert.Zero(t, report.Missions)
+		assert.Zero(t, report.Users)
+	})
+
+	t.Run("hard deletes rows deleted before the cutoff", func(t *testing.T) {
+		report, err := service.PurgeDeleted(ctx, astroRepo, missionRepo, userRepo, quotaRepo, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Unexpected error purging: %v", err)
+		}
+		assert.Equal(t, 1, report.Astronauts)
+		assert.Equal(t, 1, report.Missions)
+		assert.Equal(t, 1, report.Users)
+
+		assert.Error(t, service.RestoreAstronaut(ctx, astroRepo, a.ID))
+		assert.Error(t, service.RestoreMission(ctx, missionRepo, m.ID))
+		assert.Error(t, service.RestoreUser(ctx, userRepo, usr.ID))
+	})
+
+	t.Run("prunes the quota counts of days before the cutoff", func(t *testing.T) {
+		for _, day := range []string{"2024-01-01", "2024-01-02", "2024-01-03"} {
+			d, _ := time.Parse(time.DateOnly, day)
+			if _, err := quotaRepo.IncrementQuota(ctx, "ip:192.0.2.1", d); err != nil {
+				t.Fatalf("Unexpected error counting quota: %v", err)
+			}
+		}
+
+		cutoff := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+		report, err := service.PurgeDeleted(ctx, astroRepo, missionRepo, userRepo, quotaRepo, cutoff)
+		if err != nil {
+			t.Fatalf("Unexpected error purging: %v", err)
+		}
+		assert.Equal(t, 1, report.Quotas)
+
+		// the counts of the cutoff day and after are kept
+		used, err := quotaRepo.IncrementQuota(ctx, "ip:192.0.2.1", cutoff)
+		if err != nil {
+			t.Fatalf("Unexpected error counting quota: %v", err)
+		}
+		assert.Equal(t, 2, used)
+	})
+}
@@ -0,0 +1,238 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	now := time.Date(2024, time.July, 20, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	limits := model.RateLimits{
+		Default: model.RateLimit{Rate: 1, Burst: 2},
+		Scopes: map[string]model.RateLimit{
+			model.ScopeMissionsWrite: {Rate: 0.5, Burst: 1},
+		},
+	}
+	limiter := service.NewRateLimiter(quotaRepo, limits, clock)
+
+	allow := func(l *service.RateLimiter, subject, scope string) *model.RateLimitStatus {
+		status, err := l.Allow(ctx, subject, scope)
+		if err != nil {
+			t.Fatalf("unexpected error taking a token: %v", err)
+		}
+		return status
+	}
+
+	t.Run("rejects requests once the burst is spent", func(t *testing.T) {
+		assert.True(t, allow(limiter, "key:one", "").Allowed)
+
+		status := allow(limiter, "key:one", "")
+		assert.True(t, status.Allowed)
+		assert.Equal(t, 0, status.Remaining)
+
+		status = allow(limiter, "key:one", "")
+		assert.False(t, status.Allowed)
+		assert.Equal(t, time.Second, status.RetryAfter)
+	})
+
+	t.Run("keeps a bucket per subject", func(t *testing.T) {
+		assert.True(t, allow(limiter, "key:two", "").Allowed)
+	})
+
+	t.Run("refills the bucket over time", func(t *testing.T) {
+		now = now.Add(time.Second)
+		assert.True(t, allow(limiter, "key:one", "").Allowed)
+		assert.False(t, allow(limiter, "key:one", "").Allowed)
+	})
+
+	t.Run("uses the limit of the scope", func(t *testing.T) {
+		status := allow(limiter, "key:one", model.ScopeMissionsWrite)
+		assert.True(t, status.Allowed)
+		assert.Equal(t, 1, status.Limit)
+
+		status = allow(limiter, "key:one", model.ScopeMissionsWrite)
+		assert.False(t, status.Allowed)
+		assert.Equal(t, 2*time.Second, status.RetryAfter)
+	})
+
+	t.Run("enforces the daily quota across restarts", func(t *testing.T) {
+		now = time.Date(2024, time.July, 21, 12, 0, 0, 0, time.UTC)
+		limits.DailyQuota = 2
+		limits.Default = model.RateLimit{Rate: 100, Burst: 100}
+
+		assert.True(t, allow(service.NewRateLimiter(quotaRepo, limits, clock), "ip:192.0.2.1", "").Allowed)
+
+		restarted := service.NewRateLimiter(quotaRepo, limits, clock)
+
+		status := allow(restarted, "ip:192.0.2.1", "")
+		assert.True(t, status.Allowed)
+		assert.Equal(t, 0, status.QuotaRemaining)
+
+		status = allow(restarted, "ip:192.0.2.1", "")
+		assert.False(t, status.Allowed)
+		assert.Equal(t, 12*time.Hour, status.RetryAfter)
+
+		// quotas reset at midnight UTC
+		now = now.Add(12 * time.Hour)
+		assert.True(t, allow(restarted, "ip:192.0.2.1", "").Allowed)
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("error clearing tables: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := service.NewRateLimiter(quotaRepo, model.RateLimits{Default: model.RateLimit{Rate: 1, Burst: 1}}, nil)
+	srv := transport.NewServer(
+		logger,
+		limiter,
+		testTokens,
+		userRepo,
+		sessionRepo,
+		apiKeyRepo,
+		astroRepo,
+		astroLogRepo,
+		militaryRepo,
+		academicRepo,
+		missionRepo,
+		datasetRepo,
+		searchRepo,
+		auditRepo,
+		service.NewReadiness(healthRepo, latestMigration),
+		testMetricsToken,
+	)
+
+	register := func() *httptest.ResponseRecorder {
+		body := `{"firstName":"john","lastName":"doe","email":"john@email.com","password":"Qwerty_123"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("sets the rate limit headers", func(t *testing.T) {
+		rec := register()
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+		assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Reset"))
+	})
+
+	t.Run("limits unauthenticated requests by IP", func(t *testing.T) {
+		rec := register()
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	})
+
+	get := func(path, key, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-KEY", key)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("limits made up api keys by IP", func(t *testing.T) {
+		codes := make([]int, 3)
+		for i := range codes {
+			codes[i] = get("/api/v1/missions", randomAPIKey(t), "192.0.2.2:1234").Code
+		}
+		assert.Equal(t, []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+	})
+
+	t.Run("limits verified api keys by key", func(t *testing.T) {
+		usr, err := service.RegisterUser(context.TODO(), userRepo, &model.User{
+			FirstName: "jane",
+			LastName:  "doe",
+			Email:     "jane@email.com",
+			Password:  plainPwd,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error registering user: %v", err)
+		}
+
+		// the key has a bucket of its own even though its IP has spent the IP bucket
+		assert.Equal(t, http.StatusOK, get("/api/v1/missions", usr.APIKey, "192.0.2.2:1234").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get("/api/v1/missions", usr.APIKey, "192.0.2.3:1234").Code)
+	})
+
+	t.Run("limits access tokens by user", func(t *testing.T) {
+		bearer := func(token, remoteAddr string) int {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/missions", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.RemoteAddr = remoteAddr
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			return rec.Code
+		}
+
+		tokens := make([]string, 2)
+		for i, email := range []string{"sam@email.com", "kim@email.com"} {
+			usr, err := service.RegisterUser(context.TODO(), userRepo, &model.User{
+				FirstName: "test",
+				LastName:  "user",
+				Email:     email,
+				Password:  plainPwd,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error registering user: %v", err)
+			}
+			pair, err := service.Login(context.TODO(), userRepo, sessionRepo, testTokens, &model.Credentials{Email: usr.Email, Password: plainPwd})
+			if err != nil {
+				t.Fatalf("unexpected error logging in: %v", err)
+			}
+			tokens[i] = pair.AccessToken
+		}
+
+		// the user's bucket follows them across IPs, and users behind one IP don't share a bucket
+		assert.Equal(t, http.StatusOK, bearer(tokens[0], "192.0.2.4:1234"))
+		assert.Equal(t, http.StatusTooManyRequests, bearer(tokens[0], "192.0.2.5:1234"))
+		assert.Equal(t, http.StatusOK, bearer(tokens[1], "192.0.2.4:1234"))
+	})
+
+	t.Run("does not limit probes", func(t *testing.T) {
+		for range 3 {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+		}
+	})
+}
+
+// randomAPIKey returns a well formed API key that was never issued.
+func randomAPIKey(t *testing.T) string {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	return model.APIKeyPrefix + hex.EncodeToString(id) + "_" + base64.RawURLEncoding.EncodeToString(secret)
+}
@@ -0,0 +1,690 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var testTokens = service.NewTokens("test-secret", time.Minute, time.Hour)
+
+const testMetricsToken = "test-metrics-token"
+
+// newServer builds the full API handler over the test repositories, with limits high enough that
+// tests are not rate limited.
+func newServer() http.Handler {
+	return newServerWith(slog.New(slog.NewTextHandler(io.Discard, nil)), service.NewReadiness(healthRepo, latestMigration))
+}
+
+func newServerWith(logger *slog.Logger, readiness *service.Readiness) http.Handler {
+	limiter := service.NewRateLimiter(quotaRepo, model.RateLimits{Default: model.RateLimit{Rate: 1000, Burst: 1000}}, nil)
+	return transport.NewServer(
+		logger,
+		limiter,
+		testTokens,
+		userRepo,
+		sessionRepo,
+		apiKeyRepo,
+		astroRepo,
+		astroLogRepo,
+		militaryRepo,
+		academicRepo,
+		missionRepo,
+		datasetRepo,
+		searchRepo,
+		auditRepo,
+		readiness,
+		testMetricsToken,
+	)
+}
+
+func TestRoutePolicy(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+	srv := newServer()
+
+	users := make([]*model.User, 2)
+	for i, email := range []string{"one@test.com", "two@test.com"} {
+		u, err := service.RegisterUser(ctx, userRepo, &model.User{
+			FirstName: "test",
+			LastName:  "user",
+			Email:     email,
+			Password:  plainPwd,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error registering user: %v", err)
+		}
+		users[i] = u
+	}
+
+	send := func(method, path, key, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-KEY", key)
+		}
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(method, path, key string) int {
+		return send(method, path, key, "").Code
+	}
+
+	t.Run("rejects requests without an api key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/missions", ""))
+	})
+
+	t.Run("rejects an unknown api key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/missions", "6f1c2a8e-1f0b-4c1e-9b1a-7d5f3e2c1b0a"))
+	})
+
+	t.Run("allows authenticated reads", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/missions", users[0].APIKey))
+	})
+
+	t.Run("forbids non admins from admin routes", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/users", users[0].APIKey))
+		assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/v1/missions/1", users[0].APIKey))
+	})
+
+	t.Run("forbids users from changing another user", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/users/%d", users[1].ID)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, path, users[0].APIKey))
+	})
+
+	t.Run("allows admins to manage other users", func(t *testing.T) {
+		if err := service.CreateAdmin(ctx, userRepo, users[0].ID); err != nil {
+			t.Fatalf("unexpected error creating admin: %v", err)
+		}
+
+		path := fmt.Sprintf("/api/v1/users/%d", users[1].ID)
+		etag := send(http.MethodGet, path, users[0].APIKey, "").Header().Get("ETag")
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, path, users[0].APIKey, etag).Code)
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/users", users[0].APIKey))
+	})
+
+	t.Run("requires If-Match on writes", func(t *testing.T) {
+		path := fmt.Sprintf("/api/v1/users/%d", users[0].ID)
+		assert.Equal(t, http.StatusPreconditionRequired, do(http.MethodDelete, path, users[0].APIKey))
+	})
+
+	t.Run("rejects writes against a stale ETag", func(t *testing.T) {
+		m, err := service.AddMission(ctx, missionRepo, &model.Mission{Name: "Apollo 11", DateOfMission: "1969-07-16", Successful: true})
+		if err != nil {
+			t.Fatalf("unexpected error adding mission: %v", err)
+		}
+		path := fmt.Sprintf("/api/v1/missions/%d", m.ID)
+
+		rec := send(http.MethodGet, path, users[0].APIKey, "")
+		etag := rec.Header().Get("ETag")
+		assert.Equal(t, `"1"`, etag)
+
+		m.Successful = false
+		if err := service.UpdateMission(ctx, missionRepo, m, model.AnyVersion); err != nil {
+			t.Fatalf("unexpected error updating mission: %v", err)
+		}
+
+		assert.Equal(t, http.StatusPreconditionFailed, send(http.MethodDelete, path, users[0].APIKey, etag).Code)
+
+		etag = send(http.MethodGet, path, users[0].APIKey, "").Header().Get("ETag")
+		assert.Equal(t, `"2"`, etag)
+		assert.Equal(t, http.StatusOK, send(http.MethodDelete, path, users[0].APIKey, etag).Code)
+	})
+}
+
+func TestUserResponses(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	srv := newServer()
+
+	send := func(method, path, key, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-KEY", key)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+			req.Header.Set("If-Match", "*")
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/api/v1/register", "", "", `{"firstName":"john","lastName":"doe","email":"john@email.com","password":"Qwerty_123"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status registering user: %d", rec.Code)
+	}
+	var u model.User
+	if err := json.NewDecoder(rec.Body).Decode(&u); err != nil {
+		t.Fatalf("unexpected error decoding user: %v", err)
+	}
+	if err := service.CreateAdmin(context.TODO(), userRepo, u.ID); err != nil {
+		t.Fatalf("unexpected error creating admin: %v", err)
+	}
+	path := fmt.Sprintf("/api/v1/users/%d", u.ID)
+
+	responses := map[string]*httptest.ResponseRecorder{
+		"register":      rec,
+		"get by id":     send(http.MethodGet, path, u.APIKey, "", ""),
+		"find by id":    send(http.MethodGet, fmt.Sprintf("/api/v1/user?uid=%d", u.ID), u.APIKey, "", ""),
+		"find by email": send(http.MethodGet, "/api/v1/user?email=john@email.com", u.APIKey, "", ""),
+		"list":          send(http.MethodGet, "/api/v1/users", u.APIKey, "", ""),
+		"patch":         send(http.MethodPatch, path, u.APIKey, model.MergePatchType, `{"firstName":"johnny"}`),
+	}
+	for name, rec := range responses {
+		t.Run(name+" leaves out the password", func(t *testing.T) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "password")
+			assert.NotContains(t, rec.Body.String(), "$2a$")
+		})
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+	srv := newServer()
+
+	u, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "test",
+		LastName:  "user",
+		Email:     "admin@test.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering user: %v", err)
+	}
+	if err := service.CreateAdmin(ctx, userRepo, u.ID); err != nil {
+		t.Fatalf("unexpected error creating admin: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/missions", strings.NewReader(`{"dateOfMission": "16-07-1969"}`))
+	req.Header.Set("X-API-KEY", u.APIKey)
+	req.Header.Set("X-Request-ID", "problem-test")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	var p struct {
+		Title     string
+		Status    int
+		Code      string
+		RequestID string
+		Errors    []model.FieldError
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("unexpected error decoding problem: %v", err)
+	}
+	assert.Equal(t, "Bad Request", p.Title)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "validation_failed", p.Code)
+	assert.Equal(t, "problem-test", p.RequestID)
+	assert.Equal(t, []model.FieldError{
+		{Field: "name", Code: model.CodeRequired, Message: "name must not be empty"},
+		{Field: "dateOfMission", Code: model.CodeInvalidFormat, Message: "dateOfMission must be a valid date yyyy-mm-dd"},
+	}, p.Errors)
+}
+
+func TestMalformedRequests(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+	srv := newServer()
+
+	u, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "test",
+		LastName:  "user",
+		Email:     "admin@test.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering user: %v", err)
+	}
+	if err := service.CreateAdmin(ctx, userRepo, u.ID); err != nil {
+		t.Fatalf("unexpected error creating admin: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		field  string
+	}{
+		{"a path param that is not an integer", http.MethodGet, "/api/v1/astronauts/abc", "", "astronautID"},
+		{"the second path param that is not an integer", http.MethodPut, "/api/v1/missions/1/crew/abc", "", "astronautID"},
+		{"malformed json", http.MethodPost, "/api/v1/astronauts", `{"firstName": `, "body"},
+		{"an empty body", http.MethodPost, "/api/v1/missions", "", "body"},
+		{"a field of the wrong type", http.MethodPost, "/api/v1/missions", `{"name": "apollo 11", "successful": "yes"}`, "successful"},
+		{"a query param that is not an integer", http.MethodGet, "/api/v1/user?uid=abc", "", "uid"},
+		{"a malformed query string", http.MethodGet, "/api/v1/user?email=%zz", "", "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("X-API-KEY", u.APIKey)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var p handlers.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+				t.Fatalf("unexpected error decoding problem: %v", err)
+			}
+			assert.Equal(t, "validation_failed", p.Code)
+			if assert.Len(t, p.Errors, 1) {
+				assert.Equal(t, tt.field, p.Errors[0].Field)
+			}
+		})
+	}
+
+	t.Run("a patch too large to read", func(t *testing.T) {
+		body := `{"name": "` + strings.Repeat("a", 64<<10) + `"}`
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/missions/1", strings.NewReader(body))
+		req.Header.Set("X-API-KEY", u.APIKey)
+		req.Header.Set("Content-Type", model.MergePatchType)
+		req.Header.Set("If-Match", "*")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	srv := newServer()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status getting the OpenAPI document: %d", rec.Code)
+	}
+
+	var spec struct {
+		OpenAPI string
+		Paths   map[string]map[string]any
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("unexpected error decoding the OpenAPI document: %v", err)
+	}
+	assert.Equal(t, "3.1.0", spec.OpenAPI)
+
+	for _, pattern := range transport.RoutePatterns() {
+		method, path, _ := strings.Cut(pattern, " ")
+		_, ok := spec.Paths[path][strings.ToLower(method)]
+		assert.True(t, ok, "route %s is missing from the OpenAPI document", pattern)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+	assert.NotContains(t, rec.Body.String(), "https://")
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/docs/swagger-ui-bundle.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/javascript")
+	assert.Contains(t, rec.Body.String(), "SwaggerUIBundle")
+}
+
+func TestMetrics(t *testing.T) {
+	srv := newServer()
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v1/astronauts/1", "/api/v1/astronauts/2", "/no/such/route"} {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	scrape := func(srv http.Handler, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("requires the metrics token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, scrape(srv, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, scrape(srv, "wrong-token").Code)
+	})
+
+	t.Run("is not served without a metrics token", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, scrape(transport.NewServer(
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+			service.NewRateLimiter(quotaRepo, model.RateLimits{Default: model.RateLimit{Rate: 1000, Burst: 1000}}, nil),
+			testTokens,
+			userRepo,
+			sessionRepo,
+			apiKeyRepo,
+			astroRepo,
+			astroLogRepo,
+			militaryRepo,
+			academicRepo,
+			missionRepo,
+			datasetRepo,
+			searchRepo,
+			auditRepo,
+			service.NewReadiness(healthRepo, latestMigration),
+			"",
+		), "").Code)
+	})
+
+	rec := scrape(srv, testMetricsToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE http_requests_total counter")
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/v1/openapi.json",status="200"}`)
+	assert.Contains(t, body, `http_request_duration_seconds_bucket{method="GET",route="/api/v1/openapi.json",status="200",le="+Inf"}`)
+	assert.Contains(t, body, `route="/api/v1/astronauts/{astronautID}"`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.NotContains(t, body, `route="/api/v1/astronauts/1"`)
+	assert.Contains(t, body, `repository="APIKeyRepository"}`)
+}
+
+func TestTracing(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+	srv := newServer()
+
+	u, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "test",
+		LastName:  "user",
+		Email:     "trace@test.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering user: %v", err)
+	}
+	a, err := service.AddAstronaut(ctx, &model.Astronaut{
+		FirstName:  "neil",
+		LastName:   "armstrong",
+		Gender:     "M",
+		BirthDate:  "1930-08-05",
+		BirthPlace: "Wapakoneta, OH",
+	}, astroRepo)
+	if err != nil {
+		t.Fatalf("Unexpected error adding astronaut: %v", err)
+	}
+
+	// record installs a tracer provider recording the spans of a subtest
+	record := func() *tracetest.SpanRecorder {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		return recorder
+	}
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	get := func(traceparent string) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/astronauts/%d", a.ID), nil)
+		req.Header.Set("X-API-KEY", u.APIKey)
+		if traceparent != "" {
+			req.Header.Set("Traceparent", traceparent)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status getting astronaut: %d", rec.Code)
+		}
+	}
+
+	byName := func(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, s := range recorder.Ended() {
+			spans[s.Name()] = s
+		}
+		return spans
+	}
+
+	t.Run("nests repository spans in service spans in the server span", func(t *testing.T) {
+		recorder := record()
+		get("")
+
+		spans := byName(recorder)
+		server, ok := spans["GET /api/v1/astronauts/{astronautID}"]
+		if !assert.True(t, ok, "missing server span") {
+			return
+		}
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+		assert.False(t, server.Parent().IsValid())
+		assert.Contains(t, server.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+
+		svc, ok := spans["service.GetAstronaut"]
+		if !assert.True(t, ok, "missing service span") {
+			return
+		}
+		assert.Equal(t, server.SpanContext().SpanID(), svc.Parent().SpanID())
+
+		repo, ok := spans["AstronautRepository.FindAstronautByID"]
+		if !assert.True(t, ok, "missing repository span") {
+			return
+		}
+		assert.Equal(t, trace.SpanKindClient, repo.SpanKind())
+		assert.Equal(t, svc.SpanContext().SpanID(), repo.Parent().SpanID())
+		assert.Equal(t, server.SpanContext().TraceID(), repo.SpanContext().TraceID())
+		assert.Contains(t, repo.Attributes(), attribute.String("db.system", "postgresql"))
+
+		var statement string
+		for _, attr := range repo.Attributes() {
+			if attr.Key == "db.statement" {
+				statement = attr.Value.AsString()
+			}
+		}
+		assert.Contains(t, statement, "FROM astronaut WHERE id = $1")
+	})
+
+	t.Run("continues the trace of a traceparent header", func(t *testing.T) {
+		recorder := record()
+		get("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		server := byName(recorder)["GET /api/v1/astronauts/{astronautID}"]
+		if !assert.NotNil(t, server, "missing server span") {
+			return
+		}
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+		assert.True(t, server.Parent().IsRemote())
+	})
+
+	t.Run("records nothing for an unsampled trace", func(t *testing.T) {
+		recorder := record()
+		get("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+		assert.Empty(t, recorder.Ended())
+	})
+
+	t.Run("starts a new trace for an invalid traceparent", func(t *testing.T) {
+		recorder := record()
+		get("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+
+		server := byName(recorder)["GET /api/v1/astronauts/{astronautID}"]
+		if !assert.NotNil(t, server, "missing server span") {
+			return
+		}
+		assert.True(t, server.SpanContext().TraceID().IsValid())
+		assert.False(t, server.Parent().IsValid())
+	})
+}
+
+func TestErrorLogging(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	var logs bytes.Buffer
+	srv := newServerWith(slog.New(slog.NewJSONHandler(&logs, nil)), service.NewReadiness(healthRepo, latestMigration))
+
+	u, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "test",
+		LastName:  "user",
+		Email:     "logs@test.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering user: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/astronauts/999999", nil)
+	req.Header.Set("X-API-KEY", u.APIKey)
+	req.Header.Set("X-Request-ID", "log-test")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	var failed, handled map[string]any
+	dec := json.NewDecoder(&logs)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("unexpected error decoding log line: %v", err)
+		}
+		switch line["msg"] {
+		case "request failed":
+			failed = line
+		case "incoming request":
+			handled = line
+		}
+	}
+
+	if assert.NotNil(t, failed, "the error was not logged") {
+		assert.Equal(t, "log-test", failed["request_id"])
+		assert.Equal(t, float64(http.StatusNotFound), failed["status"])
+		assert.Equal(t, "not_found", failed["code"])
+		assert.NotEmpty(t, failed["exception"])
+	}
+	if assert.NotNil(t, handled, "the request was not logged") {
+		assert.Equal(t, "log-test", handled["request_id"])
+	}
+}
+
+func TestProbes(t *testing.T) {
+	readiness := service.NewReadiness(healthRepo, latestMigration)
+	srv := newServerWith(slog.New(slog.NewTextHandler(io.Discard, nil)), readiness)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	t.Run("reports the process is up", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get("/healthz").Code)
+	})
+
+	t.Run("reports the build", func(t *testing.T) {
+		rec := get("/version")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var info model.BuildInfo
+		if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+			t.Fatalf("unexpected error decoding build info: %v", err)
+		}
+		assert.True(t, strings.HasPrefix(info.GoVersion, "go"))
+	})
+
+	t.Run("is ready with the database migrated", func(t *testing.T) {
+		rec := get("/readyz")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var report model.ReadinessReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("unexpected error decoding readiness report: %v", err)
+		}
+		assert.Equal(t, model.StatusReady, report.Status)
+		assert.Equal(t, model.CheckOK, report.Checks["migrations"])
+	})
+
+	t.Run("is not ready when the schema is behind the embedded migrations", func(t *testing.T) {
+		report := service.NewReadiness(healthRepo, latestMigration+1).Check(context.TODO())
+		assert.Equal(t, model.StatusNotReady, report.Status)
+		assert.Equal(t, model.CheckOK, report.Checks["database"])
+		assert.NotEqual(t, model.CheckOK, report.Checks["migrations"])
+	})
+
+	t.Run("is not ready while draining", func(t *testing.T) {
+		readiness.Drain()
+
+		rec := get("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, http.StatusOK, get("/healthz").Code)
+	})
+}
+
+// failingDatasetRepo streams records then fails, as a dropped database connection would part way through
+// an export.
+type failingDatasetRepo struct {
+	model.DatasetRepository
+	records int
+}
+
+func (r failingDatasetRepo) StreamAstronautData(ctx context.Context, fn func(*model.AstronautData) error) error {
+	for i := 0; i < r.records; i++ {
+		if err := fn(&model.AstronautData{Name: fmt.Sprintf("Astronaut %d", i)}); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection reset by peer")
+}
+
+func TestExportFailure(t *testing.T) {
+	t.Run("writes a problem when nothing has been sent", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handlers.HandleExportAstronautData(failingDatasetRepo{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=ndjson", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("aborts the transfer once records have been sent", func(t *testing.T) {
+		srv := httptest.NewServer(handlers.HandleExportAstronautData(failingDatasetRepo{records: 1}))
+		defer srv.Close()
+
+		// depending on how much reached the connection the client fails reading either the headers or the body
+		res, err := http.Get(srv.URL + "?format=ndjson")
+		if err == nil {
+			_, err = io.ReadAll(res.Body)
+			res.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	err := clearTables(dbConn)
+	if err != nil {
+		t.Fatalf("Error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	a := &model.Astronaut{
+		FirstName:  "neil",
+		LastName:   "armstrong",
+		Gender:     "M",
+		BirthDate:  "1930-08-05",
+		BirthPlace: "wapakoneta,oh",
+	}
+	if _, err := service.AddAstronaut(ctx, a, astroRepo); err != nil {
+		t.Fatalf("Unexpected error adding Astronaut: %v", err)
+	}
+
+	m := &model.Mission{Name: "Apollo 11", DateOfMission: "1969-07-16", Successful: true}
+	if _, err := service.AddMission(ctx, missionRepo, m); err != nil {
+		t.Fatalf("Unexpected error adding mission: %v", err)
+	}
+
+	if _, err := service.AddAlmaMater(ctx, academicRepo, &model.AlmaMater{School: "Université de Montréal"}); err != nil {
+		t.Fatalf("Unexpected error adding alma mater: %v", err)
+	}
+
+	t.Run("returns an error for an empty query", func(t *testing.T) {
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: " ", Limit: model.DefaultLimit})
+		if err == nil {
+			t.Errorf("Expected error for empty query")
+		}
+		assert.Nil(t, results)
+	})
+
+	t.Run("finds an astronaut despite a typo", func(t *testing.T) {
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: "armstong", Limit: model.DefaultLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error searching: %v", err)
+		}
+		if assert.NotEmpty(t, results) {
+			assert.Equal(t, model.SearchAstronaut, results[0].Type)
+			assert.Equal(t, a.ID, results[0].ID)
+		}
+	})
+
+	t.Run("ignores diacritics", func(t *testing.T) {
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: "montreal", Limit: model.DefaultLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error searching: %v", err)
+		}
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, model.SearchAlmaMater, results[0].Type)
+		}
+	})
+
+	t.Run("restricts results by type", func(t *testing.T) {
+		q := model.SearchQuery{Text: "apollo", Types: []string{model.SearchAstronaut}, Limit: model.DefaultLimit}
+		results, err := service.Search(ctx, searchRepo, q)
+		if err != nil {
+			t.Fatalf("Unexpected error searching: %v", err)
+		}
+		assert.Empty(t, results)
+	})
+
+	t.Run("highlights the matched terms", func(t *testing.T) {
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: "apollo", Limit: model.DefaultLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error searching: %v", err)
+		}
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, "<b>Apollo</b> 11", results[0].Snippet)
+		}
+	})
+
+	t.Run("escapes markup in the matched text", func(t *testing.T) {
+		m := &model.Mission{Name: `Gemini <img src=x onerror="alert(1)"> & friends`, DateOfMission: "1965-03-23", Successful: true}
+		if _, err := service.AddMission(ctx, missionRepo, m); err != nil {
+			t.Fatalf("Unexpected error adding mission: %v", err)
+		}
+
+		results, err := service.Search(ctx, searchRepo, model.SearchQuery{Text: "gemini", Types: []string{model.SearchMission}, Limit: model.DefaultLimit})
+		if err != nil {
+			t.Fatalf("Unexpected error searching: %v", err)
+		}
+		if assert.Len(t, results, 1) {
+			assert.Equal(t, `<b>Gemini</b> &lt;img src=x onerror=&quot;alert(1)&quot;&gt; &amp; friends`, results[0].Snippet)
+		}
+	})
+}
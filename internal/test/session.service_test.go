@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogin(t *testing.T) {
+	if err := clearTables(dbConn); err != nil {
+		t.Fatalf("error clearing tables: %v", err)
+	}
+	ctx := context.TODO()
+
+	usr, err := service.RegisterUser(ctx, userRepo, &model.User{
+		FirstName: "jane",
+		LastName:  "doe",
+		Email:     "jane@test.com",
+		Password:  plainPwd,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error registering user: %v", err)
+	}
+
+	t.Run("returns an error for a wrong password", func(t *testing.T) {
+		pair, err := service.Login(ctx, userRepo, sessionRepo, testTokens, &model.Credentials{Email: usr.Email, Password: "Wrong!pass1"})
+		if err == nil {
+			t.Errorf("expected error for wrong password")
+		}
+		assert.Nil(t, pair)
+	})
+
+	t.Run("returns an error for an unknown email", func(t *testing.T) {
+		_, err := service.Login(ctx, userRepo, sessionRepo, testTokens, &model.Credentials{Email: "nobody@test.com", Password: plainPwd})
+		if err == nil {
+			t.Errorf("expected error for unknown email")
+		}
+	})
+
+	pair, err := service.Login(ctx, userRepo, sessionRepo, testTokens, &model.Credentials{Email: usr.Email, Password: plainPwd})
+	if err != nil {
+		t.Fatalf("unexpected error logging in: %v", err)
+	}
+
+	t.Run("issues an access token for the user", func(t *testing.T) {
+		u, err := service.VerifyAccessToken(ctx, userRepo, sessionRepo, testTokens, pair.AccessToken)
+		if err != nil {
+			t.Fatalf("unexpected error verifying access token: %v", err)
+		}
+		assert.Equal(t, usr.ID, u.ID)
+		assert.Equal(t, "Bearer", pair.TokenType)
+	})
+
+	t.Run("rejects a token signed with another secret", func(t *testing.T) {
+		other := service.NewTokens("other-secret", 0, 0)
+		if _, err := service.VerifyAccessToken(ctx, userRepo, sessionRepo, other, pair.AccessToken); err == nil {
+			t.Errorf("expected error for token signed with another secret")
+		}
+	})
+
+	t.Run("authenticates requests with a bearer token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/missions", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		rec := httptest.NewRecorder()
+		newServer().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("rotates the refresh token", func(t *testing.T) {
+		next, err := service.RefreshSession(ctx, sessionRepo, testTokens, pair.RefreshToken)
+		if err != nil {
+			t.Fatalf("unexpected error refreshing session: %v", err)
+		}
+		assert.NotEqual(t, pair.RefreshToken, next.RefreshToken)
+
+		if _, err := service.RefreshSession(ctx, sessionRepo, testTokens, pair.RefreshToken); err == nil {
+			t.Errorf("expected error reusing a rotated refresh token")
+		}
+		pair = next
+	})
+
+	t.Run("revokes the session on logout", func(t *testing.T) {
+		if err := service.Logout(ctx, sessionRepo, pair.RefreshToken); err != nil {
+			t.Fatalf("unexpected error logging out: %v", err)
+		}
+
+		if _, err := service.VerifyAccessToken(ctx, userRepo, sessionRepo, testTokens, pair.AccessToken); err == nil {
+			t.Errorf("expected error for access token of a revoked session")
+		}
+		if _, err := service.RefreshSession(ctx, sessionRepo, testTokens, pair.RefreshToken); err == nil {
+			t.Errorf("expected error refreshing a revoked session")
+		}
+	})
+}
@@ -2,6 +2,8 @@ package test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
@@ -43,7 +45,7 @@ func TestRegisterUser(t *testing.T) {
 
 		assert.Equal(t, usr.Email, u.Email)
 		assert.NotEqual(t, plainPwd, u.Password)
-		assert.NotEmpty(t, u.APIKey)
+		assert.True(t, strings.HasPrefix(u.APIKey, model.APIKeyPrefix))
 	})
 
 	t.Run("return an error if email is already in use", func(t *testing.T) {
@@ -154,12 +156,14 @@ func TestGetUsers(t *testing.T) {
 
 	ctx := context.TODO()
 
+	opts := model.QueryOptions{Limit: model.DefaultLimit}
+
 	t.Run("return nil if no users are found", func(t *testing.T) {
-		usr, err := service.GetUsers(ctx, userRepo)
+		page, err := service.GetUsers(ctx, userRepo, opts, model.UserFilter{})
 		if err != nil {
 			t.Errorf("unexpected error getting users: %v", err)
 		}
-		assert.Nil(t, usr)
+		assert.Nil(t, page.Data)
 	})
 
 	t.Run("returns a list of users", func(t *testing.T) {
@@ -184,11 +188,32 @@ func TestGetUsers(t *testing.T) {
 			}
 		}
 
-		usrs, err := service.GetUsers(ctx, userRepo)
+		page, err := service.GetUsers(ctx, userRepo, opts, model.UserFilter{})
 		if err != nil {
 			t.Errorf("unexpected error getting users: %v", err)
 		}
-		assert.Len(t, usrs, 2)
+		assert.Len(t, page.Data, 2)
+		assert.Equal(t, 2, page.Total)
+	})
+
+	t.Run("reports an invalid date range on the created params", func(t *testing.T) {
+		_, err := service.GetUsers(ctx, userRepo, opts, model.UserFilter{CreatedFrom: "2023-01-01", CreatedTo: "01-01-2022"})
+
+		var apiErr *model.APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected an APIError, got %v", err)
+		}
+		assert.Equal(t, model.Problems{
+			{Field: "createdTo", Code: model.CodeInvalidFormat, Message: "createdTo must be a valid date yyyy-mm-dd"},
+		}, apiErr.Problems)
+
+		_, err = service.GetUsers(ctx, userRepo, opts, model.UserFilter{CreatedFrom: "2023-01-01", CreatedTo: "2022-01-01"})
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected an APIError, got %v", err)
+		}
+		assert.Equal(t, model.Problems{
+			{Field: "createdFrom", Code: model.CodeInvalidRange, Message: "createdFrom must not be after createdTo"},
+		}, apiErr.Problems)
 	})
 }
 
@@ -220,7 +245,7 @@ func TestUpdateUser(t *testing.T) {
 			Password:  "",
 		}
 
-		err := service.UpdateUser(ctx, userRepo, usr)
+		err := service.UpdateUser(ctx, userRepo, usr, model.AnyVersion)
 		if err == nil {
 			t.Error("expected an error updating user with invalid input")
 		}
@@ -235,7 +260,7 @@ func TestUpdateUser(t *testing.T) {
 			Password:  u.Password,
 		}
 
-		err := service.UpdateUser(ctx, userRepo, usr)
+		err := service.UpdateUser(ctx, userRepo, usr, model.AnyVersion)
 		if err != nil {
 			t.Errorf("unexpected error updating user: %v", err)
 		}
@@ -260,7 +285,7 @@ func TestDeleteUser(t *testing.T) {
 	t.Run("returns an error when trying to delete unknown user", func(t *testing.T) {
 		uid := 998
 
-		if err := service.DeleteUser(ctx, userRepo, uid); err == nil {
+		if err := service.DeleteUser(ctx, userRepo, uid, model.AnyVersion); err == nil {
 			t.Error("expected an error deleting unknown user")
 		}
 	})
@@ -278,7 +303,7 @@ func TestDeleteUser(t *testing.T) {
 			t.Fatalf("unexpected error registering a user: %v", err)
 		}
 
-		err = service.DeleteUser(ctx, userRepo, u.ID)
+		err = service.DeleteUser(ctx, userRepo, u.ID, model.AnyVersion)
 		if err != nil {
 			t.Errorf("unexpected error deleting a user: %v", err)
 		}
@@ -363,7 +388,7 @@ func TestGenerateNewUserAPIKey(t *testing.T) {
 	t.Run("returns error generating new APIKey for unknown user", func(t *testing.T) {
 		uid := 78
 
-		key, err := service.GenerateNewAPIKey(ctx, userRepo, uid)
+		key, err := service.GenerateNewAPIKey(ctx, apiKeyRepo, uid)
 		if err == nil {
 			t.Error("expected error generating new APIKey for unknown user")
 		}
@@ -371,19 +396,19 @@ func TestGenerateNewUserAPIKey(t *testing.T) {
 	})
 
 	t.Run("generates new APIKey", func(t *testing.T) {
-		uid := u.ID
-		u, err := service.SearchUserID(ctx, userRepo, uid)
-		if err != nil {
-			t.Fatalf("unexpected error geting user: %v", err)
-		}
-
-		key, err := service.GenerateNewAPIKey(ctx, userRepo, uid)
+		key, err := service.GenerateNewAPIKey(ctx, apiKeyRepo, u.ID)
 		if err != nil {
 			t.Errorf("unexpected error generating new APIKey: %v", err)
 		}
 
 		assert.NotEqual(t, u.APIKey, key)
 	})
+
+	t.Run("keeps the old APIKey working during the grace window", func(t *testing.T) {
+		if _, _, err := service.SearchAPIKey(ctx, userRepo, apiKeyRepo, u.APIKey); err != nil {
+			t.Errorf("unexpected error searching rotated APIKey: %v", err)
+		}
+	})
 }
 
 func TestCreateAdmin(t *testing.T) {
@@ -522,15 +547,28 @@ func TestSearchUserAPIKey(t *testing.T) {
 	t.Run("returns error trying to find a user with invalid API key", func(t *testing.T) {
 		key := "67e505ea-51ee-402a-9a79-cd0c7afede4b"
 
-		usr, err := service.SearchAPIKey(ctx, userRepo, key)
+		usr, _, err := service.SearchAPIKey(ctx, userRepo, apiKeyRepo, key)
 		if err == nil {
 			t.Error("expected error trying to find a user with invalid API key")
 		}
 		assert.Nil(t, usr)
 	})
 
+	t.Run("returns error for a key with a known prefix but wrong secret", func(t *testing.T) {
+		key := u.APIKey[:len(u.APIKey)-4] + "AAAA"
+		if key == u.APIKey {
+			key = u.APIKey[:len(u.APIKey)-4] + "BBBB"
+		}
+
+		usr, _, err := service.SearchAPIKey(ctx, userRepo, apiKeyRepo, key)
+		if err == nil {
+			t.Error("expected error trying to find a user with a tampered API key")
+		}
+		assert.Nil(t, usr)
+	})
+
 	t.Run("returns a user", func(t *testing.T) {
-		usr, err := service.SearchAPIKey(ctx, userRepo, u.APIKey)
+		usr, _, err := service.SearchAPIKey(ctx, userRepo, apiKeyRepo, u.APIKey)
 		if err != nil {
 			t.Errorf("unexpected error searching user API key: %v", err)
 		}
@@ -60,6 +60,17 @@ func clearTables(db *sql.DB) error {
 		return err
 	}
 
+	stmt = `DELETE FROM astronaut_alma_mater;
+	DELETE FROM astronaut_undergrad_major;
+	DELETE FROM astronaut_grad_major;
+	DELETE FROM alma_mater;
+	DELETE FROM major;`
+
+	_, err = tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+
 	stmt = `DELETE FROM astronaut_log;`
 
 	_, err = tx.ExecContext(ctx, stmt)
@@ -91,10 +102,21 @@ func clearTables(db *sql.DB) error {
 		return err
 	}
 
-	stmt = `DELETE FROM "api_key";
+	stmt = `DELETE FROM session;
+  DELETE FROM "api_key";
   DELETE FROM "user";
   ALTER SEQUENCE user_id_seq RESTART WITH 1;`
 
+	_, err = tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	stmt = `DELETE FROM api_quota;
+  DELETE FROM audit_event;
+  DELETE FROM astronaut_version;
+  DELETE FROM astronaut_log_version;
+  DELETE FROM military_history_version;`
+
 	_, err = tx.ExecContext(ctx, stmt)
 	if err != nil {
 		return err
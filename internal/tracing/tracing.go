@@ -0,0 +1,56 @@
+// Package tracing records spans with OpenTelemetry. Spans are carried in the context.Context passed
+// down from the handlers through the services and repositories, and are started with the global
+// tracer provider, which records nothing until otel.SetTracerProvider is called with one from
+// NewProvider.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer the spans of the api are recorded with.
+const instrumentationName = "github.com/LaQuannT/astronaut-api"
+
+// Start starts a span as a child of the span in ctx, or of the remote span extracted into it, and
+// returns a context carrying the new span. Spans are internal unless opts give another kind.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// NewProvider returns a tracer provider batching spans to exporter, one of otlp or stdout, or nil when
+// exporter is none. endpoint is the base url of the OpenTelemetry collector receiving OTLP/HTTP, such
+// as http://localhost:4318. Spans are dropped rather than blocking requests when the exporter falls
+// behind, and a span whose parent was not sampled is not recorded.
+func NewProvider(ctx context.Context, exporter, endpoint, serviceName string) (*sdktrace.TracerProvider, error) {
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	case "stdout":
+		exp, err = stdouttrace.New()
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res)), nil
+}
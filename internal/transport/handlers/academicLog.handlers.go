@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleGetAcademicLog(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		al, err := service.GetAstronautAcademicLog(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, al)
+	}
+}
+
+func HandleAddAstronautAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, almaMaterID, err := educationPathValues(r, "almaMaterID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.AddAstronautAlmaMater(r.Context(), repository, astronautID, almaMaterID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Alma Mater has been added to astronaut"})
+	}
+}
+
+func HandleRemoveAstronautAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, almaMaterID, err := educationPathValues(r, "almaMaterID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteAstronautAlmaMater(r.Context(), repository, astronautID, almaMaterID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Alma Mater has been removed from astronaut"})
+	}
+}
+
+func HandleAddAstronautUndergradMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, majorID, err := educationPathValues(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.AddAstronautUndergradMajor(r.Context(), repository, astronautID, majorID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Undergrad Major has been added to astronaut"})
+	}
+}
+
+func HandleRemoveAstronautUndergradMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, majorID, err := educationPathValues(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteUnderGradMajor(r.Context(), repository, astronautID, majorID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Undergrad Major has been removed from astronaut"})
+	}
+}
+
+func HandleAddAstronautGradMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, majorID, err := educationPathValues(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.AddAstronautGradMajor(r.Context(), repository, astronautID, majorID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Grad Major has been added to astronaut"})
+	}
+}
+
+func HandleRemoveAstronautGradMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		astronautID, majorID, err := educationPathValues(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteGradeMajor(r.Context(), repository, astronautID, majorID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Grad Major has been removed from astronaut"})
+	}
+}
+
+func educationPathValues(r *http.Request, name string) (astronautID, id int, err error) {
+	astronautID, err = pathInt(r, "astronautID")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	id, err = pathInt(r, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return astronautID, id, nil
+}
+
+func HandleCreateMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m := new(model.Major)
+
+		if err := decodeJSON(r, m, "major data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		m, err := service.AddMajor(r.Context(), repository, m)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func HandleGetMajors(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ms, err := service.GetMajors(r.Context(), repository)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ms)
+	}
+}
+
+func HandleGetMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		m, err := service.GetMajorByID(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, m.Version)
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func HandleUpdateMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		m := new(model.Major)
+
+		if err := decodeJSON(r, m, "major data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		m.ID = id
+
+		if err := service.UpdateMajor(r.Context(), repository, m, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, m.Version)
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func HandleDeleteMajor(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "majorID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteMajor(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Major has been deleted"})
+	}
+}
+
+func HandleCreateAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a := new(model.AlmaMater)
+
+		if err := decodeJSON(r, a, "alma mater data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		a, err := service.AddAlmaMater(r.Context(), repository, a)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, a)
+	}
+}
+
+func HandleGetAlmaMaters(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		as, err := service.GetAlmaMaters(r.Context(), repository)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, as)
+	}
+}
+
+func HandleGetAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "almaMaterID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		a, err := service.GetAlmaMaterByID(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, a.Version)
+		writeJSON(w, http.StatusOK, a)
+	}
+}
+
+func HandleUpdateAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "almaMaterID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		a := new(model.AlmaMater)
+
+		if err := decodeJSON(r, a, "alma mater data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		a.ID = id
+
+		if err := service.UpdateAlaMater(r.Context(), repository, a, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, a.Version)
+		writeJSON(w, http.StatusOK, a)
+	}
+}
+
+func HandleDeleteAlmaMater(repository model.AcademicLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "almaMaterID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteAlmaMater(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Alma Mater has been deleted"})
+	}
+}
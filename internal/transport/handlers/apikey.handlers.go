@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleCreateAPIKey(repository model.APIKeyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		k := new(model.APIKey)
+		if err := decodeJSON(r, k, "API key data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		k, err = service.CreateAPIKey(r.Context(), repository, uid, k)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, k)
+	}
+}
+
+func HandleGetAPIKeys(repository model.APIKeyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		keys, err := service.GetAPIKeys(r.Context(), repository, uid)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// HandleRotateAPIKey issues a replacement for a key. The grace query param, a duration such as 1h,
+// sets how long the old key keeps working and defaults to 24h.
+func HandleRotateAPIKey(repository model.APIKeyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, kid, err := apiKeyPathValues(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		grace := service.DefaultRotationGrace
+		if g := r.URL.Query().Get("grace"); g != "" {
+			grace, err = time.ParseDuration(g)
+			if err != nil {
+				WriteError(w, r, invalidParam("grace", err))
+				return
+			}
+		}
+
+		k, err := service.RotateAPIKey(r.Context(), repository, uid, kid, grace)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, k)
+	}
+}
+
+func HandleRevokeAPIKey(repository model.APIKeyRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, kid, err := apiKeyPathValues(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.RevokeAPIKey(r.Context(), repository, uid, kid); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "API key has been revoked"})
+	}
+}
+
+func apiKeyPathValues(r *http.Request) (userID, keyID int, err error) {
+	userID, err = pathInt(r, "userID")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	keyID, err = pathInt(r, "keyID")
+	if err != nil {
+		return 0, 0, err
+	}
+	return userID, keyID, nil
+}
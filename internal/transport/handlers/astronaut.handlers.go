@@ -1,12 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
-	"errors"
-	"io"
 	"net/http"
-	"net/url"
-	"strconv"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 	"github.com/LaQuannT/astronaut-api/internal/service"
@@ -16,14 +11,14 @@ func HandleCreateAstronaut(repository model.AstronautRepository) http.HandlerFun
 	return func(w http.ResponseWriter, r *http.Request) {
 		a := new(model.Astronaut)
 
-		if err := json.NewDecoder(r.Body).Decode(a); err != nil {
-			WriteError(w, err)
+		if err := decodeJSON(r, a, "astronaut data"); err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
 		a, err := service.AddAstronaut(r.Context(), a, repository)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -31,94 +26,178 @@ func HandleCreateAstronaut(repository model.AstronautRepository) http.HandlerFun
 	}
 }
 
-func HandleGetAstronaut(repository model.AstronautRepository) http.HandlerFunc {
+func HandleGetAstronaut(userRepository model.UserRepository, repository model.AstronautRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		aid := r.PathValue("astronautID")
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(aid)
+		asOf, err := queryTime(r.URL.Query(), "asOf")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		a, err := service.GetAstronaut(r.Context(), repository, id)
+		var a *model.Astronaut
+		if asOf != nil {
+			a, err = service.GetAstronautAsOf(r.Context(), userRepository, repository, id, *asOf)
+		} else {
+			a, err = service.GetAstronaut(r.Context(), repository, id)
+		}
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
+		if asOf == nil {
+			setETag(w, a.Version)
+		}
 		writeJSON(w, http.StatusOK, a)
 	}
 }
 
+func HandleGetAstronautHistory(
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		h, err := service.GetAstronautHistory(r.Context(), userRepository, astronautRepository, astronautLogRepository, militaryLogRepository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, h)
+	}
+}
+
 func HandleGetAstronauts(repository model.AstronautRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// add limit and offset
+		q := r.URL.Query()
 
-		as, err := service.GetAstronauts(r.Context(), repository)
+		opts, err := parseQueryOptions(q)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, as)
+		f := model.AstronautFilter{
+			Gender: q.Get("gender"),
+			Status: q.Get("status"),
+		}
+		if f.BirthYearFrom, err = queryInt(q, "birthYearFrom"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if f.BirthYearTo, err = queryInt(q, "birthYearTo"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		page, err := service.GetAstronauts(r.Context(), repository, opts, f)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
 	}
 }
 
 func HandleUpdateAstronaut(repository model.AstronautRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		aid := r.PathValue("astroanutID")
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(aid)
+		version, err := ifMatch(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		a, err := service.GetAstronaut(r.Context(), repository, id)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		err = json.NewDecoder(r.Body).Decode(a)
-		switch {
-		case errors.Is(err, io.EOF):
-			WriteError(w, &model.APIError{
-				Code:      http.StatusBadRequest,
-				Message:   "astronaut data not provided in request body",
-				Exception: err.Error(),
-			})
+		if err := decodeJSON(r, a, "astronaut data"); err != nil {
+			WriteError(w, r, err)
 			return
+		}
 
-		case err != nil:
-			WriteError(w, err)
+		err = service.UpdateAstronaut(r.Context(), a, repository, version)
+		if err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
-		err = service.UpdateAstronaut(r.Context(), a, repository)
+		setETag(w, a.Version)
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut has been updated"})
+	}
+}
+
+func HandlePatchAstronaut(repository model.AstronautRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut has been updated"})
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		patch, err := readPatch(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		a, err := service.PatchAstronaut(r.Context(), repository, id, patch, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, a.Version)
+		writeJSON(w, http.StatusOK, a)
 	}
 }
 
 func HandleDeleteAstronaut(repository model.AstronautRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		aid := r.PathValue("astroanutID")
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(aid)
+		version, err := ifMatch(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		err = service.DeleteAstronaut(r.Context(), repository, id)
+		err = service.DeleteAstronaut(r.Context(), repository, id, version)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -126,11 +205,29 @@ func HandleDeleteAstronaut(repository model.AstronautRepository) http.HandlerFun
 	}
 }
 
+func HandleRestoreAstronaut(repository model.AstronautRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		err = service.RestoreAstronaut(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut has been restored"})
+	}
+}
+
 func HandleSearchAstronautName(repository model.AstronautRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		params, err := url.ParseQuery(r.URL.RawQuery)
+		params, err := parseQuery(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -138,7 +235,7 @@ func HandleSearchAstronautName(repository model.AstronautRepository) http.Handle
 
 		a, err := service.SearchAstronautByName(r.Context(), repository, name)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleCreateAstronautLog(repository model.AstronautLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		al := new(model.AstronautLog)
+
+		if err := decodeJSON(r, al, "astronaut log data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		al.AstronautID = id
+
+		al, err = service.AddAstronautLog(r.Context(), repository, al)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, al)
+	}
+}
+
+func HandleGetAstronautLog(
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	repository model.AstronautLogRepository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		asOf, err := queryTime(r.URL.Query(), "asOf")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		var al *model.AstronautLog
+		if asOf != nil {
+			al, err = service.GetAstronautLogAsOf(r.Context(), userRepository, astronautRepository, repository, id, *asOf)
+		} else {
+			al, err = service.GetAstronautLog(r.Context(), repository, id)
+		}
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if asOf == nil {
+			setETag(w, al.Version)
+		}
+		writeJSON(w, http.StatusOK, al)
+	}
+}
+
+// HandleUpdateAstronautLog responds with the updated log so clients see the resulting career status.
+func HandleUpdateAstronautLog(repository model.AstronautLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		al, err := service.GetAstronautLog(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := decodeJSON(r, al, "astronaut log data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		al.AstronautID = id
+
+		err = service.UpdateAstronautLog(r.Context(), repository, al, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, al.Version)
+		writeJSON(w, http.StatusOK, al)
+	}
+}
+
+func HandlePatchAstronautLog(repository model.AstronautLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		patch, err := readPatch(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		al, err := service.PatchAstronautLog(r.Context(), repository, id, patch, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, al.Version)
+		writeJSON(w, http.StatusOK, al)
+	}
+}
+
+func HandleDeleteAstronautLog(repository model.AstronautLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteAstronautLog(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut log has been deleted"})
+	}
+}
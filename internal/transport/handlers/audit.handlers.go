@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleGetAuditEvents(repository model.AuditRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		opts, err := parseQueryOptions(q)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		actor, err := queryInt(q, "actor")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		f := model.AuditFilter{
+			EntityType: q.Get("entity"),
+			EntityID:   q.Get("entityId"),
+			ActorID:    actor,
+			From:       q.Get("from"),
+			To:         q.Get("to"),
+		}
+
+		page, err := service.GetAuditEvents(r.Context(), repository, opts, f)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+// maxImportSize caps the size of an uploaded dataset, the full NASA dataset is well under 1MB.
+const maxImportSize = 1 << 20
+
+// HandleImportAstronautData accepts the NASA astronaut dataset as a raw text/csv body, a multipart
+// form "file" upload or a JSON array of astronaut data.
+func HandleImportAstronautData(repository model.DatasetRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extendDeadlines(w, service.DatasetTimeout)
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+		var data []*model.AstronautData
+		var err error
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		switch mediaType {
+		case "application/json":
+			if err := decodeJSON(r, &data, "astronaut data"); err != nil {
+				WriteError(w, r, err)
+				return
+			}
+
+		case "multipart/form-data":
+			var file io.ReadCloser
+			file, _, err = r.FormFile("file")
+			if err != nil {
+				WriteError(w, r, &model.APIError{
+					Code:      http.StatusBadRequest,
+					Message:   "dataset file not provided in form field 'file'",
+					Exception: err.Error(),
+				})
+				return
+			}
+			defer file.Close()
+
+			data, err = service.DecodeAstronautCSV(file)
+
+		default:
+			data, err = service.DecodeAstronautCSV(r.Body)
+		}
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		report, err := service.ImportAstronautData(r.Context(), repository, data)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// HandleExportAstronautData streams the full astronaut catalogue in the format given by the format
+// query param (csv, json or ndjson). Once the first record is written the status can no longer change,
+// so a failure part way through is logged and the connection aborted, leaving the client with a
+// truncated transfer rather than a complete looking file.
+func HandleExportAstronautData(repository model.DatasetRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extendDeadlines(w, service.DatasetTimeout)
+		format := r.URL.Query().Get("format")
+
+		out := &countingWriter{w: w}
+		enc, contentType, err := service.NewDatasetEncoder(out, format)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		ext := format
+		if ext == "" {
+			ext = "csv"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "astronauts."+ext))
+
+		if err := service.ExportAstronautData(r.Context(), repository, enc); err != nil {
+			if out.n == 0 {
+				w.Header().Del("Content-Disposition")
+				WriteError(w, r, err)
+				return
+			}
+
+			model.LoggerFromContext(r.Context()).Error("export aborted",
+				slog.Int64("bytes_written", out.n),
+				slog.String("exception", err.Error()),
+			)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// countingWriter counts the bytes written through it, telling whether a response has started.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// extendDeadlines lifts the server's read and write timeouts for a request expected to outlast them.
+func extendDeadlines(w http.ResponseWriter, d time.Duration) {
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(d)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
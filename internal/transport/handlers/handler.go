@@ -3,7 +3,16 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 )
@@ -14,16 +23,286 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
-func WriteError(w http.ResponseWriter, err error) {
-	var apiErr *model.APIError
+// Problem is an RFC 9457 problem details response body.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Code      string         `json:"code"`
+	Detail    string         `json:"detail,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+	Errors    model.Problems `json:"errors,omitempty"`
+}
+
+// WriteError responds with the problem details of err, errors other than an APIError are reported as
+// an internal error without their details. Every error is logged with its exception and the request
+// id returned to the client, so a reported problem can be traced to its cause.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := &model.APIError{Code: http.StatusInternalServerError, Message: "unable to process request"}
+	if !errors.As(err, &apiErr) && err != nil {
+		apiErr.Exception = err.Error()
+	}
+
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(apiErr.Code),
+		Status:    apiErr.Code,
+		Code:      problemCode(apiErr),
+		Detail:    apiErr.Message,
+		RequestID: model.RequestIDFromContext(r.Context()),
+		Errors:    apiErr.Problems,
+	}
+
+	level := slog.LevelInfo
+	if p.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	model.LoggerFromContext(r.Context()).LogAttrs(r.Context(), level, "request failed",
+		slog.Int("status", p.Status),
+		slog.String("code", p.Code),
+		slog.String("message", apiErr.Message),
+		slog.String("exception", apiErr.Exception),
+	)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// problemCode is the machine readable code of an error, derived from its status so it stays stable.
+func problemCode(apiErr *model.APIError) string {
+	if len(apiErr.Problems) > 0 {
+		return "validation_failed"
+	}
+	return strings.ToLower(strings.ReplaceAll(http.StatusText(apiErr.Code), " ", "_"))
+}
+
+// parseQueryOptions reads the limit, cursor and sort params shared by every list endpoint.
+func parseQueryOptions(q url.Values) (model.QueryOptions, error) {
+	opts := model.QueryOptions{
+		Limit:  model.DefaultLimit,
+		Cursor: q.Get("cursor"),
+		Sort:   model.ParseSort(q.Get("sort")),
+	}
+
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			return opts, invalidParam("limit", err)
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
 
-	e := struct{ Error string }{Error: ""}
+// queryInt reads an optional integer query param, returning 0 when it is not set.
+func queryInt(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, invalidParam(name, err)
+	}
+	return n, nil
+}
+
+// queryBool reads an optional boolean query param, returning nil when it is not set.
+func queryBool(q url.Values, name string) (*bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, invalidParam(name, err)
+	}
+	return &b, nil
+}
 
-	if errors.As(err, &apiErr) {
-		e.Error = apiErr.Message
-		writeJSON(w, apiErr.Code, e)
-		return
+// queryTime reads an optional date yyyy-mm-dd, taken as the start of the day in UTC, or RFC 3339
+// timestamp query param, returning nil when it is not set.
+func queryTime(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	layout := time.RFC3339
+	if len(v) == len(time.DateOnly) {
+		layout = time.DateOnly
+	}
+
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return nil, invalidParam(name, err)
+	}
+	return &t, nil
+}
+
+func invalidParam(name string, err error) error {
+	apiErr := &model.APIError{
+		Code:      http.StatusBadRequest,
+		Message:   fmt.Sprintf("invalid %s query param", name),
+		Exception: err.Error(),
+	}
+	apiErr.Problems.Add(name, model.CodeInvalidFormat, fmt.Sprintf("%s is not a valid value", name))
+	return apiErr
+}
+
+// parseQuery parses the query string of r, rejecting malformed pairs rather than dropping them like
+// r.URL.Query() does.
+func parseQuery(r *http.Request) (url.Values, error) {
+	q, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		apiErr := &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "invalid query string",
+			Exception: err.Error(),
+		}
+		apiErr.Problems.Add("query", model.CodeInvalidFormat, "query string must be url encoded")
+		return q, apiErr
+	}
+	return q, nil
+}
+
+// pathInt reads an integer path param such as {astronautID}.
+func pathInt(r *http.Request, name string) (int, error) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		apiErr := &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   fmt.Sprintf("invalid %s path param", name),
+			Exception: err.Error(),
+		}
+		apiErr.Problems.Add(name, model.CodeInvalidFormat, fmt.Sprintf("%s must be an integer", name))
+		return 0, apiErr
+	}
+	return n, nil
+}
+
+// decodeJSON decodes the JSON body of r into v, what names the body in the error returned when it is
+// missing or malformed.
+func decodeJSON(r *http.Request, v any, what string) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var (
+		maxBytesErr *http.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	apiErr := &model.APIError{
+		Code:      http.StatusBadRequest,
+		Message:   fmt.Sprintf("invalid %s", what),
+		Exception: err.Error(),
+	}
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return bodyTooLarge(maxBytesErr)
+	case errors.Is(err, io.EOF):
+		apiErr.Message = fmt.Sprintf("%s not provided in request body", what)
+		apiErr.Problems.Add("body", model.CodeRequired, "request body must not be empty")
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		apiErr.Problems.Add(field, model.CodeInvalidValue, fmt.Sprintf("%s must be a %s", field, jsonType(typeErr.Type.Kind())))
+	default:
+		apiErr.Problems.Add("body", model.CodeInvalidFormat, "request body must be valid JSON")
+	}
+	return apiErr
+}
+
+// bodyTooLarge reports a request body cut off by http.MaxBytesReader.
+func bodyTooLarge(err *http.MaxBytesError) error {
+	return &model.APIError{
+		Code:      http.StatusRequestEntityTooLarge,
+		Message:   fmt.Sprintf("request body must not be larger than %d bytes", err.Limit),
+		Exception: err.Error(),
+	}
+}
+
+// jsonType names the JSON type a Go value of kind k is decoded from.
+func jsonType(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "number"
+	}
+}
+
+// setETag sends a row's version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatch reads the version a write is conditioned on, "*" matching any version.
+func ifMatch(r *http.Request) (int, error) {
+	v := r.Header.Get("If-Match")
+	if v == "" {
+		return 0, &model.APIError{
+			Code:    http.StatusPreconditionRequired,
+			Message: "If-Match header required",
+		}
+	}
+	if v == "*" {
+		return model.AnyVersion, nil
+	}
+
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(v, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, &model.APIError{
+			Code:    http.StatusPreconditionFailed,
+			Message: "If-Match does not match the current ETag",
+		}
+	}
+	return version, nil
+}
+
+// maxPatchSize caps the size of a PATCH body, a patch of any single record is far smaller.
+const maxPatchSize = 64 << 10
+
+// readPatch reads a PATCH body, which has to be a JSON merge patch or a JSON patch.
+func readPatch(w http.ResponseWriter, r *http.Request) (model.Patch, error) {
+	var patch model.Patch
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != model.MergePatchType && mediaType != model.JSONPatchType) {
+		w.Header().Set("Accept-Patch", model.MergePatchType+", "+model.JSONPatchType)
+		return patch, &model.APIError{
+			Code:      http.StatusUnsupportedMediaType,
+			Message:   fmt.Sprintf("patch must be %s or %s", model.MergePatchType, model.JSONPatchType),
+			Exception: fmt.Sprintf("unsupported patch type %q", r.Header.Get("Content-Type")),
+		}
+	}
+	patch.ContentType = mediaType
+
+	if patch.Body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize)); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return patch, bodyTooLarge(maxBytesErr)
+		}
+		return patch, err
+	}
+	if len(patch.Body) == 0 {
+		return patch, &model.APIError{
+			Code:      http.StatusBadRequest,
+			Message:   "patch not provided in request body",
+			Exception: io.EOF.Error(),
+		}
 	}
-	e.Error = "unable to process request"
-	writeJSON(w, http.StatusInternalServerError, e)
+	return patch, nil
 }
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+// HandleHealthz reports that the process is up, it checks nothing else so a slow database never gets
+// the server restarted.
+func HandleHealthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": model.CheckOK})
+	}
+}
+
+// HandleReadyz reports whether the server can take traffic, responding 503 with the failing checks
+// when it can't.
+func HandleReadyz(readiness *service.Readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := readiness.Check(r.Context())
+
+		status := http.StatusOK
+		if report.Status != model.StatusReady {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+func HandleVersion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, service.GetBuildInfo())
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleCreateMilitaryLog(repository model.MilitaryLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		ml := new(model.MilitaryLog)
+
+		if err := decodeJSON(r, ml, "military log data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		ml.AstronautID = id
+
+		ml, err = service.AddMilitaryLog(r.Context(), repository, ml)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ml)
+	}
+}
+
+func HandleGetMilitaryLog(
+	userRepository model.UserRepository,
+	astronautRepository model.AstronautRepository,
+	repository model.MilitaryLogRepository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		asOf, err := queryTime(r.URL.Query(), "asOf")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		var ml *model.MilitaryLog
+		if asOf != nil {
+			ml, err = service.GetMilitaryLogAsOf(r.Context(), userRepository, astronautRepository, repository, id, *asOf)
+		} else {
+			ml, err = service.GetMilitaryLog(r.Context(), repository, id)
+		}
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if asOf == nil {
+			setETag(w, ml.Version)
+		}
+		writeJSON(w, http.StatusOK, ml)
+	}
+}
+
+func HandleUpdateMilitaryLog(repository model.MilitaryLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		ml, err := service.GetMilitaryLog(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := decodeJSON(r, ml, "military log data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		ml.AstronautID = id
+
+		err = service.UpdateMilitaryLog(r.Context(), repository, ml, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, ml.Version)
+		writeJSON(w, http.StatusOK, ml)
+	}
+}
+
+func HandlePatchMilitaryLog(repository model.MilitaryLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		patch, err := readPatch(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		ml, err := service.PatchMilitaryLog(r.Context(), repository, id, patch, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, ml.Version)
+		writeJSON(w, http.StatusOK, ml)
+	}
+}
+
+func HandleDeleteMilitaryLog(repository model.MilitaryLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteMilitaryLog(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Military log has been deleted"})
+	}
+}
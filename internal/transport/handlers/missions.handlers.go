@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
 	"github.com/LaQuannT/astronaut-api/internal/service"
@@ -13,14 +11,14 @@ func HandleCreateMission(repository model.MissionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		m := new(model.Mission)
 
-		if err := json.NewDecoder(r.Body).Decode(m); err != nil {
-			WriteError(w, err)
+		if err := decodeJSON(r, m, "mission data"); err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
 		m, err := service.AddMission(r.Context(), repository, m)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -30,34 +28,264 @@ func HandleCreateMission(repository model.MissionRepository) http.HandlerFunc {
 
 func HandleGetMission(repository model.MissionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		mid := r.PathValue("missionID")
-
-		id, err := strconv.Atoi(mid)
+		id, err := pathInt(r, "missionID")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		m, err := service.GetMission(r.Context(), repository, id)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
+		setETag(w, m.Version)
 		writeJSON(w, http.StatusOK, m)
 	}
 }
 
 func HandleGetMissions(repository model.MissionRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// add limit and offset
+		q := r.URL.Query()
+
+		opts, err := parseQueryOptions(q)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		f := model.MissionFilter{
+			From: q.Get("from"),
+			To:   q.Get("to"),
+		}
+		if f.Successful, err = queryBool(q, "successful"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		page, err := service.GetMissions(r.Context(), repository, opts, f)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+func HandleSearchMissionName(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := parseQuery(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		name := params.Get("name")
+
+		ms, err := service.SearchMissionName(r.Context(), repository, name)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ms)
+	}
+}
+
+func HandleUpdateMission(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "missionID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		m, err := service.GetMission(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := decodeJSON(r, m, "mission data"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		m.ID = id
+
+		err = service.UpdateMission(r.Context(), repository, m, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, m.Version)
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Mission has been updated"})
+	}
+}
+
+func HandlePatchMission(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "missionID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		patch, err := readPatch(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		m, err := service.PatchMission(r.Context(), repository, id, patch, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, m.Version)
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func HandleDeleteMission(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "missionID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		ms, err := service.GetMissions(r.Context(), repository)
+		version, err := ifMatch(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.DeleteMission(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Mission has been deleted"})
+	}
+}
+
+func HandleRestoreMission(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "missionID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.RestoreMission(r.Context(), repository, id); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Mission has been restored"})
+	}
+}
+
+func HandleGetAstronautMissions(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		ms, err := service.GetMissionsByAstronaut(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
 		writeJSON(w, http.StatusOK, ms)
 	}
 }
+
+func HandleGetMissionCrew(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "missionID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if _, err := service.GetMission(r.Context(), repository, id); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		crew, err := service.GetMissionCrew(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, crew)
+	}
+}
+
+func HandleAddMissionCrew(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		missionID, astronautID, err := crewPathValues(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.RegisterAstronautToMission(r.Context(), repository, astronautID, missionID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut has been added to mission crew"})
+	}
+}
+
+func HandleRemoveMissionCrew(repository model.MissionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		missionID, astronautID, err := crewPathValues(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.RemoveAstronautFromMission(r.Context(), repository, astronautID, missionID); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "Astronaut has been removed from mission crew"})
+	}
+}
+
+func crewPathValues(r *http.Request) (missionID, astronautID int, err error) {
+	missionID, err = pathInt(r, "missionID")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	astronautID, err = pathInt(r, "astronautID")
+	if err != nil {
+		return 0, 0, err
+	}
+	return missionID, astronautID, nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleGetAstronautProfile(
+	astronautRepository model.AstronautRepository,
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+	academicLogRepository model.AcademicLogRepository,
+	missionRepository model.MissionRepository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "astronautID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		p, err := service.GetAstronautProfile(
+			r.Context(),
+			astronautRepository,
+			astronautLogRepository,
+			militaryLogRepository,
+			academicLogRepository,
+			missionRepository,
+			id,
+		)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+// HandleSearch searches astronauts, missions, alma maters and majors. The optional type param
+// restricts results to a comma separated list of result types.
+func HandleSearch(repository model.SearchRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		sq := model.SearchQuery{
+			Text:  q.Get("q"),
+			Limit: model.DefaultLimit,
+		}
+		if t := q.Get("type"); t != "" {
+			sq.Types = strings.Split(t, ",")
+		}
+		if q.Has("limit") {
+			limit, err := queryInt(q, "limit")
+			if err != nil {
+				WriteError(w, r, err)
+				return
+			}
+			sq.Limit = limit
+		}
+
+		results, err := service.Search(r.Context(), repository, sq)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, results)
+	}
+}
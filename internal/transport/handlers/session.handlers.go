@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+)
+
+func HandleLogin(
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	tokens *service.Tokens,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := new(model.Credentials)
+
+		if err := decodeJSON(r, c, "credentials"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		pair, err := service.Login(r.Context(), userRepository, sessionRepository, tokens, c)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, pair)
+	}
+}
+
+func HandleRefreshToken(sessionRepository model.SessionRepository, tokens *service.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(model.RefreshRequest)
+
+		if err := decodeJSON(r, req, "refresh token"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		pair, err := service.RefreshSession(r.Context(), sessionRepository, tokens, req.RefreshToken)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, pair)
+	}
+}
+
+func HandleLogout(sessionRepository model.SessionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := new(model.RefreshRequest)
+
+		if err := decodeJSON(r, req, "refresh token"); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.Logout(r.Context(), sessionRepository, req.RefreshToken); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "User has been logged out"})
+	}
+}
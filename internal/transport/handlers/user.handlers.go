@@ -1,12 +1,7 @@
 package handlers
 
 import (
-	"encoding/json"
-	"errors"
-	"io"
 	"net/http"
-	"net/url"
-	"strconv"
 
 	"github.com/LaQuannT/astronaut-api/internal/service"
 
@@ -17,14 +12,14 @@ func HandleRegisterUser(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		usr := new(model.User)
 
-		if err := json.NewDecoder(r.Body).Decode(usr); err != nil {
-			WriteError(w, err)
+		if err := decodeJSON(r, usr, "user data"); err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
 		usr, err := service.RegisterUser(r.Context(), repository, usr)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -34,9 +29,10 @@ func HandleRegisterUser(repository model.UserRepository) http.HandlerFunc {
 
 func HandleGetUser(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		params, err := url.ParseQuery(r.URL.RawQuery)
+		params, err := parseQuery(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
+			return
 		}
 
 		email := params.Get("email")
@@ -48,27 +44,29 @@ func HandleGetUser(repository model.UserRepository) http.HandlerFunc {
 		case email != "":
 			usr, err = service.SearchUserEmail(r.Context(), repository, email)
 			if err != nil {
-				WriteError(w, err)
+				WriteError(w, r, err)
 				return
 			}
+			setETag(w, usr.Version)
 			writeJSON(w, http.StatusOK, usr)
 			return
 
 		case userID != "":
-			uid, err := strconv.Atoi(userID)
+			uid, err := queryInt(params, "uid")
 			if err != nil {
-				WriteError(w, err)
+				WriteError(w, r, err)
 				return
 			}
 			usr, err = service.SearchUserID(r.Context(), repository, uid)
 			if err != nil {
-				WriteError(w, err)
+				WriteError(w, r, err)
 				return
 			}
+			setETag(w, usr.Version)
 			writeJSON(w, http.StatusOK, usr)
 			return
 		default:
-			WriteError(w, &model.APIError{
+			WriteError(w, r, &model.APIError{
 				Code:      http.StatusBadRequest,
 				Message:   "email or uid param not supplied",
 				Exception: "email or user ID params not supplied",
@@ -77,73 +75,132 @@ func HandleGetUser(repository model.UserRepository) http.HandlerFunc {
 	}
 }
 
+func HandleGetUserByID(repository model.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		usr, err := service.SearchUserID(r.Context(), repository, id)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		setETag(w, usr.Version)
+		writeJSON(w, http.StatusOK, usr)
+	}
+}
+
 func HandleGetUsers(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// add limit and offset
+		q := r.URL.Query()
 
-		urs, err := service.GetUsers(r.Context(), repository)
+		opts, err := parseQueryOptions(q)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, urs)
+		f := model.UserFilter{
+			CreatedFrom: q.Get("createdFrom"),
+			CreatedTo:   q.Get("createdTo"),
+		}
+
+		page, err := service.GetUsers(r.Context(), repository, opts, f)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, page)
 	}
 }
 
 func HandleUpdateUser(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		uid := r.PathValue("userID")
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(uid)
+		version, err := ifMatch(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		usr, err := service.SearchUserID(r.Context(), repository, id)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		err = json.NewDecoder(r.Body).Decode(usr)
-		switch {
-		case errors.Is(err, io.EOF):
-			WriteError(w, &model.APIError{
-				Code:      http.StatusBadRequest,
-				Message:   "user data not provided in request body",
-				Exception: err.Error(),
-			})
+		if err := decodeJSON(r, usr, "user data"); err != nil {
+			WriteError(w, r, err)
 			return
+		}
 
-		case err != nil:
-			WriteError(w, err)
+		err = service.UpdateUser(r.Context(), repository, usr, version)
+		if err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
-		err = service.UpdateUser(r.Context(), repository, usr)
+		setETag(w, usr.Version)
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "User has been updated"})
+	}
+}
+
+func HandlePatchUser(repository model.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "userID")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		writeJSON(w, http.StatusOK, map[string]string{"Message": "User has been updated"})
+		version, err := ifMatch(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		patch, err := readPatch(w, r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		usr, err := service.PatchUser(r.Context(), repository, id, patch, version)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		setETag(w, usr.Version)
+		writeJSON(w, http.StatusOK, usr)
 	}
 }
 
 func HandleDeleteUser(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		uid := r.PathValue("userID")
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(uid)
+		version, err := ifMatch(r)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
-		if err := service.DeleteUser(r.Context(), repository, id); err != nil {
-			WriteError(w, err)
+		if err := service.DeleteUser(r.Context(), repository, id, version); err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
@@ -151,26 +208,41 @@ func HandleDeleteUser(repository model.UserRepository) http.HandlerFunc {
 	}
 }
 
-func HandlePasswordReset(repository model.UserRepository) http.HandlerFunc {
+func HandleRestoreUser(repository model.UserRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		uid := r.PathValue("userID")
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
 
-		id, err := strconv.Atoi(uid)
+		if err := service.RestoreUser(r.Context(), repository, id); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "User has been restored"})
+	}
+}
+
+func HandlePasswordReset(repository model.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "userID")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		usr := new(model.User)
 
-		if err := json.NewDecoder(r.Body).Decode(usr); err != nil {
-			WriteError(w, err)
+		if err := decodeJSON(r, usr, "user data"); err != nil {
+			WriteError(w, r, err)
 			return
 		}
 
 		err = service.ResetPassword(r.Context(), repository, usr.Password, id)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
@@ -178,22 +250,54 @@ func HandlePasswordReset(repository model.UserRepository) http.HandlerFunc {
 	}
 }
 
-func HandleAPIKeyReset(repository model.UserRepository) http.HandlerFunc {
+func HandleAPIKeyReset(repository model.APIKeyRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		uid := r.PathValue("userID")
-
-		id, err := strconv.Atoi(uid)
+		id, err := pathInt(r, "userID")
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		key, err := service.GenerateNewAPIKey(r.Context(), repository, id)
 		if err != nil {
-			WriteError(w, err)
+			WriteError(w, r, err)
 			return
 		}
 
 		writeJSON(w, http.StatusOK, map[string]string{"apiKey": key})
 	}
 }
+
+func HandleCreateAdmin(repository model.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.CreateAdmin(r.Context(), repository, id); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "User has been given admin privileges"})
+	}
+}
+
+func HandleRemoveAdmin(repository model.UserRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathInt(r, "userID")
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err := service.RemoveAdmin(r.Context(), repository, id); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"Message": "User admin privileges have been revoked"})
+	}
+}
@@ -14,18 +14,18 @@ func AdminOnly(repository model.UserRepository) func(http.Handler) http.Handler
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			usr, err := getRequestUser(r.Context())
 			if err != nil {
-				handlers.WriteError(w, err)
+				handlers.WriteError(w, r, err)
 				return
 			}
 
 			isAdmin, err := service.CheckAdminPermission(r.Context(), repository, usr.ID)
 			if err != nil {
-				handlers.WriteError(w, err)
+				handlers.WriteError(w, r, err)
 				return
 			}
 
 			if !isAdmin {
-				handlers.WriteError(w, &model.APIError{
+				handlers.WriteError(w, r, &model.APIError{
 					Code:    http.StatusForbidden,
 					Message: "User unauthorised",
 				})
@@ -38,7 +38,7 @@ func AdminOnly(repository model.UserRepository) func(http.Handler) http.Handler
 }
 
 func getRequestUser(ctx context.Context) (*model.User, error) {
-	reqUsr, ok := ctx.Value(requestUsr).(*model.User)
+	reqUsr, ok := model.UserFromContext(ctx)
 	if !ok {
 		return nil, &model.APIError{
 			Code:      http.StatusInternalServerError,
@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+)
+
+type authErrorKey struct{}
+
+// Authenticate identifies the request user from either an "Authorization: Bearer" access token or
+// the X-API-KEY header. It runs ahead of rate limiting so only verified keys are limited by key, a
+// request it cannot identify carries on without a user and is rejected by RequireUser on routes that
+// need one. Requests skip returns true for are not identified.
+func Authenticate(
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	apiKeyRepository model.APIKeyRepository,
+	tokens *service.Tokens,
+	skip func(*http.Request) bool,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var usr *model.User
+			var err error
+
+			if auth := r.Header.Get("Authorization"); auth != "" {
+				usr, err = verifyBearerToken(r, auth, userRepository, sessionRepository, tokens)
+			} else {
+				var k *model.APIKey
+				usr, k, err = verifyAPIKey(r, userRepository, apiKeyRepository)
+				if err == nil {
+					r = r.WithContext(model.ContextWithAPIKey(r.Context(), k))
+				}
+			}
+			if err != nil {
+				r = r.WithContext(context.WithValue(r.Context(), authErrorKey{}, err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.WithContext(model.ContextWithUser(r.Context(), usr))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireUser rejects requests Authenticate could not identify a user for, giving the reason it failed.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := model.UserFromContext(r.Context()); !ok {
+			err, ok := r.Context().Value(authErrorKey{}).(error)
+			if !ok {
+				err = &model.APIError{
+					Code:      http.StatusUnauthorized,
+					Message:   "User unathorised to make request",
+					Exception: "request was not authenticated",
+				}
+			}
+			handlers.WriteError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func verifyBearerToken(
+	r *http.Request,
+	auth string,
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	tokens *service.Tokens,
+) (*model.User, error) {
+	scheme, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "User unathorised to make request",
+			Exception: "user supplied malformed authorization header",
+		}
+	}
+
+	return service.VerifyAccessToken(r.Context(), userRepository, sessionRepository, tokens, token)
+}
+
+func verifyAPIKey(
+	r *http.Request,
+	userRepository model.UserRepository,
+	apiKeyRepository model.APIKeyRepository,
+) (*model.User, *model.APIKey, error) {
+	key := r.Header.Get("X-API-KEY")
+	if key == "" {
+		return nil, nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "User unathorised to make request",
+			Exception: "user did not supply api key or bearer token in request header",
+		}
+	}
+
+	if _, ok := model.APIKeyLookupPrefix(key); !ok {
+		return nil, nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "User unathorised to make request",
+			Exception: "user supplied invalid api key format",
+		}
+	}
+
+	usr, k, err := service.SearchAPIKey(r.Context(), userRepository, apiKeyRepository, key)
+	var apiErr *model.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound:
+		return nil, nil, &model.APIError{
+			Code:      http.StatusUnauthorized,
+			Message:   "User unathorised to make request",
+			Exception: "user supplied unknown, expired or revoked api key",
+		}
+	case err != nil:
+		return nil, nil, err
+	}
+	return usr, k, nil
+}
@@ -4,8 +4,9 @@ import "net/http"
 
 const (
 	allowedOrigin  = "*"
-	allowedMethods = "GET, POST, PUT, DELETE, OPTIONS"
-	allowedHeaders = "Origin, Content-Type, Accept"
+	allowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	allowedHeaders = "Origin, Content-Type, Accept, Authorization, X-API-KEY, If-Match"
+	exposedHeaders = "ETag, Accept-Patch, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-RateLimit-Quota-Limit, X-RateLimit-Quota-Remaining, Retry-After"
 )
 
 func EnableCors(next http.Handler) http.Handler {
@@ -13,6 +14,7 @@ func EnableCors(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
 		w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+		w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
 		next.ServeHTTP(w, r)
 	})
 }
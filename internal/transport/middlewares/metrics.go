@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.With(metrics.Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Requests handled, by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+	requestDuration = promauto.With(metrics.Default).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle a request, by route pattern, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// Metrics counts and times every request, labelled by the pattern patternOf matches it to rather than
+// its path so that ids in the path don't create a series each. Requests matching no route are
+// labelled "unmatched".
+func Metrics(patternOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrappedRW := wrapResponseWriter(w)
+			next.ServeHTTP(wrappedRW, r)
+
+			route := patternOf(r)
+			if _, path, ok := strings.Cut(route, " "); ok {
+				route = path
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+
+			status := wrappedRW.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			labels := []string{route, r.Method, strconv.Itoa(status)}
+			requestsTotal.WithLabelValues(labels...).Inc()
+			requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		}
+		return http.HandlerFunc(fn)
+	}
+}
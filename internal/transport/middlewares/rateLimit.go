@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+)
+
+// RateLimit limits requests per API key, or per client IP for requests without one, using the limit
+// of the scope scopeOf returns for the request. Requests exempt returns true for are not limited.
+func RateLimit(limiter *service.RateLimiter, scopeOf func(*http.Request) string, exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			status, err := limiter.Allow(r.Context(), rateLimitSubject(r), scopeOf(r))
+			if err != nil {
+				handlers.WriteError(w, r, err)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(status.Reset)))
+			if status.QuotaLimit > 0 {
+				h.Set("X-RateLimit-Quota-Limit", strconv.Itoa(status.QuotaLimit))
+				h.Set("X-RateLimit-Quota-Remaining", strconv.Itoa(status.QuotaRemaining))
+			}
+
+			if !status.Allowed {
+				retry := ceilSeconds(status.RetryAfter)
+				h.Set("Retry-After", strconv.Itoa(retry))
+				handlers.WriteError(w, r, &model.APIError{
+					Code:      http.StatusTooManyRequests,
+					Message:   fmt.Sprintf("rate limit exceeded, retry after %d seconds", retry),
+					Exception: "rate limit exceeded",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitSubject keys a request by the prefix of the API key Authenticate verified it with, or by the
+// user an access token was verified for so that users behind a shared IP don't share a quota. Other
+// requests, including those with an unknown key or token, are keyed by the client IP so that made up
+// credentials do not each get a bucket and quota of their own.
+func rateLimitSubject(r *http.Request) string {
+	if k, ok := model.APIKeyFromContext(r.Context()); ok {
+		return "key:" + k.Prefix
+	}
+	if usr, ok := model.UserFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(usr.ID)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLen bounds a client supplied X-Request-ID, longer ids are replaced.
+const maxRequestIDLen = 128
+
+// RequestID identifies each request by the client's X-Request-ID header, or a generated uuid when none
+// is sent, and echoes it in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > maxRequestIDLen {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		r = r.WithContext(model.ContextWithRequestID(r.Context(), id))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type responseWriter struct {
@@ -21,19 +24,34 @@ func (rw *responseWriter) Status() int {
 	return rw.status
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController, which handlers use to extend their
+// deadlines.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// RequestLogger gives each request a logger carrying its request id, and trace ids when it is traced,
+// for handlers, services and repositories to log with, then logs the request once it is handled.
 func RequestLogger(log *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+
+			logger := log.With(slog.String("request_id", model.RequestIDFromContext(r.Context())))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				logger = logger.With(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+			}
+			r = r.WithContext(model.ContextWithLogger(r.Context(), logger))
+
 			wrappedRW := wrapResponseWriter(w)
 			next.ServeHTTP(wrappedRW, r)
 
-			log.Info(
+			logger.Info(
 				"incoming request",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.EscapedPath()),
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+)
+
+// RequireScope rejects requests authenticated with an API key that does not hold scope. Requests
+// authenticated with an access token act with the user's full permissions.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k, ok := model.APIKeyFromContext(r.Context()); ok && !k.HasScope(scope) {
+				handlers.WriteError(w, r, &model.APIError{
+					Code:      http.StatusForbidden,
+					Message:   fmt.Sprintf("API key missing required scope %s", scope),
+					Exception: "api key missing required scope",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
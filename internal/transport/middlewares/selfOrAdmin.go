@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+)
+
+// SelfOrAdmin only allows the request through when the {userID} path value belongs to the request user,
+// or the request user is an admin.
+func SelfOrAdmin(repository model.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usr, err := getRequestUser(r.Context())
+			if err != nil {
+				handlers.WriteError(w, r, err)
+				return
+			}
+
+			if uid, err := strconv.Atoi(r.PathValue("userID")); err == nil && uid == usr.ID {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			isAdmin, err := service.CheckAdminPermission(r.Context(), repository, usr.ID)
+			if err != nil {
+				handlers.WriteError(w, r, err)
+				return
+			}
+
+			if !isAdmin {
+				handlers.WriteError(w, r, &model.APIError{
+					Code:    http.StatusForbidden,
+					Message: "User unauthorised",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts a server span for every request, continuing the trace of the client's traceparent
+// header when it sends one. The span is named after the pattern patternOf matches the request to
+// and is carried in the request context down to the services and repositories.
+func Trace(patternOf func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			name := patternOf(r)
+			_, route, _ := strings.Cut(name, " ")
+			if name == "" {
+				name = r.Method
+			}
+
+			ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", r.URL.EscapedPath()),
+					attribute.String("user_agent.original", r.UserAgent()),
+					attribute.String("client.address", r.RemoteAddr),
+					attribute.String("http.request_id", model.RequestIDFromContext(ctx)),
+				),
+			)
+			defer span.End()
+
+			wrappedRW := wrapResponseWriter(w)
+			next.ServeHTTP(wrappedRW, r.WithContext(ctx))
+
+			status := wrappedRW.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		}
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,495 @@
+package transport
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI is swagger-ui-dist 5.18.2, served along with the docs page so it loads nothing from a CDN.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var swaggerUI embed.FS
+
+type (
+	// operation documents a route, the pattern, access and scope are taken from the route itself.
+	operation struct {
+		summary string
+		query   []param
+		// request is a value of the request body type, or a content of each accepted media type.
+		request any
+		// response is a value of the 200 response body type, or a content of each media type.
+		response any
+		// versioned operations send the row version as an ETag, writes to them require If-Match.
+		versioned bool
+	}
+
+	param struct {
+		name        string
+		kind        string
+		description string
+	}
+
+	// content maps media types to a value of their body type or a schema.
+	content map[string]any
+
+	// schema is a JSON Schema written out by hand rather than generated from a type.
+	schema map[string]any
+
+	message struct {
+		Message string `json:"Message"`
+	}
+
+	apiKeyReset struct {
+		APIKey string `json:"apiKey"`
+	}
+)
+
+var (
+	listParams = []param{
+		{"limit", "integer", fmt.Sprintf("page size, between 1 and %d", model.MaxLimit)},
+		{"cursor", "string", "the next cursor of the previous page"},
+		{"sort", "string", "comma separated fields, prefixed with - to sort descending"},
+	}
+
+	asOfParam = param{"asOf", "string", "a date yyyy-mm-dd or RFC 3339 timestamp to read the record as it was then"}
+
+	jsonPatch = schema{
+		"type": "array",
+		"items": schema{
+			"type":     "object",
+			"required": []string{"op", "path"},
+			"properties": schema{
+				"op":    schema{"enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+				"path":  schema{"type": "string"},
+				"from":  schema{"type": "string"},
+				"value": schema{},
+			},
+		},
+	}
+)
+
+// patch accepts a merge patch of v or a JSON patch.
+func patch(v any) content {
+	return content{model.MergePatchType: v, model.JSONPatchType: jsonPatch}
+}
+
+func list(params ...param) []param {
+	return append(append([]param{}, listParams...), params...)
+}
+
+// operations documents every route by its pattern, a route without an entry is left out of the spec.
+var operations = map[string]operation{
+	"POST /api/v1/register":                         {summary: "Register a user", request: model.User{}, response: model.User{}},
+	"POST /api/v1/login":                            {summary: "Log in with an email and password", request: model.Credentials{}, response: model.TokenPair{}},
+	"POST /api/v1/token/refresh":                    {summary: "Exchange a refresh token for a new token pair", request: model.RefreshRequest{}, response: model.TokenPair{}},
+	"POST /api/v1/logout":                           {summary: "Revoke the session of a refresh token", request: model.RefreshRequest{}, response: message{}},
+	"GET /api/v1/user":                              {summary: "Find a user by email or id", query: []param{{"email", "string", ""}, {"uid", "integer", ""}}, response: model.User{}, versioned: true},
+	"GET /api/v1/users":                             {summary: "List users", query: list(param{"createdFrom", "string", "yyyy-mm-dd"}, param{"createdTo", "string", "yyyy-mm-dd"}), response: model.Page[*model.User]{}},
+	"GET /api/v1/users/{userID}":                    {summary: "Get a user", response: model.User{}, versioned: true},
+	"PUT /api/v1/users/{userID}":                    {summary: "Update a user", request: model.User{}, response: message{}, versioned: true},
+	"PATCH /api/v1/users/{userID}":                  {summary: "Patch a user", request: patch(model.User{}), response: model.User{}, versioned: true},
+	"DELETE /api/v1/users/{userID}":                 {summary: "Delete a user", response: message{}, versioned: true},
+	"POST /api/v1/users/{userID}/restore":           {summary: "Restore a deleted user", response: message{}},
+	"PUT /api/v1/users/password/{userID}":           {summary: "Reset a user's password", request: model.User{}, response: message{}},
+	"PUT /api/v1/users/apikey/{userID}":             {summary: "Reset a user's default API key", response: apiKeyReset{}},
+	"POST /api/v1/users/{userID}/admin":             {summary: "Give a user admin privileges", response: message{}},
+	"DELETE /api/v1/users/{userID}/admin":           {summary: "Revoke a user's admin privileges", response: message{}},
+	"GET /api/v1/users/{userID}/apikeys":            {summary: "List a user's API keys", response: []*model.APIKey{}},
+	"POST /api/v1/users/{userID}/apikeys":           {summary: "Create an API key, the key is only returned once", request: model.APIKey{}, response: model.APIKey{}},
+	"DELETE /api/v1/users/{userID}/apikeys/{keyID}": {summary: "Revoke an API key", response: message{}},
+
+	"POST /api/v1/users/{userID}/apikeys/{keyID}/rotate": {
+		summary:  "Replace an API key, the old key stays usable for the grace period",
+		query:    []param{{"grace", "string", "a duration such as 24h"}},
+		response: model.APIKey{},
+	},
+
+	"POST /api/v1/astronauts": {summary: "Add an astronaut", request: model.Astronaut{}, response: model.Astronaut{}},
+	"GET /api/v1/astronauts": {
+		summary: "List astronauts",
+		query: list(
+			param{"gender", "string", "M or F"},
+			param{"status", "string", "active, retired, management or deceased"},
+			param{"birthYearFrom", "integer", ""},
+			param{"birthYearTo", "integer", ""},
+		),
+		response: model.Page[*model.Astronaut]{},
+	},
+	"GET /api/v1/astronauts/search":                 {summary: "Search astronauts by name", query: []param{{"name", "string", ""}}, response: []*model.Astronaut{}},
+	"GET /api/v1/astronauts/{astronautID}":          {summary: "Get an astronaut", query: []param{asOfParam}, response: model.Astronaut{}, versioned: true},
+	"PUT /api/v1/astronauts/{astronautID}":          {summary: "Update an astronaut", request: model.Astronaut{}, response: message{}, versioned: true},
+	"PATCH /api/v1/astronauts/{astronautID}":        {summary: "Patch an astronaut", request: patch(model.Astronaut{}), response: model.Astronaut{}, versioned: true},
+	"DELETE /api/v1/astronauts/{astronautID}":       {summary: "Delete an astronaut", response: message{}, versioned: true},
+	"POST /api/v1/astronauts/{astronautID}/restore": {summary: "Restore a deleted astronaut", response: message{}},
+	"GET /api/v1/astronauts/{astronautID}/history":  {summary: "List every version of an astronaut and their records", response: model.AstronautHistory{}},
+	"GET /api/v1/astronauts/{astronautID}/profile":  {summary: "Get an astronaut with all of their records", response: model.AstronautProfile{}},
+
+	"POST /api/v1/astronauts/{astronautID}/log":   {summary: "Add an astronaut's log", request: model.AstronautLog{}, response: model.AstronautLog{}},
+	"GET /api/v1/astronauts/{astronautID}/log":    {summary: "Get an astronaut's log", query: []param{asOfParam}, response: model.AstronautLog{}, versioned: true},
+	"PUT /api/v1/astronauts/{astronautID}/log":    {summary: "Update an astronaut's log", request: model.AstronautLog{}, response: model.AstronautLog{}, versioned: true},
+	"PATCH /api/v1/astronauts/{astronautID}/log":  {summary: "Patch an astronaut's log", request: patch(model.AstronautLog{}), response: model.AstronautLog{}, versioned: true},
+	"DELETE /api/v1/astronauts/{astronautID}/log": {summary: "Delete an astronaut's log", response: message{}, versioned: true},
+
+	"POST /api/v1/astronauts/{astronautID}/military":   {summary: "Add an astronaut's military record", request: model.MilitaryLog{}, response: model.MilitaryLog{}},
+	"GET /api/v1/astronauts/{astronautID}/military":    {summary: "Get an astronaut's military record", query: []param{asOfParam}, response: model.MilitaryLog{}, versioned: true},
+	"PUT /api/v1/astronauts/{astronautID}/military":    {summary: "Update an astronaut's military record", request: model.MilitaryLog{}, response: model.MilitaryLog{}, versioned: true},
+	"PATCH /api/v1/astronauts/{astronautID}/military":  {summary: "Patch an astronaut's military record", request: patch(model.MilitaryLog{}), response: model.MilitaryLog{}, versioned: true},
+	"DELETE /api/v1/astronauts/{astronautID}/military": {summary: "Delete an astronaut's military record", response: message{}, versioned: true},
+
+	"GET /api/v1/astronauts/{astronautID}/education":                               {summary: "Get an astronaut's education", response: model.AcademicLog{}},
+	"PUT /api/v1/astronauts/{astronautID}/education/alma-maters/{almaMaterID}":     {summary: "Add an alma mater to an astronaut", response: message{}},
+	"DELETE /api/v1/astronauts/{astronautID}/education/alma-maters/{almaMaterID}":  {summary: "Remove an alma mater from an astronaut", response: message{}},
+	"PUT /api/v1/astronauts/{astronautID}/education/undergrad-majors/{majorID}":    {summary: "Add an undergraduate major to an astronaut", response: message{}},
+	"DELETE /api/v1/astronauts/{astronautID}/education/undergrad-majors/{majorID}": {summary: "Remove an undergraduate major from an astronaut", response: message{}},
+	"PUT /api/v1/astronauts/{astronautID}/education/grad-majors/{majorID}":         {summary: "Add a graduate major to an astronaut", response: message{}},
+	"DELETE /api/v1/astronauts/{astronautID}/education/grad-majors/{majorID}":      {summary: "Remove a graduate major from an astronaut", response: message{}},
+
+	"POST /api/v1/majors":             {summary: "Add a major", request: model.Major{}, response: model.Major{}},
+	"GET /api/v1/majors":              {summary: "List majors", response: []*model.Major{}},
+	"GET /api/v1/majors/{majorID}":    {summary: "Get a major", response: model.Major{}, versioned: true},
+	"PUT /api/v1/majors/{majorID}":    {summary: "Update a major", request: model.Major{}, response: model.Major{}, versioned: true},
+	"DELETE /api/v1/majors/{majorID}": {summary: "Delete a major", response: message{}, versioned: true},
+
+	"POST /api/v1/alma-maters":                 {summary: "Add an alma mater", request: model.AlmaMater{}, response: model.AlmaMater{}},
+	"GET /api/v1/alma-maters":                  {summary: "List alma maters", response: []*model.AlmaMater{}},
+	"GET /api/v1/alma-maters/{almaMaterID}":    {summary: "Get an alma mater", response: model.AlmaMater{}, versioned: true},
+	"PUT /api/v1/alma-maters/{almaMaterID}":    {summary: "Update an alma mater", request: model.AlmaMater{}, response: model.AlmaMater{}, versioned: true},
+	"DELETE /api/v1/alma-maters/{almaMaterID}": {summary: "Delete an alma mater", response: message{}, versioned: true},
+
+	"POST /api/v1/missions": {summary: "Add a mission", request: model.Mission{}, response: model.Mission{}},
+	"GET /api/v1/missions": {
+		summary: "List missions",
+		query: list(
+			param{"from", "string", "yyyy-mm-dd"},
+			param{"to", "string", "yyyy-mm-dd"},
+			param{"successful", "boolean", ""},
+		),
+		response: model.Page[*model.Mission]{},
+	},
+	"GET /api/v1/missions/search":                            {summary: "Search missions by name", query: []param{{"name", "string", ""}}, response: []*model.Mission{}},
+	"GET /api/v1/missions/{missionID}":                       {summary: "Get a mission", response: model.Mission{}, versioned: true},
+	"PUT /api/v1/missions/{missionID}":                       {summary: "Update a mission", request: model.Mission{}, response: message{}, versioned: true},
+	"PATCH /api/v1/missions/{missionID}":                     {summary: "Patch a mission", request: patch(model.Mission{}), response: model.Mission{}, versioned: true},
+	"DELETE /api/v1/missions/{missionID}":                    {summary: "Delete a mission", response: message{}, versioned: true},
+	"POST /api/v1/missions/{missionID}/restore":              {summary: "Restore a deleted mission", response: message{}},
+	"GET /api/v1/missions/{missionID}/crew":                  {summary: "List a mission's crew", response: []*model.Astronaut{}},
+	"PUT /api/v1/missions/{missionID}/crew/{astronautID}":    {summary: "Add an astronaut to a mission's crew", response: message{}},
+	"DELETE /api/v1/missions/{missionID}/crew/{astronautID}": {summary: "Remove an astronaut from a mission's crew", response: message{}},
+	"GET /api/v1/astronauts/{astronautID}/missions":          {summary: "List an astronaut's missions", response: []*model.Mission{}},
+
+	"POST /api/v1/import": {
+		summary: "Import astronaut data from CSV or JSON",
+		request: content{
+			"text/csv":         schema{"type": "string"},
+			"application/json": []*model.AstronautData{},
+			"multipart/form-data": schema{
+				"type":       "object",
+				"properties": schema{"file": schema{"type": "string", "contentMediaType": "text/csv"}},
+			},
+		},
+		response: model.ImportReport{},
+	},
+	"GET /api/v1/export": {
+		summary: "Export every astronaut",
+		query:   []param{{"format", "string", "csv, json or ndjson, defaults to csv"}},
+		response: content{
+			"text/csv":             schema{"type": "string"},
+			"application/json":     []*model.AstronautData{},
+			"application/x-ndjson": model.AstronautData{},
+		},
+	},
+
+	"GET /api/v1/search": {
+		summary: "Search astronauts, missions, alma maters and majors",
+		query: []param{
+			{"q", "string", "the search text"},
+			{"type", "string", "comma separated astronaut, mission, alma_mater or major"},
+			{"limit", "integer", ""},
+		},
+		response: []*model.SearchResult{},
+	},
+
+	"GET /api/v1/audit": {
+		summary: "List the audit trail",
+		query: list(
+			param{"entity", "string", "the type of entity changed"},
+			param{"entityId", "string", ""},
+			param{"actor", "integer", "the id of the user who made the change"},
+			param{"from", "string", "a date yyyy-mm-dd or RFC 3339 timestamp"},
+			param{"to", "string", "a date yyyy-mm-dd or RFC 3339 timestamp"},
+		),
+		response: model.Page[*model.AuditEvent]{},
+	},
+
+	"GET /api/v1/openapi.json": {summary: "Get this OpenAPI document", response: schema{"type": "object"}},
+	"GET /api/v1/docs":         {summary: "Browse the API documentation", response: content{"text/html": schema{"type": "string"}}},
+
+	"GET /api/v1/docs/swagger-ui-bundle.js": {summary: "Get the script of the documentation page", response: content{"text/javascript": schema{"type": "string"}}},
+	"GET /api/v1/docs/swagger-ui.css":       {summary: "Get the stylesheet of the documentation page", response: content{"text/css": schema{"type": "string"}}},
+
+	"GET /healthz": {summary: "Check the process is up", response: map[string]string{}},
+	"GET /readyz":  {summary: "Check the server can take traffic, responding 503 when it can't", response: model.ReadinessReport{}},
+	"GET /version": {summary: "Get the build of the running server", response: model.BuildInfo{}},
+	"GET /metrics": {summary: "Scrape metrics in the Prometheus text format, sending the metrics token as a bearer token", response: content{"text/plain": schema{"type": "string"}}},
+}
+
+// newSpec builds the OpenAPI document of the routes, only routes with an operation are included.
+func newSpec(routes []route) map[string]any {
+	g := &schemaGenerator{schemas: make(map[string]any)}
+	problem := g.schemaOf(reflect.TypeOf(handlers.Problem{}))
+
+	paths := make(map[string]map[string]any)
+	for _, rt := range routes {
+		op, ok := operations[rt.pattern]
+		if !ok {
+			continue
+		}
+
+		method, path, _ := strings.Cut(rt.pattern, " ")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(method)] = g.operation(rt, method, path, op, problem)
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "Astronaut API",
+			"version": "1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-KEY"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func (g *schemaGenerator) operation(rt route, method, path string, op operation, problem any) map[string]any {
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]any{
+			"name": m[1], "in": "path", "required": true, "schema": schema{"type": "integer"},
+		})
+	}
+	for _, p := range op.query {
+		qp := map[string]any{"name": p.name, "in": "query", "schema": schema{"type": p.kind}}
+		if p.description != "" {
+			qp["description"] = p.description
+		}
+		params = append(params, qp)
+	}
+
+	write := method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete
+	if op.versioned && write {
+		params = append(params, map[string]any{
+			"name":        "If-Match",
+			"in":          "header",
+			"required":    true,
+			"description": "the ETag the change is based on, or * to apply it to any version",
+			"schema":      schema{"type": "string"},
+		})
+	}
+
+	ok := map[string]any{"description": "OK", "content": g.content(op.response)}
+	if op.versioned && method != http.MethodDelete {
+		ok["headers"] = map[string]any{
+			"ETag": map[string]any{"description": "the version of the record", "schema": schema{"type": "string"}},
+		}
+	}
+
+	// operations are tagged by their resource, the probes outside /api/v1 share one tag
+	tag := strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/")[0]
+	if rt.access == probe {
+		tag = "monitoring"
+	}
+
+	o := map[string]any{
+		"summary":     op.summary,
+		"operationId": operationID(method, path),
+		"tags":        []string{tag},
+		"responses": map[string]any{
+			"200": ok,
+			"default": map[string]any{
+				"description": "problem details of the error",
+				"content":     map[string]any{"application/problem+json": map[string]any{"schema": problem}},
+			},
+		},
+	}
+	if params != nil {
+		o["parameters"] = params
+	}
+	if op.request != nil {
+		o["requestBody"] = map[string]any{"required": true, "content": g.content(op.request)}
+	}
+
+	switch rt.access {
+	case public:
+		o["security"] = []any{}
+	case probe:
+		o["security"] = []any{}
+		o["description"] = "Not rate limited."
+	case authenticated:
+		o["description"] = fmt.Sprintf("Requires the %s scope.", rt.scope)
+	case self:
+		o["description"] = fmt.Sprintf("Requires the %s scope, as the user in the path or an admin.", rt.scope)
+	case admin:
+		o["description"] = fmt.Sprintf("Requires the %s scope, as an admin.", rt.scope)
+	}
+	if rt.access != public && rt.access != probe {
+		o["security"] = []any{map[string]any{"apiKey": []string{}}, map[string]any{"bearer": []string{}}}
+	}
+	return o
+}
+
+// operationID names an operation after its method and the literal segments of its path.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, s := range strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/") {
+		s = strings.Trim(s, "{}")
+		for _, w := range strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '.' }) {
+			id += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return id
+}
+
+func (g *schemaGenerator) content(v any) map[string]any {
+	c, ok := v.(content)
+	if !ok {
+		c = content{"application/json": v}
+	}
+
+	m := make(map[string]any, len(c))
+	for mediaType, body := range c {
+		s, ok := body.(schema)
+		if !ok {
+			s = g.schemaOf(reflect.TypeOf(body))
+		}
+		m[mediaType] = map[string]any{"schema": s}
+	}
+	return m
+}
+
+// schemaGenerator writes JSON Schemas of Go types, named structs are added to schemas and referenced.
+type schemaGenerator struct {
+	schemas map[string]any
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	typeQualifier  = regexp.MustCompile(`[\w./-]*\.`)
+	nonAlphaNum    = regexp.MustCompile(`[^A-Za-z0-9]`)
+)
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		// generic type names hold the package path of their type arguments, Page[*model.Mission]
+		// is named PageMission
+		name := nonAlphaNum.ReplaceAllString(typeQualifier.ReplaceAllString(t.Name(), ""), "")
+		name = strings.ToUpper(name[:1]) + name[1:]
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return schema{"$ref": "#/components/schemas/" + name}
+	}
+	return schema{}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) schema {
+	props := make(schema)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schemaOf(f.Type)
+	}
+	return schema{"type": "object", "properties": props}
+}
+
+// handleOpenAPI serves the OpenAPI document, spec is filled in once every route is known.
+func handleOpenAPI(spec *map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(*spec)
+	}
+}
+
+func handleDocs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	}
+}
+
+// handleDocsAsset serves a swagger-ui file the docs page loads.
+func handleDocsAsset(name, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := swaggerUI.ReadFile("swagger-ui/" + name)
+		if err != nil {
+			handlers.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(b)
+	}
+}
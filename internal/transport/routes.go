@@ -3,31 +3,251 @@ package transport
 import (
 	"net/http"
 
+	"github.com/LaQuannT/astronaut-api/internal/metrics"
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
 	"github.com/LaQuannT/astronaut-api/internal/transport/handlers"
+	"github.com/LaQuannT/astronaut-api/internal/transport/middlewares"
 )
 
+// access is the level of authorisation a route requires.
+type access int
+
+const (
+	// public routes can be called without an API key
+	public access = iota
+	// authenticated routes require a valid API key or access token
+	authenticated
+	// self routes require the credentials of the {userID} in the path or of an admin
+	self
+	// admin routes require the credentials of an admin
+	admin
+	// probe routes skip authentication and rate limiting, so health checks and metrics scrapes are
+	// never throttled or blocked by the quota check when the database is down. /metrics checks its
+	// own token instead.
+	probe
+)
+
+// route is a single endpoint, scope is the API key scope required to call it when access is not public.
+type route struct {
+	pattern string
+	access  access
+	scope   string
+	handler http.Handler
+}
+
 func addRoutes(
 	mux *http.ServeMux,
+	tokens *service.Tokens,
 	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	apiKeyRepository model.APIKeyRepository,
 	astronautRepository model.AstronautRepository,
-) {
-	// user routes
-	mux.Handle("POST /api/v1/register", handlers.HandleRegisterUser(userRepository))
-	mux.Handle("GET /api/v1/user", handlers.HandleGetUser(userRepository))
-	mux.Handle("GET /api/v1/users", handlers.HandleGetUsers(userRepository))
-	mux.Handle("PUT /api/v1/users/{userID}", handlers.HandleUpdateUser(userRepository))
-	mux.Handle("DELETE /api/v1/users/{userID}", handlers.HandleDeleteUser(userRepository))
-	mux.Handle("PUT /api/v1/users/password/{userID}", handlers.HandlePasswordReset(userRepository))
-	mux.Handle("PUT /api/v1/users/apikey/{userID}", handlers.HandleAPIKeyReset(userRepository))
-
-	// astronaut routes
-	mux.Handle("POST /api/v1/astonauts", handlers.HandleCreateAstronaut(astronautRepository))
-	mux.Handle("GET /api/v1/astonauts", handlers.HandleGetAstronauts(astronautRepository))
-	mux.Handle("GET /api/v1/astronauts/search", handlers.HandleSearchAstronautName(astronautRepository))
-	mux.Handle("GET /api/v1/astonauts/{astronautID}", handlers.HandleGetAstronaut(astronautRepository))
-	mux.Handle("PUT /api/v1/astronauts/{astronautID}", handlers.HandleUpdateAstronaut(astronautRepository))
-	mux.Handle("DELETE /api/v1/astronauts/{astronautID}", handlers.HandleDeleteAstronaut(astronautRepository))
-
-	// mission routes
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+	academicLogRepository model.AcademicLogRepository,
+	missionRepository model.MissionRepository,
+	datasetRepository model.DatasetRepository,
+	searchRepository model.SearchRepository,
+	auditRepository model.AuditRepository,
+	readiness *service.Readiness,
+	metricsToken string,
+) map[string]route {
+	spec := new(map[string]any)
+
+	routes := routeTable(
+		spec,
+		tokens,
+		userRepository,
+		sessionRepository,
+		apiKeyRepository,
+		astronautRepository,
+		astronautLogRepository,
+		militaryLogRepository,
+		academicLogRepository,
+		missionRepository,
+		datasetRepository,
+		searchRepository,
+		auditRepository,
+		readiness,
+		metricsToken,
+	)
+	*spec = newSpec(routes)
+
+	adminOnly := middlewares.AdminOnly(userRepository)
+	selfOrAdmin := middlewares.SelfOrAdmin(userRepository)
+
+	// byPattern maps each pattern to its route so requests can be rate limited by scope
+	byPattern := make(map[string]route, len(routes))
+
+	for _, rt := range routes {
+		byPattern[rt.pattern] = rt
+
+		handler := rt.handler
+		if rt.access != public && rt.access != probe {
+			handler = middlewares.RequireScope(rt.scope)(handler)
+		}
+
+		switch rt.access {
+		case authenticated:
+			handler = middlewares.RequireUser(handler)
+		case self:
+			handler = middlewares.RequireUser(selfOrAdmin(handler))
+		case admin:
+			handler = middlewares.RequireUser(adminOnly(handler))
+		}
+
+		mux.Handle(rt.pattern, handler)
+	}
+	return byPattern
+}
+
+// routeTable lists every route, spec is served at /api/v1/openapi.json once it has been built from them.
+func routeTable(
+	spec *map[string]any,
+	tokens *service.Tokens,
+	userRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	apiKeyRepository model.APIKeyRepository,
+	astronautRepository model.AstronautRepository,
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+	academicLogRepository model.AcademicLogRepository,
+	missionRepository model.MissionRepository,
+	datasetRepository model.DatasetRepository,
+	searchRepository model.SearchRepository,
+	auditRepository model.AuditRepository,
+	readiness *service.Readiness,
+	metricsToken string,
+) []route {
+	return []route{
+
+		// user routes
+		{"POST /api/v1/register", public, "", handlers.HandleRegisterUser(userRepository)},
+		{"POST /api/v1/login", public, "", handlers.HandleLogin(userRepository, sessionRepository, tokens)},
+		{"POST /api/v1/token/refresh", public, "", handlers.HandleRefreshToken(sessionRepository, tokens)},
+		{"POST /api/v1/logout", public, "", handlers.HandleLogout(sessionRepository)},
+		{"GET /api/v1/user", admin, model.ScopeUsersAdmin, handlers.HandleGetUser(userRepository)},
+		{"GET /api/v1/users", admin, model.ScopeUsersAdmin, handlers.HandleGetUsers(userRepository)},
+		{"GET /api/v1/users/{userID}", self, model.ScopeUsersRead, handlers.HandleGetUserByID(userRepository)},
+		{"PUT /api/v1/users/{userID}", self, model.ScopeUsersWrite, handlers.HandleUpdateUser(userRepository)},
+		{"PATCH /api/v1/users/{userID}", self, model.ScopeUsersWrite, handlers.HandlePatchUser(userRepository)},
+		{"DELETE /api/v1/users/{userID}", self, model.ScopeUsersWrite, handlers.HandleDeleteUser(userRepository)},
+		{"POST /api/v1/users/{userID}/restore", admin, model.ScopeUsersAdmin, handlers.HandleRestoreUser(userRepository)},
+		{"PUT /api/v1/users/password/{userID}", self, model.ScopeUsersWrite, handlers.HandlePasswordReset(userRepository)},
+		{"PUT /api/v1/users/apikey/{userID}", self, model.ScopeUsersWrite, handlers.HandleAPIKeyReset(apiKeyRepository)},
+		{"POST /api/v1/users/{userID}/admin", admin, model.ScopeUsersAdmin, handlers.HandleCreateAdmin(userRepository)},
+		{"GET /api/v1/users/{userID}/apikeys", self, model.ScopeUsersRead, handlers.HandleGetAPIKeys(apiKeyRepository)},
+		{"POST /api/v1/users/{userID}/apikeys", self, model.ScopeUsersWrite, handlers.HandleCreateAPIKey(apiKeyRepository)},
+		{"POST /api/v1/users/{userID}/apikeys/{keyID}/rotate", self, model.ScopeUsersWrite, handlers.HandleRotateAPIKey(apiKeyRepository)},
+		{"DELETE /api/v1/users/{userID}/apikeys/{keyID}", self, model.ScopeUsersWrite, handlers.HandleRevokeAPIKey(apiKeyRepository)},
+		{"DELETE /api/v1/users/{userID}/admin", admin, model.ScopeUsersAdmin, handlers.HandleRemoveAdmin(userRepository)},
+
+		// astronaut routes
+		{"POST /api/v1/astronauts", admin, model.ScopeAstronautsWrite, handlers.HandleCreateAstronaut(astronautRepository)},
+		{"GET /api/v1/astronauts", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAstronauts(astronautRepository)},
+		{"GET /api/v1/astronauts/search", authenticated, model.ScopeAstronautsRead, handlers.HandleSearchAstronautName(astronautRepository)},
+		{"GET /api/v1/astronauts/{astronautID}", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAstronaut(userRepository, astronautRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}", admin, model.ScopeAstronautsWrite, handlers.HandleUpdateAstronaut(astronautRepository)},
+		{"PATCH /api/v1/astronauts/{astronautID}", admin, model.ScopeAstronautsWrite, handlers.HandlePatchAstronaut(astronautRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}", admin, model.ScopeAstronautsWrite, handlers.HandleDeleteAstronaut(astronautRepository)},
+		{"POST /api/v1/astronauts/{astronautID}/restore", admin, model.ScopeAstronautsWrite, handlers.HandleRestoreAstronaut(astronautRepository)},
+
+		{"GET /api/v1/astronauts/{astronautID}/history", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAstronautHistory(
+			userRepository,
+			astronautRepository,
+			astronautLogRepository,
+			militaryLogRepository,
+		)},
+
+		{"GET /api/v1/astronauts/{astronautID}/profile", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAstronautProfile(
+			astronautRepository,
+			astronautLogRepository,
+			militaryLogRepository,
+			academicLogRepository,
+			missionRepository,
+		)},
+
+		// astronaut log routes
+		{"POST /api/v1/astronauts/{astronautID}/log", admin, model.ScopeAstronautsWrite, handlers.HandleCreateAstronautLog(astronautLogRepository)},
+		{"GET /api/v1/astronauts/{astronautID}/log", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAstronautLog(userRepository, astronautRepository, astronautLogRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}/log", admin, model.ScopeAstronautsWrite, handlers.HandleUpdateAstronautLog(astronautLogRepository)},
+		{"PATCH /api/v1/astronauts/{astronautID}/log", admin, model.ScopeAstronautsWrite, handlers.HandlePatchAstronautLog(astronautLogRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}/log", admin, model.ScopeAstronautsWrite, handlers.HandleDeleteAstronautLog(astronautLogRepository)},
+
+		// military log routes
+		{"POST /api/v1/astronauts/{astronautID}/military", admin, model.ScopeAstronautsWrite, handlers.HandleCreateMilitaryLog(militaryLogRepository)},
+		{"GET /api/v1/astronauts/{astronautID}/military", authenticated, model.ScopeAstronautsRead, handlers.HandleGetMilitaryLog(userRepository, astronautRepository, militaryLogRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}/military", admin, model.ScopeAstronautsWrite, handlers.HandleUpdateMilitaryLog(militaryLogRepository)},
+		{"PATCH /api/v1/astronauts/{astronautID}/military", admin, model.ScopeAstronautsWrite, handlers.HandlePatchMilitaryLog(militaryLogRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}/military", admin, model.ScopeAstronautsWrite, handlers.HandleDeleteMilitaryLog(militaryLogRepository)},
+
+		// academic routes
+		{"GET /api/v1/astronauts/{astronautID}/education", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAcademicLog(academicLogRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}/education/alma-maters/{almaMaterID}", admin, model.ScopeAstronautsWrite, handlers.HandleAddAstronautAlmaMater(academicLogRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}/education/alma-maters/{almaMaterID}", admin, model.ScopeAstronautsWrite, handlers.HandleRemoveAstronautAlmaMater(academicLogRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}/education/undergrad-majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleAddAstronautUndergradMajor(academicLogRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}/education/undergrad-majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleRemoveAstronautUndergradMajor(academicLogRepository)},
+		{"PUT /api/v1/astronauts/{astronautID}/education/grad-majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleAddAstronautGradMajor(academicLogRepository)},
+		{"DELETE /api/v1/astronauts/{astronautID}/education/grad-majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleRemoveAstronautGradMajor(academicLogRepository)},
+
+		{"POST /api/v1/majors", admin, model.ScopeAstronautsWrite, handlers.HandleCreateMajor(academicLogRepository)},
+		{"GET /api/v1/majors", authenticated, model.ScopeAstronautsRead, handlers.HandleGetMajors(academicLogRepository)},
+		{"GET /api/v1/majors/{majorID}", authenticated, model.ScopeAstronautsRead, handlers.HandleGetMajor(academicLogRepository)},
+		{"PUT /api/v1/majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleUpdateMajor(academicLogRepository)},
+		{"DELETE /api/v1/majors/{majorID}", admin, model.ScopeAstronautsWrite, handlers.HandleDeleteMajor(academicLogRepository)},
+
+		{"POST /api/v1/alma-maters", admin, model.ScopeAstronautsWrite, handlers.HandleCreateAlmaMater(academicLogRepository)},
+		{"GET /api/v1/alma-maters", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAlmaMaters(academicLogRepository)},
+		{"GET /api/v1/alma-maters/{almaMaterID}", authenticated, model.ScopeAstronautsRead, handlers.HandleGetAlmaMater(academicLogRepository)},
+		{"PUT /api/v1/alma-maters/{almaMaterID}", admin, model.ScopeAstronautsWrite, handlers.HandleUpdateAlmaMater(academicLogRepository)},
+		{"DELETE /api/v1/alma-maters/{almaMaterID}", admin, model.ScopeAstronautsWrite, handlers.HandleDeleteAlmaMater(academicLogRepository)},
+
+		// mission routes
+		{"POST /api/v1/missions", admin, model.ScopeMissionsWrite, handlers.HandleCreateMission(missionRepository)},
+		{"GET /api/v1/missions", authenticated, model.ScopeMissionsRead, handlers.HandleGetMissions(missionRepository)},
+		{"GET /api/v1/missions/search", authenticated, model.ScopeMissionsRead, handlers.HandleSearchMissionName(missionRepository)},
+		{"GET /api/v1/missions/{missionID}", authenticated, model.ScopeMissionsRead, handlers.HandleGetMission(missionRepository)},
+		{"PUT /api/v1/missions/{missionID}", admin, model.ScopeMissionsWrite, handlers.HandleUpdateMission(missionRepository)},
+		{"PATCH /api/v1/missions/{missionID}", admin, model.ScopeMissionsWrite, handlers.HandlePatchMission(missionRepository)},
+		{"DELETE /api/v1/missions/{missionID}", admin, model.ScopeMissionsWrite, handlers.HandleDeleteMission(missionRepository)},
+		{"POST /api/v1/missions/{missionID}/restore", admin, model.ScopeMissionsWrite, handlers.HandleRestoreMission(missionRepository)},
+		{"GET /api/v1/missions/{missionID}/crew", authenticated, model.ScopeMissionsRead, handlers.HandleGetMissionCrew(missionRepository)},
+		{"PUT /api/v1/missions/{missionID}/crew/{astronautID}", admin, model.ScopeMissionsWrite, handlers.HandleAddMissionCrew(missionRepository)},
+		{"DELETE /api/v1/missions/{missionID}/crew/{astronautID}", admin, model.ScopeMissionsWrite, handlers.HandleRemoveMissionCrew(missionRepository)},
+		{"GET /api/v1/astronauts/{astronautID}/missions", authenticated, model.ScopeMissionsRead, handlers.HandleGetAstronautMissions(missionRepository)},
+
+		// dataset routes
+		{"POST /api/v1/import", admin, model.ScopeAstronautsWrite, handlers.HandleImportAstronautData(datasetRepository)},
+		{"GET /api/v1/export", authenticated, model.ScopeAstronautsRead, handlers.HandleExportAstronautData(datasetRepository)},
+
+		// search routes
+		{"GET /api/v1/search", authenticated, model.ScopeAstronautsRead, handlers.HandleSearch(searchRepository)},
+
+		// audit routes
+		{"GET /api/v1/audit", admin, model.ScopeUsersAdmin, handlers.HandleGetAuditEvents(auditRepository)},
+
+		// documentation routes
+		{"GET /api/v1/openapi.json", public, "", handleOpenAPI(spec)},
+		{"GET /api/v1/docs", public, "", handleDocs()},
+		{"GET /api/v1/docs/swagger-ui-bundle.js", public, "", handleDocsAsset("swagger-ui-bundle.js", "text/javascript; charset=utf-8")},
+		{"GET /api/v1/docs/swagger-ui.css", public, "", handleDocsAsset("swagger-ui.css", "text/css; charset=utf-8")},
+
+		// monitoring routes
+		{"GET /healthz", probe, "", handlers.HandleHealthz()},
+		{"GET /readyz", probe, "", handlers.HandleReadyz(readiness)},
+		{"GET /version", probe, "", handlers.HandleVersion()},
+		{"GET /metrics", probe, "", metrics.Handler(metrics.Default, metricsToken)},
+	}
+}
+
+// RoutePatterns returns the pattern of every route registered by NewServer.
+func RoutePatterns() []string {
+	routes := routeTable(new(map[string]any), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "")
+
+	patterns := make([]string, len(routes))
+	for i, rt := range routes {
+		patterns[i] = rt.pattern
+	}
+	return patterns
 }
@@ -5,25 +5,77 @@ import (
 	"net/http"
 
 	"github.com/LaQuannT/astronaut-api/internal/model"
+	"github.com/LaQuannT/astronaut-api/internal/service"
 	"github.com/LaQuannT/astronaut-api/internal/transport/middlewares"
 )
 
 func NewServer(
 	logger *slog.Logger,
+	limiter *service.RateLimiter,
+	tokens *service.Tokens,
 	usrRepository model.UserRepository,
+	sessionRepository model.SessionRepository,
+	apiKeyRepository model.APIKeyRepository,
 	astronautRepository model.AstronautRepository,
+	astronautLogRepository model.AstronautLogRepository,
+	militaryLogRepository model.MilitaryLogRepository,
+	academicLogRepository model.AcademicLogRepository,
+	missionRepository model.MissionRepository,
+	datasetRepository model.DatasetRepository,
+	searchRepository model.SearchRepository,
+	auditRepository model.AuditRepository,
+	readiness *service.Readiness,
+	metricsToken string,
 ) http.Handler {
 	mux := http.NewServeMux()
 
-	addRoutes(
+	// every change made through the api is recorded in the audit trail
+	usrRepository = service.NewAuditedUserRepo(usrRepository, auditRepository)
+	astronautRepository = service.NewAuditedAstronautRepo(astronautRepository, auditRepository)
+	astronautLogRepository = service.NewAuditedAstronautLogRepo(astronautLogRepository, auditRepository)
+	militaryLogRepository = service.NewAuditedMilitaryLogRepo(militaryLogRepository, auditRepository)
+	academicLogRepository = service.NewAuditedAcademicLogRepo(academicLogRepository, auditRepository)
+	missionRepository = service.NewAuditedMissionRepo(missionRepository, auditRepository)
+	apiKeyRepository = service.NewAuditedAPIKeyRepo(apiKeyRepository, auditRepository)
+	datasetRepository = service.NewAuditedDatasetRepo(datasetRepository, auditRepository)
+
+	routes := addRoutes(
 		mux,
+		tokens,
 		usrRepository,
+		sessionRepository,
+		apiKeyRepository,
 		astronautRepository,
+		astronautLogRepository,
+		militaryLogRepository,
+		academicLogRepository,
+		missionRepository,
+		datasetRepository,
+		searchRepository,
+		auditRepository,
+		readiness,
+		metricsToken,
 	)
 
+	patternOf := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+	scopeOf := func(r *http.Request) string {
+		return routes[patternOf(r)].scope
+	}
+	exempt := func(r *http.Request) bool {
+		return routes[patternOf(r)].access == probe
+	}
+
 	var handler http.Handler = mux
+	handler = middlewares.RateLimit(limiter, scopeOf, exempt)(handler)
+	handler = middlewares.Authenticate(usrRepository, sessionRepository, apiKeyRepository, tokens, exempt)(handler)
 	handler = middlewares.EnableCors(handler)
+	handler = middlewares.Metrics(patternOf)(handler)
 	mw := middlewares.RequestLogger(logger)
 	handler = mw(handler)
+	handler = middlewares.Trace(patternOf)(handler)
+	handler = middlewares.RequestID(handler)
 	return handler
 }
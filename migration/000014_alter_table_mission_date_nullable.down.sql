@@ -0,0 +1,2 @@
+UPDATE mission SET date_of_mission = '1970-01-01' WHERE date_of_mission IS NULL;
+ALTER TABLE mission ALTER COLUMN date_of_mission SET NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE mission ALTER COLUMN date_of_mission DROP NOT NULL;
\ No newline at end of file
@@ -0,0 +1,12 @@
+DROP VIEW IF EXISTS search_document;
+
+DROP INDEX IF EXISTS astronaut_search_trgm_idx;
+DROP INDEX IF EXISTS astronaut_search_tsv_idx;
+DROP INDEX IF EXISTS mission_search_trgm_idx;
+DROP INDEX IF EXISTS mission_search_tsv_idx;
+DROP INDEX IF EXISTS alma_mater_search_trgm_idx;
+DROP INDEX IF EXISTS alma_mater_search_tsv_idx;
+DROP INDEX IF EXISTS major_search_trgm_idx;
+DROP INDEX IF EXISTS major_search_tsv_idx;
+
+DROP FUNCTION IF EXISTS search_text(TEXT);
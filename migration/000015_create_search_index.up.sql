@@ -0,0 +1,44 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- unaccent is only stable, so it is wrapped with the dictionary pinned to be usable in indexes
+CREATE FUNCTION search_text(TEXT)
+RETURNS TEXT AS $$
+    SELECT lower(public.unaccent('public.unaccent'::REGDICTIONARY, $1));
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+
+CREATE INDEX astronaut_search_trgm_idx ON astronaut
+    USING GIN (search_text(first_name || ' ' || last_name || ' ' || birth_place) gin_trgm_ops);
+CREATE INDEX astronaut_search_tsv_idx ON astronaut
+    USING GIN (to_tsvector('simple', search_text(first_name || ' ' || last_name || ' ' || birth_place)));
+
+CREATE INDEX mission_search_trgm_idx ON mission
+    USING GIN (search_text(name || ' ' || COALESCE(alias, '')) gin_trgm_ops);
+CREATE INDEX mission_search_tsv_idx ON mission
+    USING GIN (to_tsvector('simple', search_text(name || ' ' || COALESCE(alias, ''))));
+
+CREATE INDEX alma_mater_search_trgm_idx ON alma_mater
+    USING GIN (search_text(school) gin_trgm_ops);
+CREATE INDEX alma_mater_search_tsv_idx ON alma_mater
+    USING GIN (to_tsvector('simple', search_text(school)));
+
+CREATE INDEX major_search_trgm_idx ON major
+    USING GIN (search_text(course) gin_trgm_ops);
+CREATE INDEX major_search_tsv_idx ON major
+    USING GIN (to_tsvector('simple', search_text(course)));
+
+-- each branch repeats the indexed expression so the planner can use the indexes through the view
+CREATE VIEW search_document AS
+    SELECT 'astronaut' AS type, id, first_name || ' ' || last_name AS title, birth_place AS detail,
+           search_text(first_name || ' ' || last_name || ' ' || birth_place) AS document
+    FROM astronaut
+    UNION ALL
+    SELECT 'mission', id, name, COALESCE(alias, ''),
+           search_text(name || ' ' || COALESCE(alias, ''))
+    FROM mission
+    UNION ALL
+    SELECT 'alma_mater', id, school, '', search_text(school)
+    FROM alma_mater
+    UNION ALL
+    SELECT 'major', id, course, '', search_text(course)
+    FROM major;
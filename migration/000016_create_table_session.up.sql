@@ -0,0 +1,10 @@
+CREATE TABLE session (
+    id UUID PRIMARY KEY NOT NULL DEFAULT uuid_generate_v4(),
+    user_id INT REFERENCES "user"(id) ON DELETE CASCADE NOT NULL,
+    refresh_token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX session_user_id_idx ON session (user_id);
@@ -0,0 +1,13 @@
+DROP INDEX api_key_user_id_idx;
+
+-- only the newest key of each user survives the return to a single key per user
+DELETE FROM api_key AS a USING api_key AS b WHERE a.user_id = b.user_id AND a.id < b.id;
+
+ALTER TABLE api_key
+    DROP COLUMN id,
+    DROP COLUMN name,
+    DROP COLUMN scopes,
+    DROP COLUMN expires_at,
+    DROP COLUMN last_used_at,
+    DROP COLUMN revoked_at,
+    DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1,17 @@
+ALTER TABLE api_key
+    ADD COLUMN id SERIAL UNIQUE,
+    ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT 'default',
+    ADD COLUMN scopes TEXT[] NOT NULL DEFAULT ARRAY[
+        'astronauts:read', 'astronauts:write', 'missions:read', 'missions:write', 'users:read', 'users:write', 'users:admin'
+    ],
+    ADD COLUMN expires_at TIMESTAMPTZ,
+    ADD COLUMN last_used_at TIMESTAMPTZ,
+    ADD COLUMN revoked_at TIMESTAMPTZ,
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- existing keys keep every scope, new keys must be given a name and scopes
+ALTER TABLE api_key
+    ALTER COLUMN name DROP DEFAULT,
+    ALTER COLUMN scopes DROP DEFAULT;
+
+CREATE INDEX api_key_user_id_idx ON api_key (user_id);
@@ -0,0 +1,8 @@
+-- plaintext keys cannot be recovered from their digest, every key is replaced by a new random uuid
+DROP INDEX api_key_prefix_idx;
+
+ALTER TABLE api_key
+    DROP CONSTRAINT api_key_pkey,
+    ADD COLUMN key UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    DROP COLUMN prefix,
+    DROP COLUMN key_hash;
\ No newline at end of file
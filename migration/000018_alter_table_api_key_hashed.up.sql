@@ -0,0 +1,16 @@
+ALTER TABLE api_key
+    ADD COLUMN prefix VARCHAR(32),
+    ADD COLUMN key_hash CHAR(64);
+
+-- existing uuid keys keep working, they are looked up by the first 8 characters of the uuid
+UPDATE api_key SET
+    prefix = 'legacy_' || LEFT(key::TEXT, 8),
+    key_hash = ENCODE(SHA256(CONVERT_TO(key::TEXT, 'UTF8')), 'hex');
+
+ALTER TABLE api_key
+    ALTER COLUMN prefix SET NOT NULL,
+    ALTER COLUMN key_hash SET NOT NULL,
+    DROP COLUMN key,
+    ADD PRIMARY KEY (id);
+
+CREATE INDEX api_key_prefix_idx ON api_key (prefix);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS api_quota (
+    subject VARCHAR(64) NOT NULL,
+    day DATE NOT NULL,
+    used INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (subject, day)
+);
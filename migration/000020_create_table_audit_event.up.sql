@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_event (
+    id BIGSERIAL PRIMARY KEY,
+    -- not a foreign key, events outlive the user that made them
+    actor_id INT,
+    action VARCHAR(16) NOT NULL,
+    entity_type VARCHAR(64) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_event_entity_idx ON audit_event (entity_type, entity_id);
+CREATE INDEX audit_event_actor_id_idx ON audit_event (actor_id);
+CREATE INDEX audit_event_created_at_idx ON audit_event (created_at);
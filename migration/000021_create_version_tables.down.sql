@@ -0,0 +1,14 @@
+DROP TRIGGER military_history_versioning_update ON military_history;
+DROP TRIGGER military_history_versioning ON military_history;
+DROP TRIGGER astronaut_log_versioning_update ON astronaut_log;
+DROP TRIGGER astronaut_log_versioning ON astronaut_log;
+DROP TRIGGER astronaut_versioning_update ON astronaut;
+DROP TRIGGER astronaut_versioning ON astronaut;
+
+DROP FUNCTION version_military_history();
+DROP FUNCTION version_astronaut_log();
+DROP FUNCTION version_astronaut();
+
+DROP TABLE military_history_version;
+DROP TABLE astronaut_log_version;
+DROP TABLE astronaut_version;
@@ -0,0 +1,122 @@
+-- every version of an astronaut, astronaut_log and military_history row is kept along with the period
+-- it was current for. valid_to is NULL for the current version and set once the row is updated or
+-- deleted. Versions are written by triggers, so changes made outside the api are kept too.
+
+CREATE TABLE astronaut_version (
+    version_id BIGSERIAL PRIMARY KEY,
+    id INT NOT NULL,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    gender CHAR(1),
+    birth_date DATE NOT NULL,
+    birth_place VARCHAR(255) NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ
+);
+
+CREATE INDEX astronaut_version_id_idx ON astronaut_version (id, valid_from);
+
+CREATE TABLE astronaut_log_version (
+    version_id BIGSERIAL PRIMARY KEY,
+    astronaut_id INT NOT NULL,
+    space_flights INT,
+    space_flight_hrs INT,
+    space_walks INT,
+    space_walk_hrs INT,
+    status status NOT NULL,
+    death_date DATE,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ
+);
+
+CREATE INDEX astronaut_log_version_astronaut_id_idx ON astronaut_log_version (astronaut_id, valid_from);
+
+CREATE TABLE military_history_version (
+    version_id BIGSERIAL PRIMARY KEY,
+    astronaut_id INT NOT NULL,
+    branch VARCHAR(255) NOT NULL,
+    rank VARCHAR(255) NOT NULL,
+    retired BOOLEAN,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ
+);
+
+CREATE INDEX military_history_version_astronaut_id_idx ON military_history_version (astronaut_id, valid_from);
+
+CREATE FUNCTION version_astronaut()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_version SET valid_to = CURRENT_TIMESTAMP WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+        VALUES (NEW.id, NEW.first_name, NEW.last_name, NEW.gender, NEW.birth_date, NEW.birth_place, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+CREATE FUNCTION version_astronaut_log()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_log_version SET valid_to = CURRENT_TIMESTAMP WHERE astronaut_id = OLD.astronaut_id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO astronaut_log_version (astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+            status, death_date, valid_from)
+        VALUES (NEW.astronaut_id, NEW.space_flights, NEW.space_flight_hrs, NEW.space_walks, NEW.space_walk_hrs,
+            NEW.status, NEW.death_date, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+CREATE FUNCTION version_military_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE military_history_version SET valid_to = CURRENT_TIMESTAMP WHERE astronaut_id = OLD.astronaut_id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO military_history_version (astronaut_id, branch, rank, retired, valid_from)
+        VALUES (NEW.astronaut_id, NEW.branch, NEW.rank, NEW.retired, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+-- updates that change nothing do not start a new version
+CREATE TRIGGER astronaut_versioning
+    AFTER INSERT OR DELETE ON astronaut
+    FOR EACH ROW EXECUTE PROCEDURE version_astronaut();
+CREATE TRIGGER astronaut_versioning_update
+    AFTER UPDATE ON astronaut
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE PROCEDURE version_astronaut();
+
+CREATE TRIGGER astronaut_log_versioning
+    AFTER INSERT OR DELETE ON astronaut_log
+    FOR EACH ROW EXECUTE PROCEDURE version_astronaut_log();
+CREATE TRIGGER astronaut_log_versioning_update
+    AFTER UPDATE ON astronaut_log
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE PROCEDURE version_astronaut_log();
+
+CREATE TRIGGER military_history_versioning
+    AFTER INSERT OR DELETE ON military_history
+    FOR EACH ROW EXECUTE PROCEDURE version_military_history();
+CREATE TRIGGER military_history_versioning_update
+    AFTER UPDATE ON military_history
+    FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE PROCEDURE version_military_history();
+
+-- earlier changes were not kept, existing rows start their history now
+INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+SELECT id, first_name, last_name, gender, birth_date, birth_place, CURRENT_TIMESTAMP FROM astronaut;
+
+INSERT INTO astronaut_log_version (astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+    status, death_date, valid_from)
+SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs, status, death_date, CURRENT_TIMESTAMP
+FROM astronaut_log;
+
+INSERT INTO military_history_version (astronaut_id, branch, rank, retired, valid_from)
+SELECT astronaut_id, branch, rank, retired, CURRENT_TIMESTAMP FROM military_history;
@@ -0,0 +1,55 @@
+-- soft deleted rows are removed rather than brought back
+DELETE FROM astronaut_log WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL);
+DELETE FROM military_history WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL);
+DELETE FROM astronaut_alma_mater WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL);
+DELETE FROM astronaut_undergrad_major WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL);
+DELETE FROM astronaut_grad_major WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL);
+DELETE FROM astronaut_mission WHERE astronaut_id IN (SELECT id FROM astronaut WHERE deleted_at IS NOT NULL)
+    OR mission_id IN (SELECT id FROM mission WHERE deleted_at IS NOT NULL);
+DELETE FROM astronaut WHERE deleted_at IS NOT NULL;
+DELETE FROM mission WHERE deleted_at IS NOT NULL;
+DELETE FROM api_key WHERE user_id IN (SELECT id FROM "user" WHERE deleted_at IS NOT NULL);
+DELETE FROM admin WHERE user_id IN (SELECT id FROM "user" WHERE deleted_at IS NOT NULL);
+DELETE FROM "user" WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE VIEW search_document AS
+    SELECT 'astronaut' AS type, id, first_name || ' ' || last_name AS title, birth_place AS detail,
+           search_text(first_name || ' ' || last_name || ' ' || birth_place) AS document
+    FROM astronaut
+    UNION ALL
+    SELECT 'mission', id, name, COALESCE(alias, ''),
+           search_text(name || ' ' || COALESCE(alias, ''))
+    FROM mission
+    UNION ALL
+    SELECT 'alma_mater', id, school, '', search_text(school)
+    FROM alma_mater
+    UNION ALL
+    SELECT 'major', id, course, '', search_text(course)
+    FROM major;
+
+CREATE OR REPLACE FUNCTION version_astronaut()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_version SET valid_to = CURRENT_TIMESTAMP WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+        VALUES (NEW.id, NEW.first_name, NEW.last_name, NEW.gender, NEW.birth_date, NEW.birth_place, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+DROP INDEX user_email_key;
+ALTER TABLE "user" ADD CONSTRAINT user_email_key UNIQUE (email);
+DROP INDEX mission_name_key;
+ALTER TABLE mission ADD CONSTRAINT mission_name_key UNIQUE (name);
+
+DROP INDEX user_deleted_at_idx;
+DROP INDEX mission_deleted_at_idx;
+DROP INDEX astronaut_deleted_at_idx;
+
+ALTER TABLE "user" DROP COLUMN deleted_at;
+ALTER TABLE mission DROP COLUMN deleted_at;
+ALTER TABLE astronaut DROP COLUMN deleted_at;
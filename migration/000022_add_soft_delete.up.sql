@@ -0,0 +1,48 @@
+-- astronauts, missions and users are soft deleted: deleted_at is set instead of removing the row, and
+-- rows are only hard deleted by the purge job once they have been deleted for the retention period.
+
+ALTER TABLE astronaut ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE mission ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX astronaut_deleted_at_idx ON astronaut (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX mission_deleted_at_idx ON mission (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX user_deleted_at_idx ON "user" (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a deleted mission name or user email can be reused
+ALTER TABLE mission DROP CONSTRAINT mission_name_key;
+CREATE UNIQUE INDEX mission_name_key ON mission (name) WHERE deleted_at IS NULL;
+ALTER TABLE "user" DROP CONSTRAINT user_email_key;
+CREATE UNIQUE INDEX user_email_key ON "user" (email) WHERE deleted_at IS NULL;
+
+-- a soft deleted astronaut ends its current version and a restored one starts a new version
+CREATE OR REPLACE FUNCTION version_astronaut()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_version SET valid_to = CURRENT_TIMESTAMP WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+        VALUES (NEW.id, NEW.first_name, NEW.last_name, NEW.gender, NEW.birth_date, NEW.birth_place, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+CREATE OR REPLACE VIEW search_document AS
+    SELECT 'astronaut' AS type, id, first_name || ' ' || last_name AS title, birth_place AS detail,
+           search_text(first_name || ' ' || last_name || ' ' || birth_place) AS document
+    FROM astronaut
+    WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT 'mission', id, name, COALESCE(alias, ''),
+           search_text(name || ' ' || COALESCE(alias, ''))
+    FROM mission
+    WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT 'alma_mater', id, school, '', search_text(school)
+    FROM alma_mater
+    UNION ALL
+    SELECT 'major', id, course, '', search_text(course)
+    FROM major;
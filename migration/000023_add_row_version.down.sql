@@ -0,0 +1,17 @@
+DROP TRIGGER version_bump ON alma_mater;
+DROP TRIGGER version_bump ON major;
+DROP TRIGGER version_bump ON "user";
+DROP TRIGGER version_bump ON mission;
+DROP TRIGGER version_bump ON military_history;
+DROP TRIGGER version_bump ON astronaut_log;
+DROP TRIGGER version_bump ON astronaut;
+
+ALTER TABLE alma_mater DROP COLUMN version;
+ALTER TABLE major DROP COLUMN version;
+ALTER TABLE "user" DROP COLUMN version;
+ALTER TABLE mission DROP COLUMN version;
+ALTER TABLE military_history DROP COLUMN version;
+ALTER TABLE astronaut_log DROP COLUMN version;
+ALTER TABLE astronaut DROP COLUMN version;
+
+DROP FUNCTION bump_version();
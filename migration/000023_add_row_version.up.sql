@@ -0,0 +1,30 @@
+-- version is bumped whenever a row changes and is sent as the ETag of the resource, writes state the
+-- version they were made against with If-Match so concurrent edits cannot overwrite each other.
+
+CREATE FUNCTION bump_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    -- an update changing nothing keeps its version
+    IF ROW(NEW.*) IS DISTINCT FROM ROW(OLD.*) THEN
+        NEW.version = OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE 'plpgsql';
+
+ALTER TABLE astronaut ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE astronaut_log ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE military_history ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE mission ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE "user" ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE major ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE alma_mater ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+CREATE TRIGGER version_bump BEFORE UPDATE ON astronaut FOR EACH ROW EXECUTE PROCEDURE bump_version();
+CREATE TRIGGER version_bump BEFORE UPDATE ON astronaut_log FOR EACH ROW EXECUTE PROCEDURE bump_version();
+CREATE TRIGGER version_bump BEFORE UPDATE ON military_history FOR EACH ROW EXECUTE PROCEDURE bump_version();
+CREATE TRIGGER version_bump BEFORE UPDATE ON mission FOR EACH ROW EXECUTE PROCEDURE bump_version();
+-- runs after update_user_updated_at as triggers fire in name order, so every user update is a new version
+CREATE TRIGGER version_bump BEFORE UPDATE ON "user" FOR EACH ROW EXECUTE PROCEDURE bump_version();
+CREATE TRIGGER version_bump BEFORE UPDATE ON major FOR EACH ROW EXECUTE PROCEDURE bump_version();
+CREATE TRIGGER version_bump BEFORE UPDATE ON alma_mater FOR EACH ROW EXECUTE PROCEDURE bump_version();
@@ -0,0 +1,14 @@
+-- versions ended by a deletion stay ended, only later deletions and restores stop versioning the records
+CREATE OR REPLACE FUNCTION version_astronaut()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_version SET valid_to = CURRENT_TIMESTAMP WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+        VALUES (NEW.id, NEW.first_name, NEW.last_name, NEW.gender, NEW.birth_date, NEW.birth_place, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
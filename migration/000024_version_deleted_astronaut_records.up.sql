@@ -0,0 +1,35 @@
+-- a soft deleted astronaut also ends the current version of their log and military history, so reads as of
+-- a time after the deletion find nothing, and a restored astronaut starts new versions of both.
+
+CREATE OR REPLACE FUNCTION version_astronaut()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE astronaut_version SET valid_to = CURRENT_TIMESTAMP WHERE id = OLD.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        UPDATE astronaut_log_version SET valid_to = CURRENT_TIMESTAMP WHERE astronaut_id = NEW.id AND valid_to IS NULL;
+        UPDATE military_history_version SET valid_to = CURRENT_TIMESTAMP WHERE astronaut_id = NEW.id AND valid_to IS NULL;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        INSERT INTO astronaut_log_version (astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs,
+            status, death_date, valid_from)
+        SELECT astronaut_id, space_flights, space_flight_hrs, space_walks, space_walk_hrs, status, death_date, CURRENT_TIMESTAMP
+        FROM astronaut_log WHERE astronaut_id = NEW.id;
+        INSERT INTO military_history_version (astronaut_id, branch, rank, retired, valid_from)
+        SELECT astronaut_id, branch, rank, retired, CURRENT_TIMESTAMP FROM military_history WHERE astronaut_id = NEW.id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        INSERT INTO astronaut_version (id, first_name, last_name, gender, birth_date, birth_place, valid_from)
+        VALUES (NEW.id, NEW.first_name, NEW.last_name, NEW.gender, NEW.birth_date, NEW.birth_place, CURRENT_TIMESTAMP);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE 'plpgsql';
+
+-- astronauts deleted before now end their records at the time they were deleted
+UPDATE astronaut_log_version v SET valid_to = a.deleted_at FROM astronaut a
+WHERE a.id = v.astronaut_id AND a.deleted_at IS NOT NULL AND v.valid_to IS NULL;
+
+UPDATE military_history_version v SET valid_to = a.deleted_at FROM astronaut a
+WHERE a.id = v.astronaut_id AND a.deleted_at IS NOT NULL AND v.valid_to IS NULL;
@@ -0,0 +1,33 @@
+// Package migration embeds the SQL migrations so the server knows the schema version it was built
+// against.
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var Files embed.FS
+
+// Latest returns the version of the newest up migration, the number its file name starts with.
+func Latest() (uint, error) {
+	names, err := fs.Glob(Files, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s is not named version_title.up.sql", name)
+		}
+		latest = max(latest, uint(v))
+	}
+	return latest, nil
+}